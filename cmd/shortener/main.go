@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,8 +15,8 @@ import (
 
 	"github.com/kirillmashkov/shortener.git/internal/app"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver"
+	"github.com/kirillmashkov/shortener.git/internal/lifecycle"
 	"github.com/kirillmashkov/shortener.git/internal/logger"
-	"github.com/kirillmashkov/shortener.git/internal/model"
 	pb "github.com/kirillmashkov/shortener.git/internal/proto"
 	"github.com/kirillmashkov/shortener.git/internal/server"
 
@@ -40,18 +42,13 @@ func main() {
 		panic(err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-
 	flag.Parse()
-	err = app.Initialize(ctx)
+	err = app.Initialize(context.Background())
 	if err != nil {
 		app.Log.Error("can't init app", zap.Error(err))
 		panic(err)
 	}
-
 	defer app.Close()
-	sigint := make(chan os.Signal, 1)
-	signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM)
 
 	var restServer server.Server
 
@@ -61,36 +58,36 @@ func main() {
 		restServer = httpserver.NewHTTP(app.ServerConf.Host)
 	}
 
-	grpcServer := pb.New(*app.Service, app.ServerConf.GRPCAddress)
-
-	model.Wg.Add(2)
-	go runServer(restServer, sigint, cancel)
-	go runServer(grpcServer, sigint, cancel)
-
-	if model.Wg != nil {
-		model.Wg.Wait()
+	servers := map[string]server.Server{
+		"rest": restServer,
+		"grpc": pb.New(app.Service, app.ServiceUtils, app.ServerConf.GRPCAddress),
 	}
-
-	if model.ShortURLchan != nil {
-		close(model.ShortURLchan)
+	if app.ServerConf.MetricsAddress != "" {
+		servers["admin"] = httpserver.NewAdmin(app.ServerConf.MetricsAddress)
 	}
 
-}
+	stopped := make(chan string, len(servers))
+	for name, srv := range servers {
+		app.Lifecycle.OnStop(lifecycle.PhaseServers, name+" server", app.ServerConf.ShutdownTimeout, srv.Shutdown)
+		go runServer(name, srv, stopped)
+	}
 
-func runServer(server server.Server, sigint chan os.Signal, cancel context.CancelFunc) {
-	go func() {
-		<-sigint
-		if errShutdown := server.Shutdown(); errShutdown != nil {
-			app.Log.Error("error shutdown server")
-		} else {
-			app.Log.Info("server shutdown graceful")
-		}
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM)
 
-		cancel()
-		model.Wg.Done()
-	}()
+	// остановка по сигналу или при падении любого из серверов
+	select {
+	case sig := <-sigint:
+		app.Log.Info("shutdown by signal", zap.Stringer("signal", sig))
+	case name := <-stopped:
+		app.Log.Error("server stopped unexpectedly, shutdown", zap.String("server", name))
+	}
+}
 
-	if err := server.Run(); err != nil {
-		app.Log.Error("can't run rest server", zap.Error(err))
+func runServer(name string, server server.Server, stopped chan<- string) {
+	err := server.Run()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		app.Log.Error("can't run server", zap.String("server", name), zap.Error(err))
 	}
+	stopped <- name
 }
@@ -0,0 +1,29 @@
+package analytics
+
+import "time"
+
+// Интервалы группировки статистики переходов
+const (
+	BucketMinute = "minute"
+	BucketHour   = "hour"
+	BucketDay    = "day"
+)
+
+// BucketDuration - длительность интервала группировки, 0 для неизвестного интервала
+func BucketDuration(bucket string) time.Duration {
+	switch bucket {
+	case BucketMinute:
+		return time.Minute
+	case BucketHour:
+		return time.Hour
+	case BucketDay:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// BucketStart - начало интервала группировки, в который попадает t (в UTC)
+func BucketStart(t time.Time, bucket string) time.Time {
+	return t.UTC().Truncate(BucketDuration(bucket))
+}
@@ -0,0 +1,133 @@
+// Модуль analytics - асинхронная запись переходов по коротким ссылкам
+package analytics
+
+import (
+	"context"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+const maxBatchSize = 500
+const timeoutFlush = 5 * time.Second
+const defaultFlushInterval = time.Second
+
+type storeClick interface {
+	AddClicks(ctx context.Context, clicks []model.Click) error
+}
+
+// Recorder - буферизованная запись переходов, не блокирующая обработку редиректа
+type Recorder struct {
+	clicks        chan model.Click
+	store         storeClick
+	flushInterval time.Duration
+	dropped       atomic.Int64
+	log           *zap.Logger
+}
+
+// NewRecorder - конструктор
+func NewRecorder(store storeClick, bufferSize int, flushInterval time.Duration, log *zap.Logger) *Recorder {
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	return &Recorder{
+		clicks:        make(chan model.Click, bufferSize),
+		store:         store,
+		flushInterval: flushInterval,
+		log:           log,
+	}
+}
+
+// Record - постановка перехода в очередь на запись. Если буфер заполнен, переход отбрасывается
+func (r *Recorder) Record(click model.Click) bool {
+	select {
+	case r.clicks <- click:
+		return true
+	default:
+		r.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped - кол-во отброшенных из-за переполнения буфера переходов
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Run - обработка очереди переходов, пишет в хранилище пачками по размеру или по таймеру
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.Click, 0, maxBatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(r.drain(batch))
+			r.log.Info("Recorder: graceful shutdown")
+			return
+		case click := <-r.clicks:
+			batch = append(batch, click)
+			if len(batch) >= maxBatchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		}
+	}
+}
+
+func (r *Recorder) drain(batch []model.Click) []model.Click {
+	for {
+		select {
+		case click := <-r.clicks:
+			batch = append(batch, click)
+		default:
+			return batch
+		}
+	}
+}
+
+func (r *Recorder) flush(batch []model.Click) []model.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutFlush)
+	defer cancel()
+
+	if err := r.store.AddClicks(ctx, batch); err != nil {
+		r.log.Error("Error save clicks", zap.Int("count", len(batch)), zap.Error(err))
+	}
+
+	return batch[:0]
+}
+
+// CoarseIP - обезличивание адреса клиента: для IPv4 сохраняется подсеть /24, для IPv6 - /48
+func CoarseIP(addr string) string {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		addr = ap.Addr().String()
+	}
+
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return ""
+	}
+
+	bits := 48
+	if ip.Unmap().Is4() {
+		ip = ip.Unmap()
+		bits = 24
+	}
+
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+
+	return prefix.String()
+}
@@ -0,0 +1,79 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type clickCollector struct {
+	mu     sync.Mutex
+	clicks []model.Click
+}
+
+func (c *clickCollector) AddClicks(ctx context.Context, clicks []model.Click) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clicks = append(c.clicks, clicks...)
+	return nil
+}
+
+func (c *clickCollector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.clicks)
+}
+
+func TestRecorderFlushOnShutdown(t *testing.T) {
+	store := &clickCollector{}
+	recorder := NewRecorder(store, 10, time.Hour, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, recorder.Record(model.Click{Key: "abc", Time: time.Now()}))
+	}
+
+	cancel()
+	<-done
+
+	assert.Equal(t, 3, store.count())
+}
+
+func TestRecorderDropsWhenBufferIsFull(t *testing.T) {
+	recorder := NewRecorder(&clickCollector{}, 1, time.Hour, zap.NewNop())
+
+	assert.True(t, recorder.Record(model.Click{Key: "abc"}))
+	assert.False(t, recorder.Record(model.Click{Key: "abc"}))
+	assert.Equal(t, int64(1), recorder.Dropped())
+}
+
+func TestCoarseIP(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want string
+	}{
+		{name: "ipv4 with port", addr: "192.168.10.25:53412", want: "192.168.10.0/24"},
+		{name: "ipv4", addr: "10.1.2.3", want: "10.1.2.0/24"},
+		{name: "ipv6", addr: "2001:db8:abcd:12::1", want: "2001:db8:abcd::/48"},
+		{name: "ipv6 with port", addr: "[2001:db8:abcd:12::1]:443", want: "2001:db8:abcd::/48"},
+		{name: "garbage", addr: "not an ip", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, CoarseIP(test.addr))
+		})
+	}
+}
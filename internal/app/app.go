@@ -3,21 +3,36 @@ package app
 
 import (
 	"context"
-	"sync"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/kirillmashkov/shortener.git/internal/analytics"
+	"github.com/kirillmashkov/shortener.git/internal/authkeys"
 	"github.com/kirillmashkov/shortener.git/internal/config"
-	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/health"
+	"github.com/kirillmashkov/shortener.git/internal/keygen"
+	"github.com/kirillmashkov/shortener.git/internal/lifecycle"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
 	"github.com/kirillmashkov/shortener.git/internal/service"
-	"github.com/kirillmashkov/shortener.git/internal/storage/database"
-	"github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	_ "github.com/kirillmashkov/shortener.git/internal/storage/boltdb"
+	"github.com/kirillmashkov/shortener.git/internal/storage/cache"
+	_ "github.com/kirillmashkov/shortener.git/internal/storage/database"
+	_ "github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/kirillmashkov/shortener.git/internal/storage/metered"
+	"github.com/kirillmashkov/shortener.git/internal/tracing"
 	"go.uber.org/zap"
 )
 
 // ServerConf - конфигурация приложения
 var ServerConf config.ServerConfig
 
-// Storage - управление хранением ссылок в памяти
-var Storage *memory.StoreURLMap
+// Storage - хранилище ссылок, драйвер выбирается конфигурацией
+var Storage storage.Storage
+
+// Cache - кэш чтения ссылок, nil если кэш отключен
+var Cache *cache.Storage
 
 // Service - управление ссылками
 var Service *service.Service
@@ -25,50 +40,151 @@ var Service *service.Service
 // ServiceUtils - утильные функции
 var ServiceUtils *service.ServiceUtils
 
-// Database - управление подключением и миграцией в БД
-var Database *database.Database
+// Recorder - асинхронная запись переходов по коротким ссылкам
+var Recorder *analytics.Recorder
+
+// RateLimit - лимиты частоты запросов клиентов для REST и gRPC
+var RateLimit *ratelimit.Policy
 
-// repositoryShortURL - управление хранением ссылок в БД
-var repositoryShortURL *database.RepositoryShortURL
+// TrustedProxies - прокси, от которых принимается ip клиента в X-Real-IP для лимитов частоты запросов
+var TrustedProxies ratelimit.Proxies
 
 // Log - логер
 var Log *zap.Logger = zap.NewNop()
 
-// Initialize - инициализация приложения
+// Health - проверки готовности сервиса
+var Health *health.Checker
+
+// Lifecycle - фоновые задачи и порядок остановки приложения
+var Lifecycle *lifecycle.Manager
+
+// AuthKeys - ключи подписи токенов авторизации
+var AuthKeys *authkeys.KeyRing
+
+// devJWTSecret - секрет подписи токенов для локальной разработки, если секрет не задан конфигурацией
+const devJWTSecret = "supersecretkey"
+
+const tracingShutdownTimeout = 5 * time.Second
+
+const healthCheckTimeout = 2 * time.Second
+
+// Initialize - инициализация приложения. Фоновые задачи работают до Close или отмены ctx
 func Initialize(ctx context.Context) error {
 	var err error
 
 	config.InitServerConf(&ServerConf, Log)
+	if ServerConf.JWTSecret == "" {
+		Log.Warn("JWT secret is not configured, using INSECURE development secret. " +
+			"Set JWT_SECRET, -jwt-secret or jwt_secret in config file for any non-local deployment")
+		ServerConf.JWTSecret = devJWTSecret
+	}
+	if ServerConf.TokenExp <= 0 {
+		Log.Error("Auth token lifetime must be positive", zap.Duration("token_exp", ServerConf.TokenExp))
+		return fmt.Errorf("token exp %s: must be positive", ServerConf.TokenExp)
+	}
 
-	model.Wg = &sync.WaitGroup{}
+	AuthKeys, err = authkeys.Parse(ServerConf.JWTKeyID, ServerConf.JWTSecret, ServerConf.JWTVerifyKeys)
+	if err != nil {
+		Log.Error("Can't parse jwt keys", zap.Error(err))
+		return err
+	}
+
+	Lifecycle = lifecycle.New(ctx, Log, ServerConf.ShutdownTimeout)
+
+	shutdownTracing, err := tracing.Init(ServerConf.TraceExporter, ServerConf.TraceFile)
+	if err != nil {
+		Log.Error("Can't init tracing", zap.String("exporter", ServerConf.TraceExporter), zap.Error(err))
+		return err
+	}
+	Lifecycle.OnStop(lifecycle.PhaseTelemetry, "tracing", tracingShutdownTimeout, shutdownTracing)
+
+	RateLimit, err = ratelimit.ParsePolicy(ServerConf.RateLimits)
+	if err != nil {
+		Log.Error("Can't parse rate limits", zap.Error(err))
+		return err
+	}
 
-	Database = database.New(&ServerConf, Log)
-	err = Database.Open()
+	TrustedProxies, err = ratelimit.ParseProxies(ServerConf.TrustedProxies)
 	if err != nil {
-		Log.Error("Error open connection db", zap.Error(err))
-		Storage, err = memory.New(&ServerConf, Log, &ServerConf)
-		if err != nil {
-			return nil
-		}
-		Service = service.New(Storage, ServerConf, Log)
-	} else {
-		if err := Database.Migrate(); err != nil {
-			return err
-		}
-		repositoryShortURL = database.NewRepositoryShortURL(Database, Log)
-		Service = service.New(repositoryShortURL, ServerConf, Log)
-		model.ShortURLchan = make(chan model.ShortURLUserID)
-		model.Wg.Add(1)
-		go repositoryShortURL.DeleteURLBatchProcessor(ctx)
+		Log.Error("Can't parse trusted proxies", zap.Error(err))
+		return err
 	}
-	ServiceUtils = service.NewServiceUtils(Database, Log)
+
+	var clickStorage storage.ClickStorage
+	Storage, clickStorage, err = storage.Open(Lifecycle.Context(), &ServerConf, Log)
+	if err != nil {
+		Log.Error("Can't open storage", zap.String("driver", storage.DriverName(&ServerConf)), zap.Error(err))
+		return err
+	}
+	closeStorage := Storage.Close
+	Lifecycle.OnStop(lifecycle.PhaseStorage, "storage", ServerConf.ShutdownTimeout, func(context.Context) error {
+		return closeStorage()
+	})
+
+	jobStorage, ok := Storage.(storage.JobStorage)
+	if !ok {
+		err := fmt.Errorf("storage %s: delete jobs: %w", storage.DriverName(&ServerConf), storage.ErrNotSupported)
+		Log.Error("Can't use storage", zap.Error(err))
+		return errors.Join(err, closeStorage())
+	}
+
+	sequence, _ := Storage.(storage.Sequencer)
+	keys, err := keygen.New(keygen.Config{
+		Strategy: ServerConf.KeyStrategy,
+		Length:   ServerConf.KeyLength,
+		Alphabet: ServerConf.KeyAlphabet,
+	}, sequence)
+	if err != nil {
+		Log.Error("Can't create short key generator", zap.String("strategy", ServerConf.KeyStrategy), zap.Error(err))
+		return errors.Join(err, closeStorage())
+	}
+
+	Health = health.New(healthCheckTimeout)
+	Health.Register("storage", Storage.Ping)
+	if migrations, ok := Storage.(storage.MigrationChecker); ok {
+		Health.Register("migrations", migrations.CheckMigrations)
+	}
+
+	driver := storage.DriverName(&ServerConf)
+	Storage = metered.New(Storage, driver)
+	clickStorage = metered.NewClicks(clickStorage, driver)
+
+	if ServerConf.CacheSize > 0 {
+		Cache = cache.New(Storage, cache.Config{
+			Size:         ServerConf.CacheSize,
+			TTL:          ServerConf.CacheTTL,
+			NegativeSize: ServerConf.CacheNegativeSize,
+			NegativeTTL:  ServerConf.CacheNegativeTTL,
+		}, Log)
+		Storage = Cache
+	}
+
+	Recorder = analytics.NewRecorder(clickStorage, ServerConf.ClickBufferSize, ServerConf.ClickFlushInterval, Log)
+	Service = service.New(Storage, clickStorage, jobStorage, keys, ServerConf, Log)
+	Health.Register("delete_worker", Service.CheckDeleteWorker)
+
+	go Service.RunDeleteWorker()
+	Lifecycle.OnStop(lifecycle.PhaseDrain, "delete queue", ServerConf.DeleteDrainTimeout, Service.StopDeleteWorker)
+	Lifecycle.Go("resume delete jobs", Service.ResumeDeleteJobs)
+	Lifecycle.Go("sweep expired urls", Service.SweepExpiredURL)
+	Lifecycle.Go("sweep deleted urls", Service.SweepDeletedURL)
+	Lifecycle.Go("sweep delete jobs", Service.SweepDeleteJobs)
+	Lifecycle.Go("click recorder", Recorder.Run)
+	Lifecycle.Go("rate limit cleanup", RateLimit.Run)
+
+	ServiceUtils = service.NewServiceUtils(Storage, Log)
 	return nil
 }
 
-// Close - закрытие приложения
+// Close - остановка приложения: серверы, очередь удаления, фоновые задачи, хранилище, телеметрия
 func Close() {
-	errClose := Database.Close()
-	if errClose != nil {
-		Log.Error("Error close connection db", zap.Error(errClose))
+	if Lifecycle == nil {
+		return
+	}
+
+	if err := Lifecycle.Stop(); err != nil {
+		Log.Error("Shutdown finished with errors", zap.Error(err))
+		return
 	}
+	Log.Info("Shutdown finished")
 }
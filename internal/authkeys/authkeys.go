@@ -0,0 +1,72 @@
+// Модуль authkeys - ключи подписи токенов авторизации. Собираются один раз при старте приложения
+// и используются REST и gRPC серверами
+package authkeys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// KidHeader - заголовок токена с id ключа подписи
+const KidHeader = "kid"
+
+// ErrNoSecret - не задан секрет активного ключа
+var ErrNoSecret = errors.New("jwt secret is not configured")
+
+// KeyRing - ключи подписи токенов: активный ключ для выпуска и все ключи, принимаемые при проверке
+type KeyRing struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// Parse - сборка ключей. Активный ключ задается activeID/secret, ключи из verifyKeys (kid1:secret1,kid2:secret2)
+// принимаются только для проверки, что позволяет сменить секрет без повторной авторизации всех пользователей
+func Parse(activeID string, secret string, verifyKeys string) (*KeyRing, error) {
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+
+	ring := &KeyRing{activeID: activeID, keys: map[string][]byte{}}
+	for _, pair := range strings.Split(verifyKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kid, key, ok := strings.Cut(pair, ":")
+		if !ok || kid == "" || key == "" {
+			return nil, fmt.Errorf("jwt verify key %q: expected kid:secret", kid)
+		}
+		ring.keys[kid] = []byte(key)
+	}
+
+	ring.keys[activeID] = []byte(secret)
+	return ring, nil
+}
+
+// SigningKey - id и значение активного ключа для выпуска токенов
+func (r *KeyRing) SigningKey() (string, []byte) {
+	return r.activeID, r.keys[r.activeID]
+}
+
+// KeyFunc - выбор ключа проверки по kid из заголовка токена. Токены без kid проверяются активным ключом
+func (r *KeyRing) KeyFunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+
+	kid, _ := t.Header[KidHeader].(string)
+	if kid == "" {
+		kid = r.activeID
+	}
+
+	key, ok := r.keys[kid]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	return key, nil
+}
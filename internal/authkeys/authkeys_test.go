@@ -0,0 +1,37 @@
+package authkeys
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		verifyKeys string
+		wantErr    bool
+	}{
+		{name: "active key only", secret: "secret"},
+		{name: "previous keys", secret: "secret", verifyKeys: "2024:old, 2023:older"},
+		{name: "no secret", verifyKeys: "2024:old", wantErr: true},
+		{name: "missing separator", secret: "secret", verifyKeys: "2024", wantErr: true},
+		{name: "empty previous secret", secret: "secret", verifyKeys: "2024:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := Parse("2025", tt.secret, tt.verifyKeys)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			kid, key := ring.SigningKey()
+			assert.Equal(t, "2025", kid)
+			assert.Equal(t, []byte(tt.secret), key)
+		})
+	}
+}
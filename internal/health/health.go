@@ -0,0 +1,99 @@
+// Модуль health - проверка готовности сервиса: набор именованных проверок компонентов
+// (хранилище, миграции, фоновые обработчики, слушатели), выполняемых параллельно
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Статусы компонента и сервиса
+const (
+	// StatusUp - компонент работает
+	StatusUp = "up"
+	// StatusDown - компонент недоступен
+	StatusDown = "down"
+)
+
+// Check - проверка компонента, nil - компонент работает
+type Check func(ctx context.Context) error
+
+// Component - результат проверки компонента
+type Component struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report - результат проверки всех компонентов. Сервис готов, если работают все компоненты
+type Report struct {
+	Status     string      `json:"status"`
+	Components []Component `json:"components"`
+}
+
+// Ready - готов ли сервис принимать запросы
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+// Checker - набор проверок готовности
+type Checker struct {
+	mu      sync.Mutex
+	names   []string
+	checks  map[string]Check
+	timeout time.Duration
+}
+
+// New - конструктор, timeout - время на выполнение одной проверки
+func New(timeout time.Duration) *Checker {
+	return &Checker{checks: map[string]Check{}, timeout: timeout}
+}
+
+// Register - добавление проверки компонента. Повторная регистрация имени заменяет проверку
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Check - параллельное выполнение всех проверок. Компоненты в отчете идут в порядке регистрации
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	names := append([]string(nil), c.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusUp, Components: make([]Component, len(names))}
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Components[i] = Component{Name: names[i], Status: StatusUp}
+			if err := checks[i](ctx); err != nil {
+				report.Components[i].Status = StatusDown
+				report.Components[i].Error = err.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
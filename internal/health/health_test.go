@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	c := New(50 * time.Millisecond)
+	c.Register("storage", func(ctx context.Context) error { return nil })
+	c.Register("worker", func(ctx context.Context) error { return errors.New("not running") })
+	c.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := c.Check(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, []Component{
+		{Name: "storage", Status: StatusUp},
+		{Name: "worker", Status: StatusDown, Error: "not running"},
+		{Name: "slow", Status: StatusDown, Error: context.DeadlineExceeded.Error()},
+	}, report.Components)
+
+	c.Register("worker", func(ctx context.Context) error { return nil })
+	c.Register("slow", func(ctx context.Context) error { return nil })
+	report = c.Check(context.Background())
+	assert.True(t, report.Ready())
+	assert.Len(t, report.Components, 3)
+}
@@ -0,0 +1,32 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+	"github.com/kirillmashkov/shortener.git/internal/server"
+)
+
+// Admin - служебный HTTP сервер, отдает метрики на отдельном адресе
+type Admin struct {
+	server *http.Server
+}
+
+// Run - запуск служебного сервера
+func (s *Admin) Run() error {
+	return s.server.ListenAndServe()
+}
+
+// Shutdown - остановка служебного сервера
+func (s *Admin) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// NewAdmin - конструктор служебного сервера с /metrics
+func NewAdmin(addr string) server.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	return &Admin{server: &http.Server{Addr: addr, Handler: mux}}
+}
@@ -7,25 +7,43 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/kirillmashkov/shortener.git/internal/analytics"
 	"github.com/kirillmashkov/shortener.git/internal/app"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
 
 	"go.uber.org/zap"
 )
 
 // ServiceShortURL - интерфейс для управления ссылками
 type ServiceShortURL interface {
-	GetShortURL(ctx context.Context, key string) (string, bool, bool)
-	ProcessURL(ctx context.Context, originalURL string, userID int) (string, error)
+	GetShortURL(ctx context.Context, key string) (model.ShortURLInfo, error)
+	ProcessURL(ctx context.Context, request model.URLToShortRequest, userID int) (string, error)
 	ProcessURLBatch(ctx context.Context, originalURLs []model.URLToShortBatchRequest, userID int) ([]model.ShortToURLBatchResponse, error)
-	DeleteURLBatch(userID int, shortURLs []string)
-	GetAllURL(ctx context.Context, userID int) ([]model.ShortOriginalURL, error)
+	DeleteURLBatch(ctx context.Context, userID int, shortURLs []string) (string, error)
+	GetDeleteJob(ctx context.Context, id string, userID int) (model.DeleteJob, error)
+	ListURLs(ctx context.Context, userID int, request model.URLListRequest) (model.URLPage, error)
 	GetStats(ctx context.Context) (model.Stats, error)
+	GetURLStats(ctx context.Context, key string, userID int, query model.ClickStatsQuery) (model.ClickStats, error)
+	UpdateURL(ctx context.Context, key string, userID int, request model.URLUpdateRequest) (model.URLResponse, error)
+	GetURLHistory(ctx context.Context, key string, userID int) ([]model.URLHistory, error)
+	RestoreURLs(ctx context.Context, userID int, shortURLs []string) ([]string, error)
 }
 
-// GetHandler - обработчик REST запроса на получение обычной ссылки по короткой
+const realIPHeader = "X-Real-IP"
+
+// nextCursorHeader - курсор следующей страницы списка, на последней странице не передается
+const nextCursorHeader = "X-Next-Cursor"
+
+// GetHandler - обработчик REST запроса на получение обычной ссылки по короткой.
+// Код ответа и адрес перехода определяются политикой перехода ссылки
 func GetHandler(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
 		http.Error(res, "Only GET requests are allowed!", http.StatusBadRequest)
@@ -33,50 +51,246 @@ func GetHandler(res http.ResponseWriter, req *http.Request) {
 	}
 
 	key := req.URL.Path[len("/"):]
-	url, deleted, exist := app.Service.GetShortURL(req.Context(), key)
+	info, err := app.Service.GetShortURL(req.Context(), key)
+
+	if err != nil {
+		if errors.Is(err, model.ErrURLDeleted) || errors.Is(err, model.ErrURLExpired) {
+			res.WriteHeader(http.StatusGone)
+			return
+		}
 
-	if !exist {
 		http.Error(res, "Key not found", http.StatusBadRequest)
 		return
 	}
 
-	if deleted {
-		res.WriteHeader(http.StatusGone)
+	app.Recorder.Record(model.Click{
+		Key:       info.Key,
+		Time:      time.Now().UTC(),
+		Referrer:  req.Referer(),
+		UserAgent: req.UserAgent(),
+		IP:        analytics.CoarseIP(clientIP(req)),
+	})
+
+	code := info.RedirectCode()
+	if code == http.StatusFound || code == http.StatusTemporaryRedirect {
+		// временный переход не должен кэшироваться, чтобы каждый переход учитывался в статистике
+		res.Header().Set("Cache-Control", "no-store")
+	}
+	http.Redirect(res, req, info.RedirectTarget(req.URL.Query()), code)
+}
+
+// clientIP - ip клиента для статистики переходов. X-Real-IP учитывается только от доверенных прокси
+func clientIP(req *http.Request) string {
+	return app.TrustedProxies.ClientIP(req.RemoteAddr, req.Header.Get(realIPHeader))
+}
+
+// GetURLStats - обработчик REST запроса на получение статистики переходов по короткой ссылке пользователя
+func GetURLStats(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(res, "Only GET requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	query := model.ClickStatsQuery{Bucket: req.URL.Query().Get("bucket")}
+	var err error
+	if query.From, err = parseTimeParam(req, "from"); err != nil {
+		http.Error(res, "from must be in RFC3339 format", http.StatusBadRequest)
+		return
+	}
+	if query.To, err = parseTimeParam(req, "to"); err != nil {
+		http.Error(res, "to must be in RFC3339 format", http.StatusBadRequest)
+		return
+	}
+
+	u := security.UserIDType("userID")
+	stats, err := app.Service.GetURLStats(req.Context(), chi.URLParam(req, "id"), req.Context().Value(u).(int), query)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrURLNotFound):
+			http.Error(res, "Key not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrInvalidStatsQuery):
+			http.Error(res, err.Error(), http.StatusBadRequest)
+		default:
+			ctxlog.From(req.Context(), app.Log).Error("Error get url stats", zap.Error(err))
+			http.Error(res, "Can't get stats", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(stats); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// PatchURL - обработчик REST запроса на изменение исходной ссылки, политики перехода или срока действия ссылки пользователя.
+// Возвращает ссылку после изменения
+func PatchURL(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPatch {
+		http.Error(res, "Only PATCH requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	var request model.URLUpdateRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("cannot parse request JSON body", zap.Error(err))
+		http.Error(res, "cannot parse request JSON body", http.StatusBadRequest)
+		return
+	}
+
+	u := security.UserIDType("userID")
+	response, err := app.Service.UpdateURL(req.Context(), chi.URLParam(req, "id"), req.Context().Value(u).(int), request)
+	if err != nil {
+		switch {
+		case writeRequestError(res, err):
+		case errors.Is(err, model.ErrURLNotFound):
+			http.Error(res, "Key not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrURLDeleted):
+			http.Error(res, "Key was deleted", http.StatusGone)
+		case errors.Is(err, model.ErrDuplicateURL):
+			http.Error(res, "url is already shortened", http.StatusConflict)
+		default:
+			ctxlog.From(req.Context(), app.Log).Error("Error update url", zap.Error(err))
+			http.Error(res, "Can't update url", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	http.Redirect(res, req, url, http.StatusTemporaryRedirect)
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(response); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
 }
 
-// GetAllURL - обработчик REST запроса на получение всех ссылок
-func GetAllURL(res http.ResponseWriter, req *http.Request) {
+// GetURLHistory - обработчик REST запроса на получение истории изменений ссылки пользователя
+func GetURLHistory(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
 		http.Error(res, "Only GET requests are allowed!", http.StatusBadRequest)
 		return
 	}
 
 	u := security.UserIDType("userID")
+	history, err := app.Service.GetURLHistory(req.Context(), chi.URLParam(req, "id"), req.Context().Value(u).(int))
+	if err != nil {
+		if errors.Is(err, model.ErrURLNotFound) {
+			http.Error(res, "Key not found", http.StatusNotFound)
+			return
+		}
+		ctxlog.From(req.Context(), app.Log).Error("Error get url history", zap.Error(err))
+		http.Error(res, "Can't get history", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(history); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+func parseTimeParam(req *http.Request, name string) (time.Time, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
 
-	result, err := app.Service.GetAllURL(req.Context(), req.Context().Value(u).(int))
+// GetAllURL - обработчик REST запроса на получение страницы ссылок пользователя.
+// Параметры: limit, cursor, q - поиск по исходной ссылке, deleted, expired, created_from, created_to
+// и sort (created, key, url, с префиксом "-" - по убыванию). Ссылка на следующую страницу передается
+// в заголовках Link и X-Next-Cursor
+func GetAllURL(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(res, "Only GET requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	request, err := parseListRequest(req)
 	if err != nil {
-		http.Error(res, "Something went wrong", http.StatusBadRequest)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u := security.UserIDType("userID")
+	page, err := app.Service.ListURLs(req.Context(), req.Context().Value(u).(int), request)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidListQuery) {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctxlog.From(req.Context(), app.Log).Error("Error list urls", zap.Error(err))
+		http.Error(res, "Something went wrong", http.StatusInternalServerError)
 		return
 	}
 
 	res.Header().Set("Content-Type", "application/json")
-	if len(result) == 0 {
+	if page.NextCursor != "" {
+		next := req.URL.Query()
+		next.Set("cursor", page.NextCursor)
+		res.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, (&url.URL{Path: req.URL.Path, RawQuery: next.Encode()}).String()))
+		res.Header().Set(nextCursorHeader, page.NextCursor)
+	}
+	if len(page.URLs) == 0 {
 		res.WriteHeader(http.StatusNoContent)
 		return
 	}
 
 	res.WriteHeader(http.StatusOK)
 	encoder := json.NewEncoder(res)
-	if err := encoder.Encode(result); err != nil {
-		app.Log.Debug("error encoding result", zap.Error(err))
+	if err := encoder.Encode(page.URLs); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding result", zap.Error(err))
 		return
 	}
 }
 
+// parseListRequest - разбор параметров запроса страницы ссылок пользователя
+func parseListRequest(req *http.Request) (model.URLListRequest, error) {
+	params := req.URL.Query()
+	request := model.URLListRequest{Cursor: params.Get("cursor"), Search: params.Get("q"), Sort: params.Get("sort")}
+
+	var err error
+	if value := params.Get("limit"); value != "" {
+		if request.Limit, err = strconv.Atoi(value); err != nil {
+			return request, errors.New("limit must be integer")
+		}
+	}
+	if request.Deleted, err = parseBoolParam(req, "deleted"); err != nil {
+		return request, errors.New("deleted must be true or false")
+	}
+	if request.Expired, err = parseBoolParam(req, "expired"); err != nil {
+		return request, errors.New("expired must be true or false")
+	}
+	if request.CreatedFrom, err = parseTimeParam(req, "created_from"); err != nil {
+		return request, errors.New("created_from must be in RFC3339 format")
+	}
+	if request.CreatedTo, err = parseTimeParam(req, "created_to"); err != nil {
+		return request, errors.New("created_to must be in RFC3339 format")
+	}
+
+	return request, nil
+}
+
+func parseBoolParam(req *http.Request, name string) (*bool, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
 // PostHandler - обработчик REST запроса на сохранение обычной ссылки. Возвращает короткую ссылку
 func PostHandler(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
@@ -92,7 +306,8 @@ func PostHandler(res http.ResponseWriter, req *http.Request) {
 
 	u := security.UserIDType("userID")
 
-	shortURL, err := app.Service.ProcessURL(req.Context(), string(originalURL), req.Context().Value(u).(int))
+	request := model.URLToShortRequest{OriginalURL: string(originalURL)}
+	shortURL, err := app.Service.ProcessURL(req.Context(), request, req.Context().Value(u).(int))
 	res.Header().Set("content-type", "text/plain")
 	if err != nil {
 		errorString := fmt.Sprintf("Something went wrong when generate short url for %s", string(originalURL))
@@ -100,13 +315,13 @@ func PostHandler(res http.ResponseWriter, req *http.Request) {
 			res.WriteHeader(http.StatusConflict)
 			_, err = res.Write([]byte(shortURL))
 			if err != nil {
-				app.Log.Error("Can't write response", zap.Error(err))
+				ctxlog.From(req.Context(), app.Log).Error("Can't write response", zap.Error(err))
 				http.Error(res, errorString, http.StatusBadRequest)
 				return
 			}
 			return
 		}
-		app.Log.Error("Error process URL", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Error("Error process URL", zap.Error(err))
 		http.Error(res, errorString, http.StatusBadRequest)
 		return
 	}
@@ -129,13 +344,13 @@ func PostGenerateShortURL(res http.ResponseWriter, req *http.Request) {
 	var request model.URLToShortRequest
 	decoder := json.NewDecoder(req.Body)
 	if err := decoder.Decode(&request); err != nil {
-		app.Log.Debug("cannot parse request JSON body", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Debug("cannot parse request JSON body", zap.Error(err))
 		http.Error(res, "cannot parse request JSON body", http.StatusBadRequest)
 		return
 	}
 
 	u := security.UserIDType("userID")
-	shortURL, err := app.Service.ProcessURL(req.Context(), request.OriginalURL, req.Context().Value(u).(int))
+	shortURL, err := app.Service.ProcessURL(req.Context(), request, req.Context().Value(u).(int))
 
 	res.Header().Set("Content-Type", "application/json")
 	response := model.ShortToURLReponse{
@@ -147,12 +362,16 @@ func PostGenerateShortURL(res http.ResponseWriter, req *http.Request) {
 			res.WriteHeader(http.StatusConflict)
 			encoder := json.NewEncoder(res)
 			if err := encoder.Encode(response); err != nil {
-				app.Log.Debug("error encoding response", zap.Error(err))
+				ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
 				return
 			}
 			return
 		}
 
+		if writeRequestError(res, err) {
+			return
+		}
+
 		errorString := fmt.Sprintf("Something went wrong when generate short url for %s", request.OriginalURL)
 		http.Error(res, errorString, http.StatusBadRequest)
 		return
@@ -161,12 +380,14 @@ func PostGenerateShortURL(res http.ResponseWriter, req *http.Request) {
 	res.WriteHeader(http.StatusCreated)
 	encoder := json.NewEncoder(res)
 	if err := encoder.Encode(response); err != nil {
-		app.Log.Debug("error encoding response", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
 		return
 	}
 }
 
-// PostGenerateShortURLBatch - обработчик REST запроса, сохранение массива обычных URL, взамен возвращает массив коротких ссылок
+// PostGenerateShortURLBatch - обработчик REST запроса, сохранение массива обычных URL, взамен возвращает массив
+// результатов по correlation_id: created, exists с существующей короткой ссылкой или invalid с описанием ошибки.
+// Код ответа 201, если создана хотя бы одна ссылка, иначе 200
 func PostGenerateShortURLBatch(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		http.Error(res, "Only POST requests are allowed!", http.StatusBadRequest)
@@ -176,28 +397,52 @@ func PostGenerateShortURLBatch(res http.ResponseWriter, req *http.Request) {
 	var request []model.URLToShortBatchRequest
 	decoder := json.NewDecoder(req.Body)
 	if err := decoder.Decode(&request); err != nil {
-		app.Log.Debug("cannot parse request JSON body", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Debug("cannot parse request JSON body", zap.Error(err))
 		http.Error(res, "cannot parse request JSON body", http.StatusBadRequest)
 		return
 	}
 
 	u := security.UserIDType("userID")
 	response, err := app.Service.ProcessURLBatch(req.Context(), request, req.Context().Value(u).(int))
-
 	if err != nil {
-		http.Error(res, "Can't store url batch", http.StatusBadRequest)
+		ctxlog.From(req.Context(), app.Log).Error("Error store url batch", zap.Error(err))
+		http.Error(res, "Can't store url batch", http.StatusInternalServerError)
 		return
 	}
+
+	code := http.StatusOK
+	for _, item := range response {
+		if item.Status == model.BatchCreated {
+			code = http.StatusCreated
+			break
+		}
+	}
 	res.Header().Set("Content-Type", "application/json")
-	res.WriteHeader(http.StatusCreated)
+	res.WriteHeader(code)
 	encoder := json.NewEncoder(res)
 	if err := encoder.Encode(response); err != nil {
-		app.Log.Debug("error encoding response", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
 		return
 	}
 }
 
-// DeleteURLBatch - обработчик REST запроса, удаление массива ссылок для пользователя
+// writeRequestError - ответ на ошибки параметров короткой ссылки, возвращает true если ошибка обработана
+func writeRequestError(res http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, model.ErrInvalidAlias), errors.Is(err, model.ErrInvalidExpiry), errors.Is(err, model.ErrInvalidRedirect),
+		errors.Is(err, model.ErrInvalidUpdate):
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return true
+	case errors.Is(err, model.ErrAliasTaken):
+		http.Error(res, err.Error(), http.StatusConflict)
+		return true
+	}
+
+	return false
+}
+
+// DeleteURLBatch - обработчик REST запроса, удаление массива ссылок для пользователя.
+// Возвращает id задания на удаление, статус которого доступен по адресу из заголовка Location
 func DeleteURLBatch(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodDelete {
 		http.Error(res, "Only Delete requests are allowed!", http.StatusBadRequest)
@@ -207,14 +452,92 @@ func DeleteURLBatch(res http.ResponseWriter, req *http.Request) {
 	var shortURLs []string
 	decoder := json.NewDecoder(req.Body)
 	if err := decoder.Decode(&shortURLs); err != nil {
-		app.Log.Debug("cannot parse request JSON body", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Debug("cannot parse request JSON body", zap.Error(err))
 		http.Error(res, "cannot parse request JSON body", http.StatusBadRequest)
 		return
 	}
 
 	u := security.UserIDType("userID")
-	app.Service.DeleteURLBatch(req.Context().Value(u).(int), shortURLs)
+	jobID, err := app.Service.DeleteURLBatch(req.Context(), req.Context().Value(u).(int), shortURLs)
+	if err != nil {
+		if errors.Is(err, model.ErrShuttingDown) {
+			http.Error(res, "Service is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		ctxlog.From(req.Context(), app.Log).Error("Error queue urls for deletion", zap.Error(err))
+		http.Error(res, "Can't delete urls", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Location", "/api/user/jobs/"+jobID)
 	res.WriteHeader(http.StatusAccepted)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(model.DeleteJobResponse{JobID: jobID}); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// RestoreURLs - обработчик REST запроса на восстановление удаленных ссылок пользователя.
+// Возвращает ключи ссылок, которые после запроса не помечены удаленными
+func RestoreURLs(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "Only POST requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	var shortURLs []string
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&shortURLs); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("cannot parse request JSON body", zap.Error(err))
+		http.Error(res, "cannot parse request JSON body", http.StatusBadRequest)
+		return
+	}
+
+	u := security.UserIDType("userID")
+	restored, err := app.Service.RestoreURLs(req.Context(), req.Context().Value(u).(int), shortURLs)
+	if err != nil {
+		ctxlog.From(req.Context(), app.Log).Error("Error restore urls", zap.Error(err))
+		http.Error(res, "Can't restore urls", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(restored); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// GetDeleteJob - обработчик REST запроса на получение статуса задания на удаление ссылок пользователя
+func GetDeleteJob(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(res, "Only GET requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	u := security.UserIDType("userID")
+	job, err := app.Service.GetDeleteJob(req.Context(), chi.URLParam(req, "id"), req.Context().Value(u).(int))
+	if err != nil {
+		if errors.Is(err, model.ErrJobNotFound) {
+			http.Error(res, "Job not found", http.StatusNotFound)
+			return
+		}
+		ctxlog.From(req.Context(), app.Log).Error("Error get delete job", zap.Error(err))
+		http.Error(res, "Can't get job", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(job); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
 }
 
 // Ping - обработчик REST запроса, проверка доступности БД
@@ -251,7 +574,78 @@ func Stats(res http.ResponseWriter, req *http.Request) {
 	res.WriteHeader(http.StatusOK)
 	encoder := json.NewEncoder(res)
 	if err := encoder.Encode(stats); err != nil {
-		app.Log.Debug("error encoding response", zap.Error(err))
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// CompactStorage - обработчик REST запроса, компактизация файла хранилища по требованию
+func CompactStorage(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "Only POST requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	compactor, ok := app.Storage.(storage.Compactor)
+	if !ok {
+		http.Error(res, "Storage doesn't support compaction", http.StatusNotImplemented)
+		return
+	}
+
+	if err := compactor.Compact(req.Context()); err != nil {
+		if errors.Is(err, storage.ErrNotSupported) {
+			http.Error(res, "Storage doesn't support compaction", http.StatusNotImplemented)
+			return
+		}
+		http.Error(res, "Can't compact storage", http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
+
+// CacheStats - обработчик REST запроса, возвращает счетчики кэша ссылок
+func CacheStats(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(res, "Only GET requests are allowed!", http.StatusBadRequest)
+		return
+	}
+
+	if app.Cache == nil {
+		http.Error(res, "Cache is disabled", http.StatusNotImplemented)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(app.Cache.Stats()); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// Healthz - обработчик REST запроса, проверка, что процесс жив
+func Healthz(res http.ResponseWriter, req *http.Request) {
+	res.WriteHeader(http.StatusOK)
+}
+
+// Readyz - обработчик REST запроса, проверка готовности компонентов сервиса.
+// Возвращает статус каждого компонента и 503, если хотя бы один недоступен
+func Readyz(res http.ResponseWriter, req *http.Request) {
+	report := app.Health.Check(req.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+		ctxlog.From(req.Context(), app.Log).Warn("Service is not ready", zap.Any("components", report.Components))
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	encoder := json.NewEncoder(res)
+	if err := encoder.Encode(report); err != nil {
+		ctxlog.From(req.Context(), app.Log).Debug("error encoding response", zap.Error(err))
 		return
 	}
 }
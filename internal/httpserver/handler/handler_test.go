@@ -2,22 +2,57 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kirillmashkov/shortener.git/internal/app"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/compress"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "shortener")
+	if err != nil {
+		log.Fatal("Can't create temp dir", err)
+	}
+
+	if err := os.Setenv("FILE_STORAGE_PATH", filepath.Join(dir, "short_url_storage.txt")); err != nil {
+		log.Fatal("Can't set env", err)
+	}
+	if err := os.Setenv("STORAGE", "file"); err != nil {
+		log.Fatal("Can't set env", err)
+	}
+	if err := os.Setenv("JWT_SECRET", "test-secret"); err != nil {
+		log.Fatal("Can't set env", err)
+	}
+
+	if err := app.Initialize(context.Background()); err != nil {
+		log.Fatal("Can't initialize app", err)
+	}
+
+	code := m.Run()
+	app.Close()
+	if err := os.RemoveAll(dir); err != nil {
+		log.Println("Can't remove temp dir", err)
+	}
+	os.Exit(code)
+}
+
 func TestPostHandler(t *testing.T) {
 	type want struct {
 		code        int
@@ -39,13 +74,6 @@ func TestPostHandler(t *testing.T) {
 		},
 	}
 
-	err := app.Initialize(context.Background())
-	if err != nil {
-		log.SetPrefix("ERROR")
-		log.Println("Can't initialize app")
-		return
-	}
-
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 
 	r := chi.NewRouter()
@@ -121,6 +149,25 @@ func TestGetHandler(t *testing.T) {
 	}
 }
 
+func TestClientIP(t *testing.T) {
+	proxies, err := ratelimit.ParseProxies("127.0.0.0/8")
+	require.NoError(t, err)
+	app.TrustedProxies = proxies
+	defer func() {
+		app.TrustedProxies = nil
+	}()
+
+	request := func(remoteAddr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/aaaaa", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set(realIPHeader, "10.0.0.1")
+		return r
+	}
+
+	assert.Equal(t, "10.0.0.1", clientIP(request("127.0.0.1:1234")), "trusted proxy passes client ip")
+	assert.Equal(t, "192.0.2.1", clientIP(request("192.0.2.1:1234")), "header of untrusted client is ignored")
+}
+
 func TestPostGenerateShortURL(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -136,10 +183,34 @@ func TestPostGenerateShortURL(t *testing.T) {
 		},
 		{
 			name:         "test successful create short link",
-			body:         `{"url": "http://www.lenta.ru"}`,
+			body:         `{"url": "http://www.lenta.ru/news"}`,
 			expectedCode: 201,
 			compress:     true,
 		},
+		{
+			name:         "test conflict on duplicate link",
+			body:         `{"url": "http://www.lenta.ru"}`,
+			expectedCode: 409,
+			compress:     false,
+		},
+		{
+			name:         "test successful create short link with alias",
+			body:         `{"url": "http://www.lenta.ru/sport", "alias": "lenta-sport"}`,
+			expectedCode: 201,
+			compress:     false,
+		},
+		{
+			name:         "test conflict on taken alias",
+			body:         `{"url": "http://www.lenta.ru/auto", "alias": "lenta-sport"}`,
+			expectedCode: 409,
+			compress:     false,
+		},
+		{
+			name:         "test reserved alias",
+			body:         `{"url": "http://www.lenta.ru/auto", "alias": "api"}`,
+			expectedCode: 400,
+			compress:     false,
+		},
 	}
 
 	r := chi.NewRouter()
@@ -181,3 +252,270 @@ func TestPostGenerateShortURL(t *testing.T) {
 		})
 	}
 }
+
+func TestReadyz(t *testing.T) {
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.JSONEq(t, `{"status":"up","components":[{"name":"storage","status":"up"},{"name":"delete_worker","status":"up"}]}`, w.Body.String())
+}
+
+func TestDeleteURLBatchJob(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(security.Auth)
+	r.Post("/", PostHandler)
+	r.Delete("/api/user/urls", DeleteURLBatch)
+	r.Get("/api/user/jobs/{id}", GetDeleteJob)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://www.delete-job.ru")))
+	require.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	key := w.Body.String()[strings.LastIndex(w.Body.String(), "/")+1:]
+
+	request := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["`+key+`"]`))
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, request)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var response model.DeleteJobResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.NotEmpty(t, response.JobID)
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/api/user/jobs/"+response.JobID, location)
+
+	var job model.DeleteJob
+	require.Eventually(t, func() bool {
+		request := httptest.NewRequest(http.MethodGet, location, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+		return job.Finished()
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, model.JobDone, job.Status)
+	assert.Equal(t, []model.DeleteJobKey{{Key: key, Status: model.JobDone}}, job.Keys)
+
+	// задание другого пользователя не доступно
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetHandlerRedirectPolicy(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(security.Auth)
+	r.Post("/api/shorten", PostGenerateShortURL)
+	r.Get("/*", GetHandler)
+
+	shorten := func(body string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body)))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response model.ShortToURLReponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response.ShortURL[strings.LastIndex(response.ShortURL, "/"):]
+	}
+
+	key := shorten(`{"url": "https://seo.ru/page?a=1"}`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, key+"?ref=x", nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "https://seo.ru/page?a=1", w.Header().Get("Location"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	key = shorten(`{"url": "https://seo.ru/permanent", "redirect": {"code": 308}}`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, key, nil))
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+
+	key = shorten(`{"url": "https://track.ru/?a=1", "redirect": {"code": 302, "pass_query": true, "utm": {"source": "mail"}}}`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, key+"?ref=x&utm_source=other", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://track.ru/?a=1&ref=x&utm_source=mail", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "https://bad.ru", "redirect": {"code": 200}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPatchURL(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(security.Auth)
+	r.Post("/", PostHandler)
+	r.Patch("/api/user/urls/{id}", PatchURL)
+	r.Get("/api/user/urls/{id}/history", GetURLHistory)
+	r.Get("/*", GetHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://www.patch-before.ru")))
+	require.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	key := w.Body.String()[strings.LastIndex(w.Body.String(), "/")+1:]
+
+	do := func(method string, target string, body string, withCookies bool) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if withCookies {
+			for _, cookie := range cookies {
+				request.AddCookie(cookie)
+			}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		return w
+	}
+
+	tests := []struct {
+		name string
+		body string
+		auth bool
+		code int
+	}{
+		{name: "empty update", body: `{}`, auth: true, code: http.StatusBadRequest},
+		{name: "no_expiry with ttl", body: `{"no_expiry": true, "ttl": 60}`, auth: true, code: http.StatusBadRequest},
+		{name: "invalid redirect", body: `{"redirect": {"code": 303}}`, auth: true, code: http.StatusBadRequest},
+		{name: "other user", body: `{"url": "https://www.patch-other.ru"}`, auth: false, code: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := do(http.MethodPatch, "/api/user/urls/"+key, test.body, test.auth)
+			assert.Equal(t, test.code, w.Code, w.Body.String())
+		})
+	}
+
+	w = do(http.MethodPatch, "/api/user/urls/"+key, `{"url": "https://www.patch-after.ru", "redirect": {"code": 301}}`, true)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response model.URLResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "https://www.patch-after.ru", response.OriginalURL)
+	assert.True(t, strings.HasSuffix(response.ShortURL, "/"+key))
+
+	w = do(http.MethodGet, "/"+key, "", false)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://www.patch-after.ru", w.Header().Get("Location"))
+
+	w = do(http.MethodGet, "/api/user/urls/"+key+"/history", "", true)
+	require.Equal(t, http.StatusOK, w.Code)
+	var history []model.URLHistory
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&history))
+	require.Len(t, history, 1)
+	assert.Equal(t, "https://www.patch-before.ru", history[0].OriginalURL)
+
+	w = do(http.MethodGet, "/api/user/urls/"+key+"/history", "", false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRestoreURLs(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(security.Auth)
+	r.Post("/", PostHandler)
+	r.Delete("/api/user/urls", DeleteURLBatch)
+	r.Post("/api/user/urls/restore", RestoreURLs)
+	r.Get("/api/user/jobs/{id}", GetDeleteJob)
+	r.Get("/*", GetHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://www.restore.ru")))
+	require.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	key := w.Body.String()[strings.LastIndex(w.Body.String(), "/")+1:]
+
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		return w
+	}
+
+	w = do(http.MethodDelete, "/api/user/urls", `["`+key+`"]`)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	location := w.Header().Get("Location")
+	require.Eventually(t, func() bool {
+		var job model.DeleteJob
+		require.NoError(t, json.NewDecoder(do(http.MethodGet, location, "").Body).Decode(&job))
+		return job.Finished()
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusGone, do(http.MethodGet, "/"+key, "").Code)
+
+	w = do(http.MethodPost, "/api/user/urls/restore", `["`+key+`", "missing"]`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var restored []string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&restored))
+	assert.Equal(t, []string{key}, restored)
+
+	w = do(http.MethodGet, "/"+key, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "https://www.restore.ru", w.Header().Get("Location"))
+
+	w = do(http.MethodPost, "/api/user/urls/restore", `{"keys": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetAllURLPagination(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(security.Auth)
+	r.Post("/", PostHandler)
+	r.Get("/api/user/urls", GetAllURL)
+
+	var cookies []*http.Cookie
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, request)
+		return w
+	}
+
+	for i, original := range []string{"https://www.page-1.ru", "https://www.page-2.ru", "https://www.page-3.ru"} {
+		w := do(http.MethodPost, "/", original)
+		require.Equal(t, http.StatusCreated, w.Code)
+		if i == 0 {
+			cookies = w.Result().Cookies()
+		}
+	}
+
+	var originals []string
+	target := "/api/user/urls?limit=2&sort=url&q=page-"
+	for target != "" {
+		w := do(http.MethodGet, target, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var page []model.UserURL
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+		for _, u := range page {
+			assert.False(t, u.CreatedAt.IsZero())
+			originals = append(originals, u.OriginalURL)
+		}
+
+		target = ""
+		if link := w.Header().Get("Link"); link != "" {
+			assert.Contains(t, link, "cursor="+url.QueryEscape(w.Header().Get("X-Next-Cursor")))
+			assert.True(t, strings.HasSuffix(link, `>; rel="next"`))
+			target = link[1:strings.Index(link, ">")]
+		}
+	}
+	assert.Equal(t, []string{"https://www.page-1.ru", "https://www.page-2.ru", "https://www.page-3.ru"}, originals)
+
+	for _, query := range []string{"limit=abc", "limit=100000", "sort=clicks", "deleted=maybe", "created_from=yesterday", "cursor=bad"} {
+		w := do(http.MethodGet, "/api/user/urls?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
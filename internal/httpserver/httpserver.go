@@ -19,8 +19,8 @@ func (s *HTTP) Run() error {
 }
 
 // Shutdown - остановка http сервера
-func (s *HTTP) Shutdown() error {
-	return s.server.Shutdown(context.Background())
+func (s *HTTP) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
 }
 
 func NewHTTP(addr string) server.Server {
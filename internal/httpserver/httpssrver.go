@@ -19,8 +19,8 @@ func (s *HTTPS) Run() error {
 }
 
 // Shutdown - остановка https сервера
-func (s *HTTPS) Shutdown() error {
-	return s.server.Shutdown(context.Background())
+func (s *HTTPS) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
 }
 
 func NewHTTPS(addr string) server.Server {
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 )
 
 type compressWriter struct {
@@ -89,7 +90,7 @@ func Compress(next http.Handler) http.Handler {
 			resultWriter = compressWriter
 			defer func() {
 				if errClose := compressWriter.Close(); errClose != nil {
-					app.Log.Error("Can't close writer when compress")
+					ctxlog.From(r.Context(), app.Log).Error("Can't close writer when compress")
 				}
 			}()
 		}
@@ -105,7 +106,7 @@ func Compress(next http.Handler) http.Handler {
 			r.Body = cr
 			defer func() {
 				if errClose := cr.Close(); errClose != nil {
-					app.Log.Error("Can't read request when decompress")
+					ctxlog.From(r.Context(), app.Log).Error("Can't read request when decompress")
 				}
 			}()
 		}
@@ -0,0 +1,38 @@
+package instrument
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware"
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+)
+
+const unmatchedRoute = "unmatched"
+
+// Metrics - учет кол-ва и длительности запросов в метриках по шаблону маршрута chi, методу и статусу.
+// Шаблон вместо пути ограничивает кол-во значений метки route
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := &middleware.Writer{ResponseWriter: w}
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := []string{route, r.Method, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
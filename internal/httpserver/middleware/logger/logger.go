@@ -1,11 +1,13 @@
 package logger
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/kirillmashkov/shortener.git/internal/app"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"go.uber.org/zap"
-	"net/http"
-	"time"
 )
 
 // ResponseWrapper - интерфейс для для получения статуса и кол-ва записанныъ байт
@@ -15,27 +17,26 @@ type ResponseWrapper interface {
 	Bytes() int
 }
 
-// Logger - перехватчик запросов для логирования статуса и кол-ва записанных байт
+// Logger - перехватчик запросов для логирования статуса и кол-ва записанных байт.
+// Берет id запроса из заголовка X-Request-ID или выдает новый, возвращает его в ответе
+// и кладет в контекст логгер запроса, которым пишут обработчики, сервис и хранилище
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		t1 := time.Now()
-		cookie, err := r.Cookie("token")
-		var token string
+		requestID := ctxlog.ResolveRequestID(r.Header.Get(ctxlog.Header))
+		w.Header().Set(ctxlog.Header, requestID)
+		ctx := ctxlog.New(r.Context(), app.Log, requestID)
 
-		if err == nil {
-			token = cookie.Value
-		}
-		app.Log.Info("request",
+		ctxlog.From(ctx, app.Log).Info("request",
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
-			zap.String("token", token),
 		)
 
 		ww := wrap(w)
 
-		next.ServeHTTP(ww, r)
+		next.ServeHTTP(ww, r.WithContext(ctx))
 
-		app.Log.Info("response",
+		ctxlog.From(ctx, app.Log).Info("response",
 			zap.Duration("elapsed", time.Since(t1)),
 			zap.Int("status", ww.Status()),
 			zap.Int("content length", ww.Bytes()))
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	app.Log = zap.New(core)
+	t.Cleanup(func() { app.Log = zap.NewNop() })
+
+	h := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxlog.With(r.Context(), zap.Int("user_id", 42))
+		ctxlog.From(r.Context(), app.Log).Info("handler")
+	}))
+
+	t.Run("request id from client", func(t *testing.T) {
+		logs.TakeAll()
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.Header.Set(ctxlog.Header, "req-1")
+		req.AddCookie(&http.Cookie{Name: "token", Value: "secret-token"})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, "req-1", rec.Header().Get(ctxlog.Header))
+
+		entries := logs.AllUntimed()
+		require.Len(t, entries, 3)
+		for _, entry := range entries {
+			fields := entry.ContextMap()
+			assert.Equal(t, "req-1", fields["request_id"], entry.Message)
+			assert.NotContains(t, fields, "token", entry.Message)
+		}
+		assert.NotContains(t, entries[0].ContextMap(), "user_id")
+		assert.Equal(t, int64(42), entries[1].ContextMap()["user_id"])
+		assert.Equal(t, int64(42), entries[2].ContextMap()["user_id"])
+	})
+
+	t.Run("invalid request id replaced", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.Header.Set(ctxlog.Header, "bad id\nforged line")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(ctxlog.Header)
+		assert.Len(t, id, 32)
+		assert.NotEqual(t, "bad id\nforged line", id)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/netip"
 
 	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"go.uber.org/zap"
 )
 
@@ -15,7 +16,7 @@ func IsFromTrustSubnet(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		trustedIPNet, err := netip.ParsePrefix(app.ServerConf.TrustedSubnet)
 		if err != nil {
-			app.Log.Error("Can't parse trustedIP from config", zap.Error(err))
+			ctxlog.From(r.Context(), app.Log).Error("Can't parse trustedIP from config", zap.Error(err))
 			http.Error(w, "Something went wrong", http.StatusForbidden)
 			return
 		}
@@ -23,13 +24,13 @@ func IsFromTrustSubnet(next http.Handler) http.Handler {
 		ipStr := r.Header.Get(realIPHeader)
 		ip, err := netip.ParseAddr(ipStr)
 		if err != nil {
-			app.Log.Error("Can't parse trustedIP from header", zap.Error(err))
+			ctxlog.From(r.Context(), app.Log).Error("Can't parse trustedIP from header", zap.Error(err))
 			http.Error(w, "Something went wrong", http.StatusForbidden)
 			return
 		}
 
 		if !trustedIPNet.Contains(ip) {
-			app.Log.Error("subnet in config doesn't contain ip in header", zap.Error(err))
+			ctxlog.From(r.Context(), app.Log).Error("subnet in config doesn't contain ip in header", zap.Error(err))
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
@@ -0,0 +1,45 @@
+package security
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+)
+
+func tokenExp() time.Duration {
+	return app.ServerConf.TokenExp
+}
+
+// newCookie - cookie с токеном и атрибутами из конфигурации приложения
+func newCookie(token string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     "token",
+		Value:    token,
+		Path:     "/",
+		Domain:   app.ServerConf.CookieDomain,
+		HttpOnly: app.ServerConf.CookieHTTPOnly,
+		Secure:   app.ServerConf.CookieSecure,
+		SameSite: sameSite(app.ServerConf.CookieSameSite),
+	}
+
+	if exp := tokenExp(); exp > 0 {
+		cookie.MaxAge = int(exp.Seconds())
+	}
+
+	return cookie
+}
+
+func sameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
@@ -2,7 +2,6 @@ package security
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 
 	"math/rand"
@@ -11,6 +10,8 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/authkeys"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"go.uber.org/zap"
 )
 
@@ -23,13 +24,20 @@ type Claims struct {
 // UserIDType - тип для сохранения в контексте запроса id пользователя
 type UserIDType string
 
-const tokenExp = time.Hour * 3
-const secretKey = "supersecretkey"
+// issuedKey - ключ контекста запроса с признаком, что токен выдан этим запросом
+type issuedKey struct{}
+
+// unauthenticatedPaths - проверки здоровья, для которых токен не выдается
+var unauthenticatedPaths = map[string]struct{}{
+	"/healthz": {},
+	"/readyz":  {},
+}
 
 // Auth - middleware для получения токена из заголовков. Если токена нет, выдает его и записывает в контекст запроса
 func Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.Contains(r.URL.Path, "debug") {
+		_, unauthenticated := unauthenticatedPaths[r.URL.Path]
+		if unauthenticated || strings.Contains(r.URL.Path, "debug") {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -42,50 +50,75 @@ func Auth(next http.Handler) http.Handler {
 
 		jwtToken, userID, err, newToken := getJWT(cookie)
 		if err != nil {
-			app.Log.Error("Error get token", zap.Error(err))
+			ctxlog.From(r.Context(), app.Log).Error("Error get token", zap.Error(err))
 			http.Error(w, "Something went wrong", http.StatusBadRequest)
 			return
 		}
 
-		u := UserIDType("userID")
-		c := context.WithValue(r.Context(), u, userID)
+		ctxlog.With(r.Context(), zap.Int("user_id", userID))
+		c := WithUser(r.Context(), userID, newToken)
 
 		if newToken {
-			resCookie := http.Cookie{Name: "token", Value: jwtToken}
-			http.SetCookie(w, &resCookie)
+			http.SetCookie(w, newCookie(jwtToken))
 		}
 
 		next.ServeHTTP(w, r.WithContext(c))
 	})
 }
 
+// WithUser - запись в контекст запроса id пользователя и признака, что токен выдан этим запросом
+func WithUser(ctx context.Context, userID int, issued bool) context.Context {
+	ctx = context.WithValue(ctx, UserIDType("userID"), userID)
+	return context.WithValue(ctx, issuedKey{}, issued)
+}
+
+// KnownUser - id пользователя запроса, предъявившего валидный токен, выданный ранее.
+// Для токена, выданного этим запросом, ok = false: новые токены выдаются бесплатно,
+// и клиент мог бы обходить лимиты по пользователю, не возвращая токен
+func KnownUser(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(UserIDType("userID")).(int)
+	if !ok {
+		return 0, false
+	}
+
+	issued, _ := ctx.Value(issuedKey{}).(bool)
+	return userID, !issued
+}
+
 func getJWT(cookie *http.Cookie) (string, int, error, bool) {
 	if cookie == nil {
-		tokenString, userID, err := buildJWTString()
+		tokenString, userID, err := NewToken()
 		return tokenString, userID, err, true
 	}
 
-	checkJWT, userID := сheckJWT(cookie)
+	userID, ok := ParseToken(cookie.Value)
 
-	if checkJWT {
+	if ok {
 		return cookie.Value, userID, nil, false
 	}
 
-	tokenString, userID, err := buildJWTString()
+	tokenString, userID, err := NewToken()
 	return tokenString, userID, err, true
 }
 
-func buildJWTString() (string, int, error) {
+// NewToken - выпуск токена для нового пользователя, возвращает токен и id пользователя
+func NewToken() (string, int, error) {
+	if app.AuthKeys == nil {
+		return "", 0, authkeys.ErrNoSecret
+	}
+	kid, key := app.AuthKeys.SigningKey()
+
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	userID := r.Int()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExp)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExp())),
 		},
 		UserID: userID,
 	})
+	token.Header[authkeys.KidHeader] = kid
 
-	tokenString, err := token.SignedString([]byte(secretKey))
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", 0, err
 	}
@@ -93,36 +126,31 @@ func buildJWTString() (string, int, error) {
 	return tokenString, userID, nil
 }
 
-func сheckJWT(cookie *http.Cookie) (bool, int) {
-	if cookie == nil {
-		app.Log.Warn("Token is empty")
-		return true, -1
+// ParseToken - проверка токена, возвращает id пользователя и признак валидности токена
+func ParseToken(tokenString string) (int, bool) {
+	if app.AuthKeys == nil {
+		app.Log.Error("Can't parse token", zap.Error(authkeys.ErrNoSecret))
+		return 0, false
 	}
 
 	claims := &Claims{UserID: -1}
-	token, err := jwt.ParseWithClaims(cookie.Value, claims,
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return []byte(secretKey), nil
-		})
+	token, err := jwt.ParseWithClaims(tokenString, claims, app.AuthKeys.KeyFunc)
 
 	if err != nil {
-		app.Log.Warn("Can't parse token")
-		return false, 0
+		app.Log.Warn("Can't parse token", zap.Error(err))
+		return 0, false
 	}
 
 	if !token.Valid {
 		app.Log.Warn("Token is not valid")
-		return false, 0
+		return 0, false
 	}
 
 	if claims.UserID == -1 {
 		app.Log.Warn("Token doesn't contain UserID")
-		return false, 0
+		return 0, false
 	}
 
 	app.Log.Info("Token is valid", zap.Int("UserID", claims.UserID))
-	return true, claims.UserID
+	return claims.UserID, true
 }
@@ -0,0 +1,80 @@
+package security
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/authkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRotation(t *testing.T) {
+	restoreServerConf(t)
+	app.ServerConf.TokenExp = time.Hour
+	useKeys(t, "2024", "old-secret", "")
+
+	oldToken, userID, err := NewToken()
+	require.NoError(t, err)
+
+	useKeys(t, "2025", "new-secret", "")
+
+	_, ok := ParseToken(oldToken)
+	assert.False(t, ok, "token of unknown key must be rejected")
+
+	useKeys(t, "2025", "new-secret", "2024:old-secret")
+
+	gotUserID, ok := ParseToken(oldToken)
+	assert.True(t, ok, "token of previous key must be accepted while the key is listed")
+	assert.Equal(t, userID, gotUserID)
+
+	newToken, newUserID, err := NewToken()
+	require.NoError(t, err)
+
+	gotUserID, ok = ParseToken(newToken)
+	assert.True(t, ok)
+	assert.Equal(t, newUserID, gotUserID)
+}
+
+func TestNewCookie(t *testing.T) {
+	restoreServerConf(t)
+	app.ServerConf.TokenExp = time.Hour
+	app.ServerConf.CookieHTTPOnly = true
+	app.ServerConf.CookieSecure = true
+	app.ServerConf.CookieSameSite = "Strict"
+	app.ServerConf.CookieDomain = "example.com"
+
+	cookie := newCookie("value")
+
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.Equal(t, 3600, cookie.MaxAge)
+}
+
+// useKeys - замена ключей подписи токенов на время теста
+func useKeys(t *testing.T, activeID string, secret string, verifyKeys string) {
+	t.Helper()
+
+	keys, err := authkeys.Parse(activeID, secret, verifyKeys)
+	require.NoError(t, err)
+
+	saved := app.AuthKeys
+	app.AuthKeys = keys
+	t.Cleanup(func() {
+		app.AuthKeys = saved
+	})
+}
+
+// restoreServerConf - возврат конфигурации приложения, измененной тестом
+func restoreServerConf(t *testing.T) {
+	t.Helper()
+
+	saved := app.ServerConf
+	t.Cleanup(func() {
+		app.ServerConf = saved
+	})
+}
@@ -0,0 +1,49 @@
+package throttle
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+const realIPHeader = "X-Real-IP"
+
+// Limit - middleware ограничения частоты запросов группы group, подключается после security.Auth.
+// Клиент с ранее выданным валидным токеном ограничивается по id пользователя, без него - по ip соединения
+// или X-Real-IP доверенного прокси.
+// При превышении лимита отвечает 429 с Retry-After
+func Limit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if app.RateLimit == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := clientKey(r)
+			allowed, delay := app.RateLimit.Allow(group, key, time.Now())
+			if !allowed {
+				ctxlog.From(r.Context(), app.Log).Debug("Rate limit exceeded", zap.String("group", group), zap.String("client", key))
+				w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(delay)))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientKey(r *http.Request) string {
+	if userID, ok := security.KnownUser(r.Context()); ok {
+		return ratelimit.UserKey(userID)
+	}
+
+	return ratelimit.IPKey(app.TrustedProxies.ClientIP(r.RemoteAddr, r.Header.Get(realIPHeader)))
+}
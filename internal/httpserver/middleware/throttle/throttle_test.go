@@ -0,0 +1,92 @@
+package throttle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit(t *testing.T) {
+	app.RateLimit = ratelimit.NewPolicy(map[string]ratelimit.Limit{ratelimit.GroupShorten: {RPS: 1, Burst: 1}})
+	defer func() { app.RateLimit = nil }()
+
+	handler := Limit(ratelimit.GroupShorten)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	request := func(remoteAddr string, realIP string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set(realIPHeader, realIP)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, request("10.0.0.1:1234", "").Code)
+
+	res := request("10.0.0.1:1234", "")
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1234", "10.0.0.9").Code,
+		"X-Real-IP of untrusted client must be ignored")
+	assert.Equal(t, http.StatusCreated, request("10.0.0.2:1234", "").Code)
+}
+
+func TestLimitTrustedProxy(t *testing.T) {
+	app.RateLimit = ratelimit.NewPolicy(map[string]ratelimit.Limit{ratelimit.GroupShorten: {RPS: 1, Burst: 1}})
+	proxies, err := ratelimit.ParseProxies("127.0.0.0/8")
+	require.NoError(t, err)
+	app.TrustedProxies = proxies
+	defer func() {
+		app.RateLimit = nil
+		app.TrustedProxies = nil
+	}()
+
+	handler := Limit(ratelimit.GroupShorten)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	request := func(realIP string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		r.Header.Set(realIPHeader, realIP)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, request("10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1"))
+	assert.Equal(t, http.StatusCreated, request("10.0.0.2"), "clients behind trusted proxy have own buckets")
+}
+
+func TestLimitByKnownUser(t *testing.T) {
+	app.RateLimit = ratelimit.NewPolicy(map[string]ratelimit.Limit{ratelimit.GroupShorten: {RPS: 1, Burst: 1}})
+	defer func() { app.RateLimit = nil }()
+
+	handler := Limit(ratelimit.GroupShorten)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	request := func(userID int, issued bool) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r = r.WithContext(security.WithUser(r.Context(), userID, issued))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, request(1, false))
+	assert.Equal(t, http.StatusCreated, request(2, false), "users with own tokens have own buckets")
+	assert.Equal(t, http.StatusCreated, request(3, true))
+	assert.Equal(t, http.StatusTooManyRequests, request(4, true), "fresh tokens must be limited by ip")
+}
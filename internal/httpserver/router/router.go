@@ -6,9 +6,12 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/handler"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/compress"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/instrument"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/logger"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/net"
 	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/throttle"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
 
 	"net/http/pprof"
 )
@@ -16,6 +19,8 @@ import (
 // Serv - роутер REST запросов
 func Serv() http.Handler {
 	r := chi.NewRouter()
+	r.Use(instrument.Tracing)
+	r.Use(instrument.Metrics)
 	r.Use(logger.Logger)
 	r.Use(compress.Compress)
 	r.Use(security.Auth)
@@ -27,17 +32,35 @@ func Serv() http.Handler {
 	r.Get("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
 	r.Get("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 
-	r.Post("/", handler.PostHandler)
-	r.Get("/{id}", handler.GetHandler)
-	r.Get("/api/user/urls", handler.GetAllURL)
-	r.Post("/api/shorten", handler.PostGenerateShortURL)
-	r.Post("/api/shorten/batch", handler.PostGenerateShortURLBatch)
-	r.Delete("/api/user/urls", handler.DeleteURLBatch)
-	r.Get("/ping", handler.Ping)
+	r.Get("/healthz", handler.Healthz)
+	r.Get("/readyz", handler.Readyz)
+
+	r.With(throttle.Limit(ratelimit.GroupRedirect)).Get("/{id}", handler.GetHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(throttle.Limit(ratelimit.GroupShorten))
+		r.Post("/", handler.PostHandler)
+		r.Post("/api/shorten", handler.PostGenerateShortURL)
+		r.Post("/api/shorten/batch", handler.PostGenerateShortURLBatch)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(throttle.Limit(ratelimit.GroupAPI))
+		r.Get("/api/user/urls", handler.GetAllURL)
+		r.Get("/api/user/urls/{id}/stats", handler.GetURLStats)
+		r.Patch("/api/user/urls/{id}", handler.PatchURL)
+		r.Get("/api/user/urls/{id}/history", handler.GetURLHistory)
+		r.Delete("/api/user/urls", handler.DeleteURLBatch)
+		r.Post("/api/user/urls/restore", handler.RestoreURLs)
+		r.Get("/api/user/jobs/{id}", handler.GetDeleteJob)
+		r.Get("/ping", handler.Ping)
+	})
 
 	r.Group(func(r chi.Router) {
 		r.Use(net.IsFromTrustSubnet)
 		r.Get("/api/internal/stats", handler.Stats)
+		r.Post("/api/internal/storage/compact", handler.CompactStorage)
+		r.Get("/api/internal/cache/stats", handler.CacheStats)
 	})
 
 	return r
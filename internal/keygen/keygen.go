@@ -0,0 +1,121 @@
+// Модуль keygen - стратегии генерации ключей коротких ссылок
+package keygen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Стратегии генерации ключей
+const (
+	// StrategyRandom - случайный ключ из crypto/rand
+	StrategyRandom = "random"
+	// StrategyCounter - номер из последовательности хранилища в системе счисления по алфавиту
+	StrategyCounter = "counter"
+	// StrategyHash - ключ из хэша исходной ссылки, одна и та же ссылка получает один и тот же ключ
+	StrategyHash = "hash"
+	// StrategySqids - номер из последовательности, перемешанный так, что соседние номера дают непохожие ключи
+	StrategySqids = "sqids"
+)
+
+// Алфавиты по умолчанию
+const (
+	// AlphabetUpper - заглавные латинские буквы и цифры, для стратегий random и hash
+	AlphabetUpper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// AlphabetBase62 - цифры и латинские буквы, для стратегий counter и sqids
+	AlphabetBase62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// MaxLength - максимальная длина ключа
+const MaxLength = 32
+
+// ErrUnknownStrategy - неизвестная стратегия генерации ключей
+var ErrUnknownStrategy = errors.New("unknown key strategy")
+
+// Generator - генератор ключей коротких ссылок
+type Generator interface {
+	// Key - ключ для исходной ссылки. attempt - номер попытки начиная с 0:
+	// если ключ оказался занят, он запрашивается повторно со следующим номером попытки
+	Key(ctx context.Context, originalURL string, attempt int) (string, error)
+}
+
+// Sequence - источник возрастающих номеров, общий для всех экземпляров сервиса
+type Sequence interface {
+	NextSequence(ctx context.Context) (uint64, error)
+}
+
+// Config - стратегия, длина ключа и алфавит. Пустой алфавит - алфавит стратегии по умолчанию.
+// Для стратегий по номеру длина минимальная: когда номера не помещаются, ключи становятся длиннее
+type Config struct {
+	Strategy string
+	Length   int
+	Alphabet string
+}
+
+// New - генератор по конфигурации. sequence используется стратегиями counter и sqids
+func New(cfg Config, sequence Sequence) (Generator, error) {
+	alphabet := cfg.Alphabet
+	if alphabet == "" {
+		alphabet = AlphabetUpper
+		if cfg.Strategy == StrategyCounter || cfg.Strategy == StrategySqids {
+			alphabet = AlphabetBase62
+		}
+	}
+	if err := validate(alphabet, cfg.Length); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Strategy {
+	case StrategyRandom:
+		return NewRandom(alphabet, cfg.Length), nil
+	case StrategyHash:
+		return NewHash(alphabet, cfg.Length), nil
+	case StrategyCounter, StrategySqids:
+		if sequence == nil {
+			return nil, fmt.Errorf("key strategy %s: storage has no sequence", cfg.Strategy)
+		}
+		if cfg.Strategy == StrategyCounter {
+			return NewCounter(alphabet, cfg.Length, sequence), nil
+		}
+		return NewSqids(alphabet, cfg.Length, sequence), nil
+	}
+
+	return nil, fmt.Errorf("%w %q, expected random, counter, hash or sqids", ErrUnknownStrategy, cfg.Strategy)
+}
+
+// validate - в алфавите не меньше двух неповторяющихся символов, допустимых в пути URL без экранирования
+func validate(alphabet string, length int) error {
+	if length < 1 || length > MaxLength {
+		return fmt.Errorf("key length must be from 1 to %d", MaxLength)
+	}
+	if len(alphabet) < 2 {
+		return errors.New("key alphabet must have at least 2 symbols")
+	}
+
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("key alphabet symbol %q is not allowed, use latin letters, digits, '-' and '_'", c)
+		}
+		if strings.IndexByte(alphabet[i+1:], c) >= 0 {
+			return fmt.Errorf("key alphabet symbol %q is repeated", c)
+		}
+	}
+
+	return nil
+}
+
+// encode - запись числа n в системе счисления по алфавиту, дополненная слева до minLen нулевым символом алфавита
+func encode(n uint64, alphabet string, minLen int) string {
+	base := uint64(len(alphabet))
+	var buf [64]byte
+	i := len(buf)
+	for n > 0 || len(buf)-i < minLen {
+		i--
+		buf[i] = alphabet[n%base]
+		n /= base
+	}
+	return string(buf[i:])
+}
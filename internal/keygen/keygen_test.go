@@ -0,0 +1,133 @@
+package keygen
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSequence struct {
+	n atomic.Uint64
+}
+
+func (s *testSequence) NextSequence(ctx context.Context) (uint64, error) {
+	return s.n.Add(1), nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "random", cfg: Config{Strategy: StrategyRandom, Length: 8}},
+		{name: "hash", cfg: Config{Strategy: StrategyHash, Length: 8}},
+		{name: "counter", cfg: Config{Strategy: StrategyCounter, Length: 6}},
+		{name: "sqids", cfg: Config{Strategy: StrategySqids, Length: 6, Alphabet: "abcdef0123"}},
+		{name: "unknown strategy", cfg: Config{Strategy: "uuid", Length: 8}, wantErr: true},
+		{name: "zero length", cfg: Config{Strategy: StrategyRandom}, wantErr: true},
+		{name: "too long", cfg: Config{Strategy: StrategyRandom, Length: MaxLength + 1}, wantErr: true},
+		{name: "one symbol", cfg: Config{Strategy: StrategyRandom, Length: 8, Alphabet: "a"}, wantErr: true},
+		{name: "repeated symbol", cfg: Config{Strategy: StrategyRandom, Length: 8, Alphabet: "abca"}, wantErr: true},
+		{name: "not url safe", cfg: Config{Strategy: StrategyRandom, Length: 8, Alphabet: "ab/c"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, &testSequence{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	_, err := New(Config{Strategy: StrategyCounter, Length: 6}, nil)
+	assert.Error(t, err, "counter needs storage sequence")
+}
+
+func TestRandom(t *testing.T) {
+	ctx := context.Background()
+
+	small := NewRandom("abc", 12)
+	for range 100 {
+		key, err := small.Key(ctx, "", 0)
+		require.NoError(t, err)
+		require.Len(t, key, 12)
+		require.Empty(t, strings.Trim(key, "abc"), "key must use only alphabet symbols")
+	}
+
+	g := NewRandom(AlphabetUpper, 8)
+	seen := map[string]struct{}{}
+	for range 10000 {
+		key, err := g.Key(ctx, "", 0)
+		require.NoError(t, err)
+		seen[key] = struct{}{}
+	}
+	assert.Len(t, seen, 10000)
+}
+
+func TestHash(t *testing.T) {
+	g := NewHash(AlphabetUpper, 8)
+	ctx := context.Background()
+
+	first, err := g.Key(ctx, "http://a.ru", 0)
+	require.NoError(t, err)
+	again, err := g.Key(ctx, "http://a.ru", 0)
+	require.NoError(t, err)
+	retry, err := g.Key(ctx, "http://a.ru", 1)
+	require.NoError(t, err)
+	other, err := g.Key(ctx, "http://b.ru", 0)
+	require.NoError(t, err)
+
+	assert.Len(t, first, 8)
+	assert.Equal(t, first, again, "same url must get same key")
+	assert.NotEqual(t, first, retry, "retry after collision must get another key")
+	assert.NotEqual(t, first, other)
+}
+
+func TestCounter(t *testing.T) {
+	g := NewCounter("01", 2, &testSequence{})
+	var keys []string
+	for range 5 {
+		key, err := g.Key(context.Background(), "", 0)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"01", "10", "11", "100", "101"}, keys)
+}
+
+func TestSqids(t *testing.T) {
+	t.Run("permutation of key space", func(t *testing.T) {
+		g := NewSqids("abc", 3, &testSequence{})
+		seen := map[string]struct{}{}
+		for range 26 {
+			key, err := g.Key(context.Background(), "", 0)
+			require.NoError(t, err)
+			require.Len(t, key, 3)
+			seen[key] = struct{}{}
+		}
+		assert.Len(t, seen, 26, "numbers 1..26 must get distinct keys")
+
+		key, err := g.Key(context.Background(), "", 0)
+		require.NoError(t, err)
+		assert.Len(t, key, 4, "key grows when numbers do not fit length")
+	})
+
+	t.Run("keys do not reveal order", func(t *testing.T) {
+		g := NewSqids(AlphabetBase62, 6, &testSequence{})
+		prev, err := g.Key(context.Background(), "", 0)
+		require.NoError(t, err)
+		for range 100 {
+			key, err := g.Key(context.Background(), "", 0)
+			require.NoError(t, err)
+			require.Len(t, key, 6)
+			assert.NotEqual(t, prev[:5], key[:5], "neighbour numbers must differ not only in last symbol")
+			prev = key
+		}
+	})
+}
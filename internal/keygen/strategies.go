@@ -0,0 +1,185 @@
+package keygen
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strconv"
+)
+
+// Random - случайные ключи из crypto/rand. Символы выбираются равновероятно
+type Random struct {
+	alphabet string
+	length   int
+}
+
+// NewRandom - конструктор
+func NewRandom(alphabet string, length int) *Random {
+	return &Random{alphabet: alphabet, length: length}
+}
+
+// Key - новый случайный ключ на каждую попытку
+func (g *Random) Key(ctx context.Context, originalURL string, attempt int) (string, error) {
+	// байты не меньше limit отбрасываются, чтобы остаток от деления на размер алфавита был равновероятным
+	limit := 256 - 256%len(g.alphabet)
+	key := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+	for len(key) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			key = append(key, g.alphabet[int(b)%len(g.alphabet)])
+			if len(key) == g.length {
+				break
+			}
+		}
+	}
+
+	return string(key), nil
+}
+
+// Hash - ключ из SHA-256 исходной ссылки. При коллизии номер попытки добавляется к хэшируемой строке
+type Hash struct {
+	alphabet string
+	length   int
+}
+
+// NewHash - конструктор
+func NewHash(alphabet string, length int) *Hash {
+	return &Hash{alphabet: alphabet, length: length}
+}
+
+// Key - ключ исходной ссылки для попытки attempt
+func (g *Hash) Key(ctx context.Context, originalURL string, attempt int) (string, error) {
+	data := originalURL
+	if attempt > 0 {
+		data += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(data))
+
+	n := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+	key := make([]byte, g.length)
+	for i := range key {
+		n.DivMod(n, base, digit)
+		key[i] = g.alphabet[digit.Int64()]
+	}
+
+	return string(key), nil
+}
+
+// Counter - номер из последовательности в системе счисления по алфавиту, например base62
+type Counter struct {
+	alphabet string
+	length   int
+	sequence Sequence
+}
+
+// NewCounter - конструктор
+func NewCounter(alphabet string, length int, sequence Sequence) *Counter {
+	return &Counter{alphabet: alphabet, length: length, sequence: sequence}
+}
+
+// Key - ключ следующего номера последовательности
+func (g *Counter) Key(ctx context.Context, originalURL string, attempt int) (string, error) {
+	n, err := g.sequence.NextSequence(ctx)
+	if err != nil {
+		return "", err
+	}
+	return encode(n, g.alphabet, g.length), nil
+}
+
+// Sqids - номер из последовательности, переставленный внутри пространства ключей своей длины
+// и записанный перемешанным алфавитом, по аналогии с Sqids. Ключи уникальны, но не раскрывают
+// кол-во ссылок и порядок создания. Перестановка не является шифрованием
+type Sqids struct {
+	alphabet   string
+	length     int
+	multiplier uint64
+	offset     uint64
+	sequence   Sequence
+}
+
+// sqidsMultiplier - нечетная константа золотого сечения, начальный множитель перестановки
+const sqidsMultiplier = 0x9E3779B97F4A7C15
+
+// NewSqids - конструктор. Перемешивание алфавита и параметры перестановки зависят только от алфавита
+func NewSqids(alphabet string, length int, sequence Sequence) *Sqids {
+	seed := sha256.Sum256([]byte(alphabet))
+	base := uint64(len(alphabet))
+
+	// множитель взаимно прост с основанием, а значит и с размером пространства ключей любой длины
+	multiplier := uint64(sqidsMultiplier)
+	for gcd(multiplier, base) != 1 {
+		multiplier += 2
+	}
+
+	return &Sqids{
+		alphabet:   shuffle(alphabet, seed),
+		length:     length,
+		multiplier: multiplier,
+		offset:     binary.BigEndian.Uint64(seed[8:16]),
+		sequence:   sequence,
+	}
+}
+
+// Key - ключ следующего номера последовательности
+func (g *Sqids) Key(ctx context.Context, originalURL string, attempt int) (string, error) {
+	n, err := g.sequence.NextSequence(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	base := uint64(len(g.alphabet))
+	size := uint64(1)
+	digits := 0
+	for digits < g.length || size <= n {
+		hi, lo := bits.Mul64(size, base)
+		if hi != 0 {
+			return "", fmt.Errorf("sequence number %d does not fit key space", n)
+		}
+		size = lo
+		digits++
+	}
+
+	// (n * multiplier + offset) mod size - перестановка чисел [0, size)
+	hi, lo := bits.Mul64(n%size, g.multiplier%size)
+	product := bits.Rem64(hi, lo, size)
+	permuted := product + g.offset%size
+	if offset := g.offset % size; product >= size-offset {
+		permuted = product - (size - offset)
+	}
+
+	return encode(permuted, g.alphabet, digits), nil
+}
+
+// shuffle - детерминированное перемешивание алфавита по seed
+func shuffle(alphabet string, seed [32]byte) string {
+	result := []byte(alphabet)
+	state := binary.BigEndian.Uint64(seed[:8])
+	for i := len(result) - 1; i > 0; i-- {
+		// xorshift64
+		state ^= state << 13
+		state ^= state >> 7
+		state ^= state << 17
+		j := int(state % uint64(i+1))
+		result[i], result[j] = result[j], result[i]
+	}
+	return string(result)
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,166 @@
+// Модуль lifecycle - запуск фоновых задач и упорядоченная остановка приложения:
+// сначала прекращается прием запросов, затем дорабатываются принятые, останавливаются
+// фоновые задачи и только после этого закрывается хранилище
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Phase - фаза остановки. Фазы выполняются по возрастанию, шаги одной фазы - параллельно
+type Phase int
+
+// Фазы остановки
+const (
+	// PhaseServers - остановка серверов, новые запросы не принимаются
+	PhaseServers Phase = iota
+	// PhaseDrain - обработка запросов, принятых в очереди до остановки серверов
+	PhaseDrain
+	// PhaseTasks - остановка фоновых задач, запущенных через Go
+	PhaseTasks
+	// PhaseStorage - закрытие хранилища
+	PhaseStorage
+	// PhaseTelemetry - выгрузка накопленной телеметрии
+	PhaseTelemetry
+)
+
+var phaseNames = map[Phase]string{
+	PhaseServers:   "servers",
+	PhaseDrain:     "drain",
+	PhaseTasks:     "tasks",
+	PhaseStorage:   "storage",
+	PhaseTelemetry: "telemetry",
+}
+
+// String - название фазы для логов
+func (p Phase) String() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("phase %d", int(p))
+}
+
+// step - шаг остановки
+type step struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// Manager - фоновые задачи и шаги остановки приложения
+type Manager struct {
+	log    *zap.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	tasks  sync.WaitGroup
+
+	mu       sync.Mutex
+	steps    map[Phase][]step
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// New - конструктор. Контекст фоновых задач производный от ctx,
+// taskTimeout - время ожидания завершения фоновых задач после отмены их контекста
+func New(ctx context.Context, log *zap.Logger, taskTimeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(ctx)
+	m := &Manager{log: log, ctx: ctx, cancel: cancel, steps: map[Phase][]step{}}
+	m.OnStop(PhaseTasks, "background tasks", taskTimeout, m.stopTasks)
+	return m
+}
+
+// Context - контекст фоновых задач, отменяется в фазе PhaseTasks
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Go - запуск фоновой задачи. Задача должна завершиться после отмены ctx
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	m.tasks.Add(1)
+	go func() {
+		defer m.tasks.Done()
+		run(m.ctx)
+		m.log.Debug("Background task stopped", zap.String("task", name))
+	}()
+}
+
+// OnStop - добавление шага остановки в фазу phase. Шаг должен завершиться за timeout
+func (m *Manager) OnStop(phase Phase, name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.steps[phase] = append(m.steps[phase], step{name: name, timeout: timeout, stop: stop})
+}
+
+// Stop - выполнение шагов остановки по фазам. Ошибка или таймаут шага не прерывает остановку,
+// все ошибки возвращаются вместе. Повторный вызов возвращает результат первого
+func (m *Manager) Stop() error {
+	m.stopOnce.Do(func() {
+		m.mu.Lock()
+		steps := m.steps
+		m.steps = map[Phase][]step{}
+		m.mu.Unlock()
+
+		var errs []error
+		for phase := PhaseServers; phase <= PhaseTelemetry; phase++ {
+			errs = append(errs, m.runPhase(phase, steps[phase])...)
+		}
+		m.stopErr = errors.Join(errs...)
+	})
+
+	return m.stopErr
+}
+
+func (m *Manager) runPhase(phase Phase, steps []step) []error {
+	errs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i, s := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = m.runStep(phase, s)
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func (m *Manager) runStep(phase Phase, s step) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.stop(ctx)
+	if err != nil {
+		m.log.Error("Shutdown step failed", zap.Stringer("phase", phase), zap.String("step", s.name), zap.Error(err))
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+
+	m.log.Info("Shutdown step done", zap.Stringer("phase", phase), zap.String("step", s.name), zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// stopTasks - отмена контекста фоновых задач и ожидание их завершения
+func (m *Manager) stopTasks(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,60 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStopOrder(t *testing.T) {
+	m := New(context.Background(), zap.NewNop(), time.Second)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	m.OnStop(PhaseTelemetry, "tracing", time.Second, record("tracing"))
+	m.OnStop(PhaseStorage, "storage", time.Second, record("storage"))
+	m.OnStop(PhaseDrain, "delete queue", time.Second, record("delete queue"))
+	m.OnStop(PhaseServers, "http", time.Second, record("http"))
+	m.Go("task", func(ctx context.Context) {
+		<-ctx.Done()
+		record("task")(ctx)
+	})
+
+	require.NoError(t, m.Stop())
+	assert.Equal(t, []string{"http", "delete queue", "task", "storage", "tracing"}, order)
+	require.NoError(t, m.Stop(), "repeated stop must be no-op")
+	assert.Len(t, order, 5)
+}
+
+func TestStopContinuesAfterTimeout(t *testing.T) {
+	m := New(context.Background(), zap.NewNop(), time.Second)
+
+	closed := false
+	m.OnStop(PhaseServers, "stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	m.OnStop(PhaseStorage, "storage", time.Second, func(context.Context) error {
+		closed = true
+		return nil
+	})
+
+	err := m.Stop()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stuck")
+	assert.True(t, closed, "storage must be closed even if previous step timed out")
+}
@@ -0,0 +1,90 @@
+// Модуль ctxlog - логгер запроса в контексте. Все строки лога одного запроса содержат
+// его request_id, а после авторизации - user_id, так что запрос можно найти одним поиском
+package ctxlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Header - заголовок HTTP (и ключ метаданных gRPC) с id запроса
+const Header = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type ctxKey struct{}
+
+// entry - логгер запроса. Общий для всех контекстов, производных от контекста запроса,
+// поэтому поля, добавленные внутренними обработчиками, видны и внешним
+type entry struct {
+	mu        sync.Mutex
+	log       *zap.Logger
+	requestID string
+}
+
+// New - контекст с логгером запроса: log с полем request_id
+func New(ctx context.Context, log *zap.Logger, requestID string) context.Context {
+	e := &entry{log: log.With(zap.String("request_id", requestID)), requestID: requestID}
+	return context.WithValue(ctx, ctxKey{}, e)
+}
+
+// From - логгер запроса из контекста, если его нет - fallback
+func From(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok {
+		return fallback
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.log
+}
+
+// With - добавление полей в логгер запроса, например user_id после авторизации
+func With(ctx context.Context, fields ...zap.Field) {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log = e.log.With(fields...)
+}
+
+// RequestID - id запроса из контекста
+func RequestID(ctx context.Context) string {
+	if e, ok := ctx.Value(ctxKey{}).(*entry); ok {
+		return e.requestID
+	}
+	return ""
+}
+
+// ResolveRequestID - id запроса от клиента, если он допустим, иначе новый случайный
+func ResolveRequestID(fromClient string) string {
+	if validRequestID(fromClient) {
+		return fromClient
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID - непустой, не длиннее maxRequestIDLength, из печатных ASCII символов без пробелов,
+// чтобы клиент не мог подделать строки лога
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+// Модуль metrics - метрики Prometheus приложения, отдаются отдельным admin сервером
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+// Registry - реестр метрик приложения
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// HTTPRequests - кол-во REST запросов по шаблону маршрута, методу и статусу ответа
+var HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "requests_total",
+	Help:      "Number of HTTP requests by route, method and status.",
+}, []string{"route", "method", "status"})
+
+// HTTPDuration - время обработки REST запросов
+var HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "request_duration_seconds",
+	Help:      "HTTP request latency by route, method and status.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"route", "method", "status"})
+
+// GRPCRequests - кол-во gRPC вызовов по методу и коду ответа
+var GRPCRequests = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "grpc",
+	Name:      "requests_total",
+	Help:      "Number of gRPC calls by method and code.",
+}, []string{"method", "code"})
+
+// GRPCDuration - время обработки gRPC вызовов
+var GRPCDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "grpc",
+	Name:      "request_duration_seconds",
+	Help:      "gRPC call latency by method and code.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "code"})
+
+// StorageDuration - время операций хранилища по драйверу и операции
+var StorageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "storage",
+	Name:      "operation_duration_seconds",
+	Help:      "Storage operation latency by backend and operation.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+}, []string{"backend", "operation"})
+
+// StorageErrors - кол-во ошибок операций хранилища по драйверу и операции
+var StorageErrors = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "storage",
+	Name:      "operation_errors_total",
+	Help:      "Number of failed storage operations by backend and operation.",
+}, []string{"backend", "operation"})
+
+// DeleteQueueDepth - кол-во запросов на удаление, ожидающих в очереди
+var DeleteQueueDepth = factory.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "delete_queue_depth",
+	Help:      "Number of delete requests waiting in the queue.",
+})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveStorage - учет длительности и ошибки операции хранилища
+func ObserveStorage(backend string, operation string, start time.Time, err error) {
+	StorageDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		StorageErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// Handler - обработчик /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
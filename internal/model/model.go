@@ -2,12 +2,19 @@ package model
 
 import (
 	"errors"
-	"sync"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 // URLToShortRequest - запрос с исходной ссылкой
 type URLToShortRequest struct {
-	OriginalURL string `json:"url"`
+	OriginalURL string     `json:"url"`
+	Alias       string     `json:"alias,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTL         int64      `json:"ttl,omitempty"`
+	Redirect    *Redirect  `json:"redirect,omitempty"`
 }
 
 // URLToShortRequest - ответ с короткой ссылкой
@@ -17,45 +24,452 @@ type ShortToURLReponse struct {
 
 // URLToShortBatchRequest - запрос с исходной ссылкой и ключом корреляции
 type URLToShortBatchRequest struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"`
+	Redirect      *Redirect  `json:"redirect,omitempty"`
+}
+
+// URLUpdateRequest - изменение ссылки владельцем. Не заданные поля не меняются.
+// Пустая политика перехода {} возвращает переход по умолчанию, NoExpiry снимает срок действия
+type URLUpdateRequest struct {
+	OriginalURL string     `json:"url,omitempty"`
+	Redirect    *Redirect  `json:"redirect,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	TTL         int64      `json:"ttl,omitempty"`
+	NoExpiry    bool       `json:"no_expiry,omitempty"`
+}
+
+// URLResponse - ссылка пользователя с настройками
+type URLResponse struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Redirect    *Redirect  `json:"redirect,omitempty"`
+}
+
+// URLChange - изменяемые поля ссылки и сведения об изменении для истории. Незаданные поля не меняются:
+// хранилище применяет изменение к состоянию ссылки, заблокированному на время изменения,
+// чтобы параллельные изменения разных полей не затирали друг друга
+type URLChange struct {
+	Key string
+	// OriginalURL - новая исходная ссылка, пусто - без изменения
+	OriginalURL string
+	// SetRedirect - заменить политику перехода на Redirect, nil - политика по умолчанию
+	SetRedirect bool
+	Redirect    *Redirect
+	// SetExpiry - заменить срок действия на ExpiresAt, nil - бессрочная ссылка
+	SetExpiry bool
+	ExpiresAt *time.Time
+	UserID    int
+	RequestID string
+	ChangedAt time.Time
+}
+
+// Apply - состояние ссылки info после изменения
+func (c URLChange) Apply(info ShortURLInfo) ShortURLInfo {
+	if c.OriginalURL != "" {
+		info.OriginalURL = c.OriginalURL
+	}
+	if c.SetRedirect {
+		info.Redirect = c.Redirect
+	}
+	if c.SetExpiry {
+		info.ExpiresAt = c.ExpiresAt
+	}
+	return info
 }
 
+// URLHistory - состояние ссылки до изменения
+type URLHistory struct {
+	OriginalURL string     `json:"original_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Redirect    *Redirect  `json:"redirect,omitempty"`
+	RequestID   string     `json:"request_id,omitempty"`
+	ChangedAt   time.Time  `json:"changed_at"`
+}
+
+// DeleteJobResponse - ответ на запрос удаления ссылок с id задания
+type DeleteJobResponse struct {
+	JobID string `json:"job_id"`
+}
+
+// Результаты сохранения ссылки из массива
+const (
+	// BatchCreated - ссылка сохранена
+	BatchCreated = "created"
+	// BatchExists - исходная ссылка уже сокращена, возвращается существующая короткая ссылка
+	BatchExists = "exists"
+	// BatchInvalid - ссылка не сохранена из-за ошибки в параметрах
+	BatchInvalid = "invalid"
+	// BatchFailed - ссылка не сохранена, так как не удалось подобрать свободный ключ
+	BatchFailed = "failed"
+)
+
 // ShortToURLBatchResponse - ответ с короткой ссылкой и ключом корреляции
 type ShortToURLBatchResponse struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
 }
 
 // KeyOriginalURL - короткая ссылка + исходная ссылка
 type KeyOriginalURL struct {
 	Key         string
 	OriginalURL string
+	ExpiresAt   *time.Time
+	Redirect    *Redirect
+	// CreatedAt - время создания ссылки. Нулевое значение - время сохранения
+	CreatedAt time.Time
 }
 
-// ShortOriginalURL - короткая ссылка + исходная ссылка
-type ShortOriginalURL struct {
-	Short       string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+// DefaultRedirectCode - код ответа перехода по ссылке без политики перехода
+const DefaultRedirectCode = http.StatusTemporaryRedirect
+
+// Redirect - политика перехода по короткой ссылке. nil - переход с кодом DefaultRedirectCode без изменения исходной ссылки
+type Redirect struct {
+	// Code - код ответа: 301 и 308 - постоянный переход, который браузеры кэшируют,
+	// 302 и 307 - временный, каждый переход проходит через сервис. 0 - DefaultRedirectCode
+	Code int `json:"code,omitempty"`
+	// PassQuery - добавлять параметры запроса перехода к исходной ссылке
+	PassQuery bool `json:"pass_query,omitempty"`
+	// UTM - utm метки, добавляемые к исходной ссылке при переходе, ключ без префикса utm_: source, medium, campaign, term, content
+	UTM map[string]string `json:"utm,omitempty"`
+}
+
+// AddURLResult - результат сохранения ссылки из массива. Err == nil - ссылка сохранена,
+// ErrDuplicateURL - исходная ссылка уже сокращена, Key - ключ существующей ссылки,
+// ErrDuplicateKey - ключ занят другой ссылкой
+type AddURLResult struct {
+	Key string
+	Err error
+}
+
+// ShortURLInfo - сохраненная короткая ссылка со служебными признаками
+type ShortURLInfo struct {
+	Key         string
+	OriginalURL string
+	UserID      int
+	Deleted     bool
+	DeletedAt   *time.Time
+	ExpiresAt   *time.Time
+	Redirect    *Redirect
+	CreatedAt   time.Time
+}
+
+// RedirectCode - код ответа перехода по ссылке
+func (i ShortURLInfo) RedirectCode() int {
+	if i.Redirect == nil || i.Redirect.Code == 0 {
+		return DefaultRedirectCode
+	}
+	return i.Redirect.Code
+}
+
+// RedirectTarget - адрес перехода по ссылке: исходная ссылка с параметрами запроса перехода, если они передаются,
+// и utm метками политики. Метки политики заменяют одноименные параметры. Параметры исходной ссылки
+// остаются в исходном порядке и кодировке, новые параметры дописываются после них
+func (i ShortURLInfo) RedirectTarget(query url.Values) string {
+	redirect := i.Redirect
+	if redirect == nil || (!redirect.PassQuery || len(query) == 0) && len(redirect.UTM) == 0 {
+		return i.OriginalURL
+	}
+
+	target, err := url.Parse(i.OriginalURL)
+	if err != nil {
+		return i.OriginalURL
+	}
+
+	added := url.Values{}
+	if redirect.PassQuery {
+		for name, values := range query {
+			for _, value := range values {
+				added.Add(name, value)
+			}
+		}
+	}
+	for name, value := range redirect.UTM {
+		added.Set("utm_"+name, value)
+	}
+
+	parts := make([]string, 0, 2)
+	if kept := withoutParams(target.RawQuery, redirect.UTM); kept != "" {
+		parts = append(parts, kept)
+	}
+	if encoded := added.Encode(); encoded != "" {
+		parts = append(parts, encoded)
+	}
+	target.RawQuery = strings.Join(parts, "&")
+
+	return target.String()
+}
+
+// withoutParams - строка запроса rawQuery без параметров utm_<name> меток utm, остальные параметры не меняются
+func withoutParams(rawQuery string, utm map[string]string) string {
+	if rawQuery == "" || len(utm) == 0 {
+		return rawQuery
+	}
+
+	kept := make([]string, 0)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if utmName, ok := strings.CutPrefix(name, "utm_"); ok {
+			if _, replaced := utm[utmName]; replaced {
+				continue
+			}
+		}
+		kept = append(kept, pair)
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// DeletedBefore - удалена ли ссылка раньше момента before
+func (i ShortURLInfo) DeletedBefore(before time.Time) bool {
+	return i.Deleted && i.DeletedAt != nil && i.DeletedAt.Before(before)
+}
+
+// Expired - истек ли срок действия ссылки на момент now
+func (i ShortURLInfo) Expired(now time.Time) bool {
+	return i.ExpiresAt != nil && !i.ExpiresAt.After(now)
 }
 
 // ErrDuplicateURL - ошибка дублирования url
 var ErrDuplicateURL = errors.New("duplicate url")
 
-// ShortURLUserID - для запроса на удаления ссылок для конкретного пользователя
-type ShortURLUserID struct {
-	ShortURLs []string
-	UserID    int
+// ErrDuplicateKey - ошибка дублирования ключа короткой ссылки в хранилище
+var ErrDuplicateKey = errors.New("duplicate short url key")
+
+// ErrAliasTaken - пользовательский ключ короткой ссылки уже занят
+var ErrAliasTaken = errors.New("alias is already taken")
+
+// ErrKeyCollision - не удалось сгенерировать свободный ключ короткой ссылки
+var ErrKeyCollision = errors.New("can't generate unique short url key")
+
+// ErrInvalidAlias - пользовательский ключ короткой ссылки не прошел проверку
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// ErrInvalidRedirect - политика перехода по ссылке не прошла проверку
+var ErrInvalidRedirect = errors.New("invalid redirect")
+
+// ErrInvalidUpdate - изменение ссылки не прошло проверку
+var ErrInvalidUpdate = errors.New("invalid url update")
+
+// ErrInvalidExpiry - некорректно задан срок действия ссылки
+var ErrInvalidExpiry = errors.New("invalid expiry")
+
+// ErrURLNotFound - короткая ссылка не найдена
+var ErrURLNotFound = errors.New("url not found")
+
+// ErrURLDeleted - короткая ссылка удалена
+var ErrURLDeleted = errors.New("url was deleted")
+
+// ErrURLExpired - истек срок действия короткой ссылки
+var ErrURLExpired = errors.New("url expired")
+
+// Статусы задания на удаление ссылок и отдельных ссылок в нем
+const (
+	// JobPending - ожидает выполнения или повторной попытки
+	JobPending = "pending"
+	// JobDone - выполнено
+	JobDone = "done"
+	// JobFailed - не выполнено: ссылка не найдена или попытки удаления исчерпаны
+	JobFailed = "failed"
+)
+
+// DeleteJob - задание на удаление ссылок пользователя. Сохраняется в хранилище до постановки в очередь,
+// так что принятые запросы переживают перезапуск сервиса
+type DeleteJob struct {
+	ID     string         `json:"id"`
+	UserID int            `json:"user_id"`
+	Status string         `json:"status"`
+	Keys   []DeleteJobKey `json:"keys"`
+	// Attempts - кол-во неудачных попыток удаления
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+	// RequestID - id запроса, создавшего задание, для логов фонового удаления
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeleteJobKey - статус удаления одной ссылки задания
+type DeleteJobKey struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Finished - задание выполнено или окончательно не выполнено
+func (j DeleteJob) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
 }
 
+// UserKey - короткая ссылка пользователя, элемент пакетного удаления ссылок разных пользователей
+type UserKey struct {
+	UserID int
+	Key    string
+}
+
+// ErrJobNotFound - задание на удаление не найдено
+var ErrJobNotFound = errors.New("job not found")
+
 // Stats - для запроса статистики по кол-ву url и пользователей
 type Stats struct {
 	UrlsCount  int `json:"urls"`
 	UsersCount int `json:"users"`
 }
 
-// ShortURLchan - канал для асинхроннго удаления ссылок из БД
-var ShortURLchan chan ShortURLUserID
+// Click - переход по короткой ссылке
+type Click struct {
+	Key       string
+	Time      time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+}
+
+// ClickBucket - кол-во переходов за интервал, начинающийся в Time
+type ClickBucket struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+}
+
+// ClickStatsQuery - параметры запроса статистики переходов
+type ClickStatsQuery struct {
+	Bucket string
+	From   time.Time
+	To     time.Time
+}
+
+// ClickStats - статистика переходов по короткой ссылке
+type ClickStats struct {
+	Key    string        `json:"key"`
+	Total  int           `json:"total"`
+	Bucket string        `json:"bucket"`
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Series []ClickBucket `json:"series"`
+}
+
+// ErrInvalidStatsQuery - некорректные параметры запроса статистики переходов
+var ErrInvalidStatsQuery = errors.New("invalid stats query")
+
+// ErrShuttingDown - сервис останавливается и не принимает новые запросы на удаление
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// Поля сортировки списка ссылок пользователя
+const (
+	// URLSortCreated - по времени создания ссылки
+	URLSortCreated = "created"
+	// URLSortKey - по ключу короткой ссылки
+	URLSortKey = "key"
+	// URLSortURL - по исходной ссылке
+	URLSortURL = "url"
+)
+
+// URLListRequest - запрос страницы списка ссылок пользователя.
+// Sort - поле сортировки, с префиксом "-" - по убыванию. Нулевые значения фильтров не ограничивают выборку
+type URLListRequest struct {
+	Limit       int
+	Cursor      string
+	Search      string
+	Deleted     *bool
+	Expired     *bool
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Sort        string
+}
+
+// URLCursor - позиция в списке ссылок: поле сортировки и значения последней ссылки предыдущей страницы
+type URLCursor struct {
+	Sort        string    `json:"sort"`
+	Desc        bool      `json:"desc,omitempty"`
+	Key         string    `json:"key"`
+	OriginalURL string    `json:"url,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+}
+
+// URLListQuery - выборка ссылок пользователя для хранилища: фильтры, сортировка по Sort и ключу,
+// не более Limit ссылок после позиции After
+type URLListQuery struct {
+	Limit   int
+	After   *URLCursor
+	Search  string
+	Deleted *bool
+	Expired *bool
+	// CreatedFrom, CreatedTo - полуинтервал [CreatedFrom, CreatedTo) времени создания
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Sort        string
+	Desc        bool
+	// Now - момент, на который проверяется истечение срока действия ссылки
+	Now time.Time
+}
+
+// Compare - порядок ссылок в выборке: по полю сортировки, при равенстве - по ключу
+func (q URLListQuery) Compare(a, b ShortURLInfo) int {
+	var c int
+	switch q.Sort {
+	case URLSortKey:
+	case URLSortURL:
+		c = strings.Compare(a.OriginalURL, b.OriginalURL)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.Key, b.Key)
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+// Match - подходит ли ссылка под фильтры выборки и находится ли после позиции After
+func (q URLListQuery) Match(info ShortURLInfo) bool {
+	if q.Deleted != nil && info.Deleted != *q.Deleted {
+		return false
+	}
+	if q.Expired != nil && info.Expired(q.Now) != *q.Expired {
+		return false
+	}
+	if !q.CreatedFrom.IsZero() && info.CreatedAt.Before(q.CreatedFrom) {
+		return false
+	}
+	if !q.CreatedTo.IsZero() && !info.CreatedAt.Before(q.CreatedTo) {
+		return false
+	}
+	if q.Search != "" && !strings.Contains(strings.ToLower(info.OriginalURL), strings.ToLower(q.Search)) {
+		return false
+	}
+	if q.After == nil {
+		return true
+	}
+
+	return q.Compare(info, ShortURLInfo{Key: q.After.Key, OriginalURL: q.After.OriginalURL, CreatedAt: q.After.CreatedAt}) > 0
+}
+
+// UserURL - ссылка пользователя в списке ссылок
+type UserURL struct {
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	CreatedAt   time.Time  `json:"created_at,omitzero"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Deleted     bool       `json:"deleted,omitempty"`
+	Redirect    *Redirect  `json:"redirect,omitempty"`
+}
+
+// URLPage - страница списка ссылок пользователя. NextCursor пуст на последней странице
+type URLPage struct {
+	URLs       []UserURL
+	NextCursor string
+}
 
-// Wg - WaitGroup
-var Wg *sync.WaitGroup
+// ErrInvalidListQuery - некорректные параметры запроса списка ссылок
+var ErrInvalidListQuery = errors.New("invalid list query")
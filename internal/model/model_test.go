@@ -0,0 +1,40 @@
+package model
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectTarget(t *testing.T) {
+	query := url.Values{"ref": {"x"}, "utm_source": {"visitor"}}
+	tests := []struct {
+		name     string
+		original string
+		redirect *Redirect
+		want     string
+	}{
+		{name: "no policy", original: "https://a.ru/?b=2&a=1", want: "https://a.ru/?b=2&a=1"},
+		{name: "query is not passed", original: "https://a.ru/", redirect: &Redirect{Code: 302}, want: "https://a.ru/"},
+		{name: "pass query", original: "https://a.ru/p?a=1", redirect: &Redirect{PassQuery: true}, want: "https://a.ru/p?a=1&ref=x&utm_source=visitor"},
+		{
+			name:     "original query is kept as is",
+			original: "https://a.ru/p?b=2&a=%2F&utm_source=old",
+			redirect: &Redirect{UTM: map[string]string{"source": "mail"}},
+			want:     "https://a.ru/p?b=2&a=%2F&utm_source=mail",
+		},
+		{
+			name:     "utm replaces passed parameter",
+			original: "https://a.ru/p#top",
+			redirect: &Redirect{PassQuery: true, UTM: map[string]string{"source": "mail", "campaign": "spring"}},
+			want:     "https://a.ru/p?ref=x&utm_campaign=spring&utm_source=mail#top",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := ShortURLInfo{OriginalURL: tt.original, Redirect: tt.redirect}
+			assert.Equal(t, tt.want, info.RedirectTarget(query))
+		})
+	}
+}
@@ -1,24 +1,34 @@
 package pb
 
 import (
+	"context"
+	"errors"
 	"net"
+	"sync/atomic"
 
 	"github.com/kirillmashkov/shortener.git/internal/app"
 	"github.com/kirillmashkov/shortener.git/internal/server"
 	"github.com/kirillmashkov/shortener.git/internal/service"
 	"go.uber.org/zap"
 	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type GRPCServer struct {
 	UnimplementedShortenerServer
-	server    *grpc.Server
-	service service.Service
-	addr 	string
+	server  *grpc.Server
+	health  *health.Server
+	service *service.Service
+	utils   *service.ServiceUtils
+	addr    string
+	// listening - принимает ли сервер соединения
+	listening atomic.Bool
 }
 
 func (s *GRPCServer) Run() error {
 	RegisterShortenerServer(s.server, s)
+	healthpb.RegisterHealthServer(s.server, s.health)
 
 	listen, err := net.Listen("tcp", s.addr)
 	if err != nil {
@@ -26,16 +36,51 @@ func (s *GRPCServer) Run() error {
 		return err
 	}
 
+	s.listening.Store(true)
+	defer s.listening.Store(false)
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(Shortener_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
 	return s.server.Serve(listen)
 }
 
-func (s *GRPCServer) Shutdown() error {
-	s.server.GracefulStop()
+// Shutdown - остановка с ожиданием завершения вызовов. Если ctx отменен раньше,
+// оставшиеся вызовы прерываются
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// CheckListener - проверка готовности: принимает ли gRPC сервер соединения
+func (s *GRPCServer) CheckListener(ctx context.Context) error {
+	if !s.listening.Load() {
+		return errors.New("grpc server is not listening")
+	}
 	return nil
 }
 
-func New(service service.Service, addr string) server.Server {
-	s := grpc.NewServer()
+func New(service *service.Service, utils *service.ServiceUtils, addr string) server.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(TracingUnaryInterceptor, LoggingUnaryInterceptor, MetricsUnaryInterceptor, AuthUnaryInterceptor, RateLimitUnaryInterceptor),
+		grpc.ChainStreamInterceptor(TracingStreamInterceptor, LoggingStreamInterceptor, MetricsStreamInterceptor, AuthStreamInterceptor, RateLimitStreamInterceptor),
+	)
 
-	return &GRPCServer{server: s, service: service, addr: addr}
-}
\ No newline at end of file
+	grpcServer := &GRPCServer{server: s, health: health.NewServer(), service: service, utils: utils, addr: addr}
+	if app.Health != nil {
+		app.Health.Register("grpc", grpcServer.CheckListener)
+	}
+	return grpcServer
+}
@@ -1,14 +1,13 @@
 package pb
 
 import (
-	"fmt"
 	context "context"
 	"errors"
-	"math/rand"
-	"strconv"
+	"fmt"
 	"time"
 
 	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"github.com/kirillmashkov/shortener.git/internal/model"
 	"go.uber.org/zap"
 	codes "google.golang.org/grpc/codes"
@@ -21,18 +20,14 @@ func (s *GRPCServer) GetURL(ctx context.Context, r *GetURLRequest) (*GetURLRespo
 		return nil, status.Error(codes.InvalidArgument, "url_id required")
 	}
 
-	url, deleted, exist := s.service.GetShortURL(ctx, urlID)
-
-	if !exist {
-		return nil, status.Error(codes.NotFound, "url not found")
-	}
-
-	if deleted {
-		return nil, status.Error(codes.NotFound, "url was deleted")
+	info, err := s.service.GetShortURL(ctx, urlID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
 	}
 
 	return &GetURLResponse{
-		FullUrl: url,
+		FullUrl:  info.OriginalURL,
+		Redirect: redirectToProto(info.Redirect),
 	}, nil
 }
 
@@ -41,28 +36,287 @@ func (s *GRPCServer) CreateShort(ctx context.Context, r *CreateShortRequest) (*C
 		return nil, status.Error(codes.InvalidArgument, "url required")
 	}
 
-	var userID int
-	var err error
-	if r.UserId == "" {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		userID = r.Int()
-	} else {
-		userID, err = strconv.Atoi(r.UserId)
-		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "userId must be int")	
-		}
+	userID := userIDFromContext(ctx)
+	request := model.URLToShortRequest{
+		OriginalURL: r.Url,
+		Alias:       r.Alias,
+		ExpiresAt:   unixToTime(r.ExpiresAt),
+		TTL:         r.Ttl,
+		Redirect:    redirectFromProto(r.Redirect),
 	}
 
-	shortURL, err := s.service.ProcessURL(ctx, r.Url, userID)
+	shortURL, err := s.service.ProcessURL(ctx, request, userID)
 
 	if err != nil {
 		if errors.Is(err, model.ErrDuplicateURL) {
-			return &CreateShortResponse {ResultUrl: shortURL, UserId: "", UrlId: shortURL}, nil
+			return &CreateShortResponse{ResultUrl: shortURL, UserId: fmt.Sprint(userID), UrlId: shortURL}, nil
+		}
+		return nil, processErrorToStatus(ctx, err)
+	}
+
+	return &CreateShortResponse{ResultUrl: shortURL, UserId: fmt.Sprint(userID), UrlId: shortURL}, nil
+
+}
+
+func (s *GRPCServer) CreateShortBatch(ctx context.Context, r *CreateShortBatchRequest) (*CreateShortBatchResponse, error) {
+	if len(r.Urls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "urls required")
+	}
+
+	userID := userIDFromContext(ctx)
+	request := make([]model.URLToShortBatchRequest, 0, len(r.Urls))
+	for _, u := range r.Urls {
+		request = append(request, model.URLToShortBatchRequest{
+			CorrelationID: u.CorrelationId,
+			OriginalURL:   u.OriginalUrl,
+			Alias:         u.Alias,
+			ExpiresAt:     unixToTime(u.ExpiresAt),
+			TTL:           u.Ttl,
+			Redirect:      redirectFromProto(u.Redirect),
+		})
+	}
+
+	results, err := s.service.ProcessURLBatch(ctx, request, userID)
+	if err != nil {
+		return nil, processErrorToStatus(ctx, err)
+	}
+
+	response := &CreateShortBatchResponse{UserId: fmt.Sprint(userID)}
+	for _, res := range results {
+		response.Urls = append(response.Urls, &CreateShortBatchResult{
+			CorrelationId: res.CorrelationID,
+			ShortUrl:      res.ShortURL,
+			Status:        res.Status,
+			Error:         res.Error,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *GRPCServer) ListUserURLs(ctx context.Context, r *ListUserURLsRequest) (*ListUserURLsResponse, error) {
+	request := model.URLListRequest{
+		Limit:   int(r.Limit),
+		Cursor:  r.Cursor,
+		Search:  r.Search,
+		Deleted: r.Deleted,
+		Expired: r.Expired,
+		Sort:    r.Sort,
+	}
+	if r.CreatedFrom > 0 {
+		request.CreatedFrom = time.Unix(r.CreatedFrom, 0)
+	}
+	if r.CreatedTo > 0 {
+		request.CreatedTo = time.Unix(r.CreatedTo, 0)
+	}
+
+	page, err := s.service.ListURLs(ctx, userIDFromContext(ctx), request)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidListQuery) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		ctxlog.From(ctx, app.Log).Error("Error get user urls", zap.Error(err))
+		return nil, status.Error(codes.Internal, "can't get user urls")
+	}
+
+	response := &ListUserURLsResponse{NextCursor: page.NextCursor}
+	for _, u := range page.URLs {
+		response.Urls = append(response.Urls, &UserURL{
+			ShortUrl:    u.ShortURL,
+			OriginalUrl: u.OriginalURL,
+			CreatedAt:   timeToUnix(&u.CreatedAt),
+			ExpiresAt:   timeToUnix(u.ExpiresAt),
+			Deleted:     u.Deleted,
+			Redirect:    redirectToProto(u.Redirect),
+		})
+	}
+
+	return response, nil
+}
+
+func (s *GRPCServer) DeleteURLs(ctx context.Context, r *DeleteURLsRequest) (*DeleteURLsResponse, error) {
+	if len(r.ShortUrls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "short_urls required")
+	}
+
+	jobID, err := s.service.DeleteURLBatch(ctx, userIDFromContext(ctx), r.ShortUrls)
+	if err != nil {
+		if errors.Is(err, model.ErrShuttingDown) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		ctxlog.From(ctx, app.Log).Error("Error queue urls for deletion", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &DeleteURLsResponse{JobId: jobID}, nil
+}
+
+func (s *GRPCServer) RestoreURLs(ctx context.Context, r *RestoreURLsRequest) (*RestoreURLsResponse, error) {
+	if len(r.ShortUrls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "short_urls required")
+	}
+
+	restored, err := s.service.RestoreURLs(ctx, userIDFromContext(ctx), r.ShortUrls)
+	if err != nil {
+		ctxlog.From(ctx, app.Log).Error("Error restore urls", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &RestoreURLsResponse{ShortUrls: restored}, nil
+}
+
+func (s *GRPCServer) GetDeleteJob(ctx context.Context, r *GetDeleteJobRequest) (*GetDeleteJobResponse, error) {
+	if r.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, "job_id required")
+	}
+
+	job, err := s.service.GetDeleteJob(ctx, r.JobId, userIDFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, model.ErrJobNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
 		}
-		app.Log.Error("Error process URL", zap.Error(err))
+		ctxlog.From(ctx, app.Log).Error("Error get delete job", zap.Error(err))
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	return &CreateShortResponse {ResultUrl: shortURL, UserId: fmt.Sprint(userID)}, nil
+	response := &GetDeleteJobResponse{
+		JobId:     job.ID,
+		Status:    job.Status,
+		Attempts:  int32(job.Attempts),
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt.Unix(),
+		UpdatedAt: job.UpdatedAt.Unix(),
+	}
+	for _, key := range job.Keys {
+		response.Keys = append(response.Keys, &DeleteJobKey{ShortUrl: key.Key, Status: key.Status, Error: key.Error})
+	}
+
+	return response, nil
+}
+
+func (s *GRPCServer) UpdateURL(ctx context.Context, r *UpdateURLRequest) (*UpdateURLResponse, error) {
+	if r.UrlId == "" {
+		return nil, status.Error(codes.InvalidArgument, "url_id required")
+	}
+
+	request := model.URLUpdateRequest{
+		OriginalURL: r.Url,
+		Redirect:    redirectFromProto(r.Redirect),
+		ExpiresAt:   unixToTime(r.ExpiresAt),
+		TTL:         r.Ttl,
+		NoExpiry:    r.NoExpiry,
+	}
+
+	updated, err := s.service.UpdateURL(ctx, r.UrlId, userIDFromContext(ctx), request)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrURLNotFound), errors.Is(err, model.ErrURLDeleted):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, model.ErrDuplicateURL):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, processErrorToStatus(ctx, err)
+	}
+
+	return &UpdateURLResponse{
+		ShortUrl:    updated.ShortURL,
+		OriginalUrl: updated.OriginalURL,
+		Redirect:    redirectToProto(updated.Redirect),
+		ExpiresAt:   timeToUnix(updated.ExpiresAt),
+	}, nil
+}
+
+func (s *GRPCServer) GetURLHistory(ctx context.Context, r *GetURLHistoryRequest) (*GetURLHistoryResponse, error) {
+	if r.UrlId == "" {
+		return nil, status.Error(codes.InvalidArgument, "url_id required")
+	}
+
+	history, err := s.service.GetURLHistory(ctx, r.UrlId, userIDFromContext(ctx))
+	if err != nil {
+		if errors.Is(err, model.ErrURLNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		ctxlog.From(ctx, app.Log).Error("Error get url history", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &GetURLHistoryResponse{}
+	for _, entry := range history {
+		response.Entries = append(response.Entries, &URLHistoryEntry{
+			OriginalUrl: entry.OriginalURL,
+			Redirect:    redirectToProto(entry.Redirect),
+			ExpiresAt:   timeToUnix(entry.ExpiresAt),
+			RequestId:   entry.RequestID,
+			ChangedAt:   entry.ChangedAt.Unix(),
+		})
+	}
+
+	return response, nil
+}
+
+func (s *GRPCServer) Ping(ctx context.Context, r *PingRequest) (*PingResponse, error) {
+	if err := s.utils.PingDB(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, "DB is unavailable")
+	}
+
+	return &PingResponse{}, nil
+}
+
+func (s *GRPCServer) GetStats(ctx context.Context, r *GetStatsRequest) (*GetStatsResponse, error) {
+	if err := checkTrustedSubnet(ctx); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.service.GetStats(ctx)
+	if err != nil {
+		ctxlog.From(ctx, app.Log).Error("Error get stats", zap.Error(err))
+		return nil, status.Error(codes.Internal, "can't get stats")
+	}
+
+	return &GetStatsResponse{Urls: int64(stats.UrlsCount), Users: int64(stats.UsersCount)}, nil
+}
+
+func unixToTime(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+
+	t := time.Unix(sec, 0)
+	return &t
+}
+
+func timeToUnix(t *time.Time) int64 {
+	if t == nil || t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+func redirectFromProto(r *RedirectPolicy) *model.Redirect {
+	if r == nil {
+		return nil
+	}
+
+	return &model.Redirect{Code: int(r.Code), PassQuery: r.PassQuery, UTM: r.Utm}
+}
+
+func redirectToProto(r *model.Redirect) *RedirectPolicy {
+	if r == nil {
+		return nil
+	}
+
+	return &RedirectPolicy{Code: int32(r.Code), PassQuery: r.PassQuery, Utm: r.UTM}
+}
+
+// processErrorToStatus - преобразование ошибок сохранения ссылок в статус gRPC
+func processErrorToStatus(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, model.ErrInvalidAlias), errors.Is(err, model.ErrInvalidExpiry), errors.Is(err, model.ErrInvalidRedirect),
+		errors.Is(err, model.ErrInvalidUpdate):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, model.ErrAliasTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
 
+	ctxlog.From(ctx, app.Log).Error("Error process URL", zap.Error(err))
+	return status.Error(codes.Internal, err.Error())
 }
@@ -0,0 +1,107 @@
+package pb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"go.uber.org/zap"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+const authMetadata = "authorization"
+const bearerPrefix = "Bearer "
+
+// AuthUnaryInterceptor - получение токена из метаданных запроса. Если токена нет или он невалиден,
+// выдает новый в метаданных ответа. Id пользователя записывается в контекст запроса.
+// Вызовы сервиса проверки здоровья не авторизуются
+func AuthUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if isHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	ctx, err := authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// AuthStreamInterceptor - аналог AuthUnaryInterceptor для потоковых вызовов
+func AuthStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isHealthMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	ctx, err := authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// authServerStream - поток с подмененным контекстом
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context - контекст потока с id пользователя
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+func authenticate(ctx context.Context) (context.Context, error) {
+	var userID int
+	var ok bool
+	if token := bearerToken(ctx); token != "" {
+		userID, ok = security.ParseToken(token)
+	}
+
+	if !ok {
+		var token string
+		var err error
+		token, userID, err = security.NewToken()
+		if err != nil {
+			ctxlog.From(ctx, app.Log).Error("Error get token", zap.Error(err))
+			return nil, status.Error(codes.Internal, "can't issue token")
+		}
+
+		if err := grpc.SetHeader(ctx, metadata.Pairs(authMetadata, bearerPrefix+token)); err != nil {
+			ctxlog.From(ctx, app.Log).Error("Error set token to metadata", zap.Error(err))
+			return nil, status.Error(codes.Internal, "can't issue token")
+		}
+	}
+
+	ctxlog.With(ctx, zap.Int("user_id", userID))
+	return security.WithUser(ctx, userID, !ok), nil
+}
+
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(authMetadata) {
+		if strings.HasPrefix(value, bearerPrefix) {
+			return strings.TrimPrefix(value, bearerPrefix)
+		}
+	}
+
+	return ""
+}
+
+func userIDFromContext(ctx context.Context) int {
+	u := security.UserIDType("userID")
+	userID, _ := ctx.Value(u).(int)
+	return userID
+}
@@ -0,0 +1,93 @@
+package pb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/authkeys"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type fakeTransportStream struct {
+	header metadata.MD
+}
+
+func (s *fakeTransportStream) Method() string { return "/shortener.Shortener/GetURL" }
+
+func (s *fakeTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeTransportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *fakeTransportStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	restoreServerConf(t)
+	app.ServerConf.TokenExp = time.Hour
+	keys, err := authkeys.Parse("test", "test-secret", "")
+	require.NoError(t, err)
+	app.AuthKeys = keys
+	defer func() {
+		app.AuthKeys = nil
+	}()
+
+	token, userID, err := security.NewToken()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		md          metadata.MD
+		wantUserID  int
+		wantNewAuth bool
+	}{
+		{name: "valid token", md: metadata.Pairs(authMetadata, bearerPrefix+token), wantUserID: userID},
+		{name: "no token", md: metadata.MD{}, wantNewAuth: true},
+		{name: "broken token", md: metadata.Pairs(authMetadata, bearerPrefix+"broken"), wantNewAuth: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := &fakeTransportStream{}
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+			var gotUserID int
+			_, err := AuthUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				gotUserID = userIDFromContext(ctx)
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			issued := stream.header.Get(authMetadata)
+			if !test.wantNewAuth {
+				assert.Empty(t, issued)
+				assert.Equal(t, test.wantUserID, gotUserID)
+				return
+			}
+
+			require.Len(t, issued, 1)
+			newUserID, ok := security.ParseToken(strings.TrimPrefix(issued[0], bearerPrefix))
+			assert.True(t, ok)
+			assert.Equal(t, newUserID, gotUserID)
+		})
+	}
+}
+
+// restoreServerConf - возврат конфигурации приложения, измененной тестом
+func restoreServerConf(t *testing.T) {
+	t.Helper()
+
+	saved := app.ServerConf
+	t.Cleanup(func() {
+		app.ServerConf = saved
+	})
+}
@@ -0,0 +1,58 @@
+package pb
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"go.uber.org/zap"
+	grpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+// LoggingUnaryInterceptor - id запроса из метаданных x-request-id или новый, возвращается в метаданных ответа.
+// В контекст кладется логгер запроса, по завершении вызова пишется строка с кодом и длительностью
+func LoggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = withRequestLogger(ctx, info.FullMethod)
+	t1 := time.Now()
+
+	resp, err := handler(ctx, req)
+	logCall(ctx, t1, err)
+	return resp, err
+}
+
+// LoggingStreamInterceptor - аналог LoggingUnaryInterceptor для потоковых вызовов
+func LoggingStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withRequestLogger(ss.Context(), info.FullMethod)
+	t1 := time.Now()
+
+	err := handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, t1, err)
+	return err
+}
+
+func withRequestLogger(ctx context.Context, method string) context.Context {
+	var fromClient string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(ctxlog.Header); len(values) > 0 {
+		fromClient = values[0]
+	}
+
+	requestID := ctxlog.ResolveRequestID(fromClient)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(ctxlog.Header), requestID)); err != nil {
+		app.Log.Error("Error set request id to metadata", zap.Error(err))
+	}
+
+	ctx = ctxlog.New(ctx, app.Log, requestID)
+	ctxlog.From(ctx, app.Log).Info("grpc request", zap.String("method", method))
+	return ctx
+}
+
+func logCall(ctx context.Context, t1 time.Time, err error) {
+	ctxlog.From(ctx, app.Log).Info("grpc response",
+		zap.Duration("elapsed", time.Since(t1)),
+		zap.String("code", status.Code(err).String()))
+}
@@ -0,0 +1,32 @@
+package pb
+
+import (
+	"context"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+	grpc "google.golang.org/grpc"
+	status "google.golang.org/grpc/status"
+)
+
+// MetricsUnaryInterceptor - учет кол-ва и длительности вызовов в метриках по методу и коду ответа
+func MetricsUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+// MetricsStreamInterceptor - аналог MetricsUnaryInterceptor для потоковых вызовов
+func MetricsStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observeCall(info.FullMethod, start, err)
+	return err
+}
+
+func observeCall(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	metrics.GRPCRequests.WithLabelValues(method, code).Inc()
+	metrics.GRPCDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,86 @@
+package pb
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/httpserver/middleware/security"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
+	"go.uber.org/zap"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	status "google.golang.org/grpc/status"
+)
+
+const retryAfterMetadata = "retry-after"
+
+// methodGroups - группы лимитов для методов, совпадают с группами REST маршрутов
+var methodGroups = map[string]string{
+	Shortener_GetURL_FullMethodName:           ratelimit.GroupRedirect,
+	Shortener_CreateShort_FullMethodName:      ratelimit.GroupShorten,
+	Shortener_CreateShortBatch_FullMethodName: ratelimit.GroupShorten,
+	Shortener_ListUserURLs_FullMethodName:     ratelimit.GroupAPI,
+	Shortener_DeleteURLs_FullMethodName:       ratelimit.GroupAPI,
+	Shortener_GetDeleteJob_FullMethodName:     ratelimit.GroupAPI,
+	Shortener_RestoreURLs_FullMethodName:      ratelimit.GroupAPI,
+	Shortener_UpdateURL_FullMethodName:        ratelimit.GroupAPI,
+	Shortener_GetURLHistory_FullMethodName:    ratelimit.GroupAPI,
+	Shortener_Ping_FullMethodName:             ratelimit.GroupAPI,
+}
+
+// RateLimitUnaryInterceptor - ограничение частоты вызовов по той же политике, что и для REST.
+// Вызывается после AuthUnaryInterceptor: клиент с ранее выданным токеном ограничивается по id пользователя,
+// остальные - по ip. При превышении лимита возвращает ResourceExhausted и retry-after в метаданных ответа
+func RateLimitUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := checkRateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// RateLimitStreamInterceptor - аналог RateLimitUnaryInterceptor для потоковых вызовов
+func RateLimitStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkRateLimit(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+func checkRateLimit(ctx context.Context, method string) error {
+	group, ok := methodGroups[method]
+	if !ok || app.RateLimit == nil {
+		return nil
+	}
+
+	key := rateLimitKey(ctx)
+	allowed, delay := app.RateLimit.Allow(group, key, time.Now())
+	if allowed {
+		return nil
+	}
+
+	ctxlog.From(ctx, app.Log).Debug("Rate limit exceeded", zap.String("group", group), zap.String("client", key))
+	retryAfter := strconv.Itoa(ratelimit.RetryAfterSeconds(delay))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadata, retryAfter)); err != nil {
+		ctxlog.From(ctx, app.Log).Error("Error set retry-after to metadata", zap.Error(err))
+	}
+
+	return status.Errorf(codes.ResourceExhausted, "too many requests, retry after %s seconds", retryAfter)
+}
+
+func rateLimitKey(ctx context.Context) string {
+	if userID, ok := security.KnownUser(ctx); ok {
+		return ratelimit.UserKey(userID)
+	}
+
+	ip, ok := clientIP(ctx)
+	if !ok {
+		return ratelimit.IPKey("unknown")
+	}
+	return ratelimit.IPKey(ip)
+}
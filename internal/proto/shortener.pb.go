@@ -4,6 +4,9 @@
 // 	protoc        v6.32.0
 // source: shortener.proto
 
+// Пользователь определяется по JWT токену из метаданных "authorization: Bearer <token>".
+// Если токен не передан или невалиден, сервер выдает новый в метаданных ответа
+
 package pb
 
 import (
@@ -66,8 +69,10 @@ func (x *GetURLRequest) GetUrlId() string {
 }
 
 type GetURLResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	FullUrl       string                 `protobuf:"bytes,1,opt,name=full_url,json=fullUrl,proto3" json:"full_url,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	FullUrl string                 `protobuf:"bytes,1,opt,name=full_url,json=fullUrl,proto3" json:"full_url,omitempty"`
+	// не задана, если у ссылки нет политики перехода
+	Redirect      *RedirectPolicy `protobuf:"bytes,2,opt,name=redirect,proto3" json:"redirect,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -109,17 +114,93 @@ func (x *GetURLResponse) GetFullUrl() string {
 	return ""
 }
 
+func (x *GetURLResponse) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+// политика перехода по короткой ссылке
+type RedirectPolicy struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 301, 302, 307 или 308, 0 - 307
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// добавлять параметры запроса перехода к исходной ссылке
+	PassQuery bool `protobuf:"varint,2,opt,name=pass_query,json=passQuery,proto3" json:"pass_query,omitempty"`
+	// utm метки без префикса utm_: source, medium, campaign, term, content
+	Utm           map[string]string `protobuf:"bytes,3,rep,name=utm,proto3" json:"utm,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RedirectPolicy) Reset() {
+	*x = RedirectPolicy{}
+	mi := &file_shortener_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedirectPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedirectPolicy) ProtoMessage() {}
+
+func (x *RedirectPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedirectPolicy.ProtoReflect.Descriptor instead.
+func (*RedirectPolicy) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RedirectPolicy) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *RedirectPolicy) GetPassQuery() bool {
+	if x != nil {
+		return x.PassQuery
+	}
+	return false
+}
+
+func (x *RedirectPolicy) GetUtm() map[string]string {
+	if x != nil {
+		return x.Utm
+	}
+	return nil
+}
+
 type CreateShortRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
-	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Alias string                 `protobuf:"bytes,3,opt,name=alias,proto3" json:"alias,omitempty"`
+	// момент истечения ссылки, unix time в секундах
+	ExpiresAt int64 `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// время жизни ссылки в секундах
+	Ttl           int64           `protobuf:"varint,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Redirect      *RedirectPolicy `protobuf:"bytes,6,opt,name=redirect,proto3" json:"redirect,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CreateShortRequest) Reset() {
 	*x = CreateShortRequest{}
-	mi := &file_shortener_proto_msgTypes[2]
+	mi := &file_shortener_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -131,7 +212,7 @@ func (x *CreateShortRequest) String() string {
 func (*CreateShortRequest) ProtoMessage() {}
 
 func (x *CreateShortRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_shortener_proto_msgTypes[2]
+	mi := &file_shortener_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -144,7 +225,7 @@ func (x *CreateShortRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateShortRequest.ProtoReflect.Descriptor instead.
 func (*CreateShortRequest) Descriptor() ([]byte, []int) {
-	return file_shortener_proto_rawDescGZIP(), []int{2}
+	return file_shortener_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CreateShortRequest) GetUrl() string {
@@ -154,13 +235,34 @@ func (x *CreateShortRequest) GetUrl() string {
 	return ""
 }
 
-func (x *CreateShortRequest) GetUserId() string {
+func (x *CreateShortRequest) GetAlias() string {
 	if x != nil {
-		return x.UserId
+		return x.Alias
 	}
 	return ""
 }
 
+func (x *CreateShortRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *CreateShortRequest) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *CreateShortRequest) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
 type CreateShortResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ResultUrl     string                 `protobuf:"bytes,1,opt,name=result_url,json=resultUrl,proto3" json:"result_url,omitempty"`
@@ -172,7 +274,7 @@ type CreateShortResponse struct {
 
 func (x *CreateShortResponse) Reset() {
 	*x = CreateShortResponse{}
-	mi := &file_shortener_proto_msgTypes[3]
+	mi := &file_shortener_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -184,7 +286,7 @@ func (x *CreateShortResponse) String() string {
 func (*CreateShortResponse) ProtoMessage() {}
 
 func (x *CreateShortResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_shortener_proto_msgTypes[3]
+	mi := &file_shortener_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -197,7 +299,7 @@ func (x *CreateShortResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateShortResponse.ProtoReflect.Descriptor instead.
 func (*CreateShortResponse) Descriptor() ([]byte, []int) {
-	return file_shortener_proto_rawDescGZIP(), []int{3}
+	return file_shortener_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *CreateShortResponse) GetResultUrl() string {
@@ -221,26 +323,1515 @@ func (x *CreateShortResponse) GetUrlId() string {
 	return ""
 }
 
-var File_shortener_proto protoreflect.FileDescriptor
+type CreateShortBatchItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Alias         string                 `protobuf:"bytes,3,opt,name=alias,proto3" json:"alias,omitempty"`
+	// момент истечения ссылки, unix time в секундах
+	ExpiresAt int64 `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// время жизни ссылки в секундах
+	Ttl           int64           `protobuf:"varint,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Redirect      *RedirectPolicy `protobuf:"bytes,6,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_shortener_proto_rawDesc = "" +
-	"\n" +
-	"\x0fshortener.proto\x12\tshortener\"&\n" +
-	"\rGetURLRequest\x12\x15\n" +
-	"\x06url_id\x18\x01 \x01(\tR\x05urlId\"+\n" +
-	"\x0eGetURLResponse\x12\x19\n" +
-	"\bfull_url\x18\x01 \x01(\tR\afullUrl\"?\n" +
-	"\x12CreateShortRequest\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\tR\x06userId\"d\n" +
-	"\x13CreateShortResponse\x12\x1d\n" +
-	"\n" +
-	"result_url\x18\x01 \x01(\tR\tresultUrl\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x15\n" +
-	"\x06url_id\x18\x03 \x01(\tR\x05urlId2\x98\x01\n" +
-	"\tShortener\x12=\n" +
-	"\x06GetURL\x12\x18.shortener.GetURLRequest\x1a\x19.shortener.GetURLResponse\x12L\n" +
-	"\vCreateShort\x12\x1d.shortener.CreateShortRequest\x1a\x1e.shortener.CreateShortResponseB\x0eZ\fshortener/pbb\x06proto3"
+func (x *CreateShortBatchItem) Reset() {
+	*x = CreateShortBatchItem{}
+	mi := &file_shortener_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateShortBatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShortBatchItem) ProtoMessage() {}
+
+func (x *CreateShortBatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShortBatchItem.ProtoReflect.Descriptor instead.
+func (*CreateShortBatchItem) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateShortBatchItem) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *CreateShortBatchItem) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *CreateShortBatchItem) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *CreateShortBatchItem) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *CreateShortBatchItem) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *CreateShortBatchItem) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+type CreateShortBatchRequest struct {
+	state         protoimpl.MessageState  `protogen:"open.v1"`
+	Urls          []*CreateShortBatchItem `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateShortBatchRequest) Reset() {
+	*x = CreateShortBatchRequest{}
+	mi := &file_shortener_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateShortBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShortBatchRequest) ProtoMessage() {}
+
+func (x *CreateShortBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShortBatchRequest.ProtoReflect.Descriptor instead.
+func (*CreateShortBatchRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateShortBatchRequest) GetUrls() []*CreateShortBatchItem {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+type CreateShortBatchResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// пусто, если status = invalid
+	ShortUrl string `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// created, exists, invalid или failed
+	Status        string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateShortBatchResult) Reset() {
+	*x = CreateShortBatchResult{}
+	mi := &file_shortener_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateShortBatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShortBatchResult) ProtoMessage() {}
+
+func (x *CreateShortBatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShortBatchResult.ProtoReflect.Descriptor instead.
+func (*CreateShortBatchResult) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CreateShortBatchResult) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *CreateShortBatchResult) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *CreateShortBatchResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CreateShortBatchResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type CreateShortBatchResponse struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Urls          []*CreateShortBatchResult `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	UserId        string                    `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateShortBatchResponse) Reset() {
+	*x = CreateShortBatchResponse{}
+	mi := &file_shortener_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateShortBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateShortBatchResponse) ProtoMessage() {}
+
+func (x *CreateShortBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateShortBatchResponse.ProtoReflect.Descriptor instead.
+func (*CreateShortBatchResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateShortBatchResponse) GetUrls() []*CreateShortBatchResult {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *CreateShortBatchResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type ListUserURLsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// размер страницы, 0 - по умолчанию
+	Limit int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	// курсор следующей страницы из предыдущего ответа
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// поиск по исходной ссылке без учета регистра
+	Search string `protobuf:"bytes,3,opt,name=search,proto3" json:"search,omitempty"`
+	// не задано - удаленные и неудаленные ссылки
+	Deleted *bool `protobuf:"varint,4,opt,name=deleted,proto3,oneof" json:"deleted,omitempty"`
+	// не задано - просроченные и действующие ссылки
+	Expired *bool `protobuf:"varint,5,opt,name=expired,proto3,oneof" json:"expired,omitempty"`
+	// полуинтервал времени создания [created_from, created_to), unix time в секундах, 0 - без ограничения
+	CreatedFrom int64 `protobuf:"varint,6,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	CreatedTo   int64 `protobuf:"varint,7,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	// created, key или url, с префиксом "-" - по убыванию. По умолчанию -created
+	Sort          string `protobuf:"bytes,8,opt,name=sort,proto3" json:"sort,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserURLsRequest) Reset() {
+	*x = ListUserURLsRequest{}
+	mi := &file_shortener_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserURLsRequest) ProtoMessage() {}
+
+func (x *ListUserURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserURLsRequest.ProtoReflect.Descriptor instead.
+func (*ListUserURLsRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListUserURLsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListUserURLsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListUserURLsRequest) GetSearch() string {
+	if x != nil {
+		return x.Search
+	}
+	return ""
+}
+
+func (x *ListUserURLsRequest) GetDeleted() bool {
+	if x != nil && x.Deleted != nil {
+		return *x.Deleted
+	}
+	return false
+}
+
+func (x *ListUserURLsRequest) GetExpired() bool {
+	if x != nil && x.Expired != nil {
+		return *x.Expired
+	}
+	return false
+}
+
+func (x *ListUserURLsRequest) GetCreatedFrom() int64 {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return 0
+}
+
+func (x *ListUserURLsRequest) GetCreatedTo() int64 {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return 0
+}
+
+func (x *ListUserURLsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+type UserURL struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl    string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	// unix time в секундах
+	CreatedAt int64 `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// unix time в секундах, 0 - без срока действия
+	ExpiresAt     int64           `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Deleted       bool            `protobuf:"varint,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Redirect      *RedirectPolicy `protobuf:"bytes,6,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserURL) Reset() {
+	*x = UserURL{}
+	mi := &file_shortener_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserURL) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserURL) ProtoMessage() {}
+
+func (x *UserURL) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserURL.ProtoReflect.Descriptor instead.
+func (*UserURL) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UserURL) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *UserURL) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *UserURL) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *UserURL) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *UserURL) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+func (x *UserURL) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+type ListUserURLsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Urls  []*UserURL             `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	// пусто на последней странице
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUserURLsResponse) Reset() {
+	*x = ListUserURLsResponse{}
+	mi := &file_shortener_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUserURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUserURLsResponse) ProtoMessage() {}
+
+func (x *ListUserURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUserURLsResponse.ProtoReflect.Descriptor instead.
+func (*ListUserURLsResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListUserURLsResponse) GetUrls() []*UserURL {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *ListUserURLsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type DeleteURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrls     []string               `protobuf:"bytes,1,rep,name=short_urls,json=shortUrls,proto3" json:"short_urls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteURLsRequest) Reset() {
+	*x = DeleteURLsRequest{}
+	mi := &file_shortener_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteURLsRequest) ProtoMessage() {}
+
+func (x *DeleteURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteURLsRequest.ProtoReflect.Descriptor instead.
+func (*DeleteURLsRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteURLsRequest) GetShortUrls() []string {
+	if x != nil {
+		return x.ShortUrls
+	}
+	return nil
+}
+
+type DeleteURLsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id задания на удаление, статус доступен через GetDeleteJob
+	JobId         string `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteURLsResponse) Reset() {
+	*x = DeleteURLsResponse{}
+	mi := &file_shortener_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteURLsResponse) ProtoMessage() {}
+
+func (x *DeleteURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteURLsResponse.ProtoReflect.Descriptor instead.
+func (*DeleteURLsResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteURLsResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type RestoreURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrls     []string               `protobuf:"bytes,1,rep,name=short_urls,json=shortUrls,proto3" json:"short_urls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreURLsRequest) Reset() {
+	*x = RestoreURLsRequest{}
+	mi := &file_shortener_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreURLsRequest) ProtoMessage() {}
+
+func (x *RestoreURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreURLsRequest.ProtoReflect.Descriptor instead.
+func (*RestoreURLsRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RestoreURLsRequest) GetShortUrls() []string {
+	if x != nil {
+		return x.ShortUrls
+	}
+	return nil
+}
+
+type RestoreURLsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ссылки пользователя, которые после вызова не помечены удаленными
+	ShortUrls     []string `protobuf:"bytes,1,rep,name=short_urls,json=shortUrls,proto3" json:"short_urls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreURLsResponse) Reset() {
+	*x = RestoreURLsResponse{}
+	mi := &file_shortener_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreURLsResponse) ProtoMessage() {}
+
+func (x *RestoreURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreURLsResponse.ProtoReflect.Descriptor instead.
+func (*RestoreURLsResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RestoreURLsResponse) GetShortUrls() []string {
+	if x != nil {
+		return x.ShortUrls
+	}
+	return nil
+}
+
+type GetDeleteJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeleteJobRequest) Reset() {
+	*x = GetDeleteJobRequest{}
+	mi := &file_shortener_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeleteJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeleteJobRequest) ProtoMessage() {}
+
+func (x *GetDeleteJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeleteJobRequest.ProtoReflect.Descriptor instead.
+func (*GetDeleteJobRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetDeleteJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type DeleteJobKey struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// pending, done или failed
+	Status        string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteJobKey) Reset() {
+	*x = DeleteJobKey{}
+	mi := &file_shortener_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteJobKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteJobKey) ProtoMessage() {}
+
+func (x *DeleteJobKey) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteJobKey.ProtoReflect.Descriptor instead.
+func (*DeleteJobKey) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DeleteJobKey) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *DeleteJobKey) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *DeleteJobKey) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetDeleteJobResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	// pending, done или failed
+	Status   string          `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Keys     []*DeleteJobKey `protobuf:"bytes,3,rep,name=keys,proto3" json:"keys,omitempty"`
+	Attempts int32           `protobuf:"varint,4,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	Error    string          `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	// unix time в секундах
+	CreatedAt     int64 `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     int64 `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeleteJobResponse) Reset() {
+	*x = GetDeleteJobResponse{}
+	mi := &file_shortener_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeleteJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeleteJobResponse) ProtoMessage() {}
+
+func (x *GetDeleteJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeleteJobResponse.ProtoReflect.Descriptor instead.
+func (*GetDeleteJobResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetDeleteJobResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *GetDeleteJobResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *GetDeleteJobResponse) GetKeys() []*DeleteJobKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *GetDeleteJobResponse) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *GetDeleteJobResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *GetDeleteJobResponse) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *GetDeleteJobResponse) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type UpdateURLRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	UrlId string                 `protobuf:"bytes,1,opt,name=url_id,json=urlId,proto3" json:"url_id,omitempty"`
+	// новая исходная ссылка, пусто - не меняется
+	Url string `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	// новая политика перехода, не задана - не меняется, пустая - переход по умолчанию
+	Redirect *RedirectPolicy `protobuf:"bytes,3,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	// новый момент истечения ссылки, unix time в секундах
+	ExpiresAt int64 `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// новое время жизни ссылки в секундах
+	Ttl int64 `protobuf:"varint,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// снять срок действия ссылки
+	NoExpiry      bool `protobuf:"varint,6,opt,name=no_expiry,json=noExpiry,proto3" json:"no_expiry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateURLRequest) Reset() {
+	*x = UpdateURLRequest{}
+	mi := &file_shortener_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLRequest) ProtoMessage() {}
+
+func (x *UpdateURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLRequest.ProtoReflect.Descriptor instead.
+func (*UpdateURLRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UpdateURLRequest) GetUrlId() string {
+	if x != nil {
+		return x.UrlId
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *UpdateURLRequest) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *UpdateURLRequest) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *UpdateURLRequest) GetNoExpiry() bool {
+	if x != nil {
+		return x.NoExpiry
+	}
+	return false
+}
+
+type UpdateURLResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl    string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Redirect    *RedirectPolicy        `protobuf:"bytes,3,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	// unix time в секундах, 0 - без срока действия
+	ExpiresAt     int64 `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateURLResponse) Reset() {
+	*x = UpdateURLResponse{}
+	mi := &file_shortener_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateURLResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLResponse) ProtoMessage() {}
+
+func (x *UpdateURLResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLResponse.ProtoReflect.Descriptor instead.
+func (*UpdateURLResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UpdateURLResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *UpdateURLResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *UpdateURLResponse) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *UpdateURLResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type GetURLHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UrlId         string                 `protobuf:"bytes,1,opt,name=url_id,json=urlId,proto3" json:"url_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetURLHistoryRequest) Reset() {
+	*x = GetURLHistoryRequest{}
+	mi := &file_shortener_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetURLHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetURLHistoryRequest) ProtoMessage() {}
+
+func (x *GetURLHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetURLHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetURLHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetURLHistoryRequest) GetUrlId() string {
+	if x != nil {
+		return x.UrlId
+	}
+	return ""
+}
+
+// состояние ссылки до изменения
+type URLHistoryEntry struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Redirect    *RedirectPolicy        `protobuf:"bytes,2,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	// unix time в секундах, 0 - без срока действия
+	ExpiresAt int64  `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	RequestId string `protobuf:"bytes,4,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// unix time в секундах
+	ChangedAt     int64 `protobuf:"varint,5,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistoryEntry) Reset() {
+	*x = URLHistoryEntry{}
+	mi := &file_shortener_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistoryEntry) ProtoMessage() {}
+
+func (x *URLHistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistoryEntry.ProtoReflect.Descriptor instead.
+func (*URLHistoryEntry) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *URLHistoryEntry) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *URLHistoryEntry) GetRedirect() *RedirectPolicy {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
+func (x *URLHistoryEntry) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *URLHistoryEntry) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *URLHistoryEntry) GetChangedAt() int64 {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return 0
+}
+
+type GetURLHistoryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// в порядке изменения
+	Entries       []*URLHistoryEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetURLHistoryResponse) Reset() {
+	*x = GetURLHistoryResponse{}
+	mi := &file_shortener_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetURLHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetURLHistoryResponse) ProtoMessage() {}
+
+func (x *GetURLHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetURLHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetURLHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetURLHistoryResponse) GetEntries() []*URLHistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type PingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	mi := &file_shortener_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{24}
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	mi := &file_shortener_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{25}
+}
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_shortener_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{26}
+}
+
+type GetStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Urls          int64                  `protobuf:"varint,1,opt,name=urls,proto3" json:"urls,omitempty"`
+	Users         int64                  `protobuf:"varint,2,opt,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsResponse) Reset() {
+	*x = GetStatsResponse{}
+	mi := &file_shortener_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsResponse) ProtoMessage() {}
+
+func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetStatsResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *GetStatsResponse) GetUrls() int64 {
+	if x != nil {
+		return x.Urls
+	}
+	return 0
+}
+
+func (x *GetStatsResponse) GetUsers() int64 {
+	if x != nil {
+		return x.Users
+	}
+	return 0
+}
+
+var File_shortener_proto protoreflect.FileDescriptor
+
+const file_shortener_proto_rawDesc = "" +
+	"\n" +
+	"\x0fshortener.proto\x12\tshortener\"&\n" +
+	"\rGetURLRequest\x12\x15\n" +
+	"\x06url_id\x18\x01 \x01(\tR\x05urlId\"b\n" +
+	"\x0eGetURLResponse\x12\x19\n" +
+	"\bfull_url\x18\x01 \x01(\tR\afullUrl\x125\n" +
+	"\bredirect\x18\x02 \x01(\v2\x19.shortener.RedirectPolicyR\bredirect\"\xb1\x01\n" +
+	"\x0eRedirectPolicy\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x1d\n" +
+	"\n" +
+	"pass_query\x18\x02 \x01(\bR\tpassQuery\x124\n" +
+	"\x03utm\x18\x03 \x03(\v2\".shortener.RedirectPolicy.UtmEntryR\x03utm\x1a6\n" +
+	"\bUtmEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb3\x01\n" +
+	"\x12CreateShortRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05alias\x18\x03 \x01(\tR\x05alias\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\x12\x10\n" +
+	"\x03ttl\x18\x05 \x01(\x03R\x03ttl\x125\n" +
+	"\bredirect\x18\x06 \x01(\v2\x19.shortener.RedirectPolicyR\bredirectJ\x04\b\x02\x10\x03R\auser_id\"d\n" +
+	"\x13CreateShortResponse\x12\x1d\n" +
+	"\n" +
+	"result_url\x18\x01 \x01(\tR\tresultUrl\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x15\n" +
+	"\x06url_id\x18\x03 \x01(\tR\x05urlId\"\xde\x01\n" +
+	"\x14CreateShortBatchItem\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x14\n" +
+	"\x05alias\x18\x03 \x01(\tR\x05alias\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\x12\x10\n" +
+	"\x03ttl\x18\x05 \x01(\x03R\x03ttl\x125\n" +
+	"\bredirect\x18\x06 \x01(\v2\x19.shortener.RedirectPolicyR\bredirect\"N\n" +
+	"\x17CreateShortBatchRequest\x123\n" +
+	"\x04urls\x18\x01 \x03(\v2\x1f.shortener.CreateShortBatchItemR\x04urls\"\x8a\x01\n" +
+	"\x16CreateShortBatchResult\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"j\n" +
+	"\x18CreateShortBatchResponse\x125\n" +
+	"\x04urls\x18\x01 \x03(\v2!.shortener.CreateShortBatchResultR\x04urls\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\"\x87\x02\n" +
+	"\x13ListUserURLsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x16\n" +
+	"\x06search\x18\x03 \x01(\tR\x06search\x12\x1d\n" +
+	"\adeleted\x18\x04 \x01(\bH\x00R\adeleted\x88\x01\x01\x12\x1d\n" +
+	"\aexpired\x18\x05 \x01(\bH\x01R\aexpired\x88\x01\x01\x12!\n" +
+	"\fcreated_from\x18\x06 \x01(\x03R\vcreatedFrom\x12\x1d\n" +
+	"\n" +
+	"created_to\x18\a \x01(\x03R\tcreatedTo\x12\x12\n" +
+	"\x04sort\x18\b \x01(\tR\x04sortB\n" +
+	"\n" +
+	"\b_deletedB\n" +
+	"\n" +
+	"\b_expired\"\xd8\x01\n" +
+	"\aUserURL\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\x12\x18\n" +
+	"\adeleted\x18\x05 \x01(\bR\adeleted\x125\n" +
+	"\bredirect\x18\x06 \x01(\v2\x19.shortener.RedirectPolicyR\bredirect\"_\n" +
+	"\x14ListUserURLsResponse\x12&\n" +
+	"\x04urls\x18\x01 \x03(\v2\x12.shortener.UserURLR\x04urls\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"2\n" +
+	"\x11DeleteURLsRequest\x12\x1d\n" +
+	"\n" +
+	"short_urls\x18\x01 \x03(\tR\tshortUrls\"+\n" +
+	"\x12DeleteURLsResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"3\n" +
+	"\x12RestoreURLsRequest\x12\x1d\n" +
+	"\n" +
+	"short_urls\x18\x01 \x03(\tR\tshortUrls\"4\n" +
+	"\x13RestoreURLsResponse\x12\x1d\n" +
+	"\n" +
+	"short_urls\x18\x01 \x03(\tR\tshortUrls\",\n" +
+	"\x13GetDeleteJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"Y\n" +
+	"\fDeleteJobKey\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\xe2\x01\n" +
+	"\x14GetDeleteJobResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12+\n" +
+	"\x04keys\x18\x03 \x03(\v2\x17.shortener.DeleteJobKeyR\x04keys\x12\x1a\n" +
+	"\battempts\x18\x04 \x01(\x05R\battempts\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\x03R\tupdatedAt\"\xc0\x01\n" +
+	"\x10UpdateURLRequest\x12\x15\n" +
+	"\x06url_id\x18\x01 \x01(\tR\x05urlId\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x125\n" +
+	"\bredirect\x18\x03 \x01(\v2\x19.shortener.RedirectPolicyR\bredirect\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\x12\x10\n" +
+	"\x03ttl\x18\x05 \x01(\x03R\x03ttl\x12\x1b\n" +
+	"\tno_expiry\x18\x06 \x01(\bR\bnoExpiry\"\xa9\x01\n" +
+	"\x11UpdateURLResponse\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x125\n" +
+	"\bredirect\x18\x03 \x01(\v2\x19.shortener.RedirectPolicyR\bredirect\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\"-\n" +
+	"\x14GetURLHistoryRequest\x12\x15\n" +
+	"\x06url_id\x18\x01 \x01(\tR\x05urlId\"\xc8\x01\n" +
+	"\x0fURLHistoryEntry\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x125\n" +
+	"\bredirect\x18\x02 \x01(\v2\x19.shortener.RedirectPolicyR\bredirect\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\x03R\texpiresAt\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x04 \x01(\tR\trequestId\x12\x1d\n" +
+	"\n" +
+	"changed_at\x18\x05 \x01(\x03R\tchangedAt\"M\n" +
+	"\x15GetURLHistoryResponse\x124\n" +
+	"\aentries\x18\x01 \x03(\v2\x1a.shortener.URLHistoryEntryR\aentries\"\r\n" +
+	"\vPingRequest\"\x0e\n" +
+	"\fPingResponse\"\x11\n" +
+	"\x0fGetStatsRequest\"<\n" +
+	"\x10GetStatsResponse\x12\x12\n" +
+	"\x04urls\x18\x01 \x01(\x03R\x04urls\x12\x14\n" +
+	"\x05users\x18\x02 \x01(\x03R\x05users2\xca\x06\n" +
+	"\tShortener\x12=\n" +
+	"\x06GetURL\x12\x18.shortener.GetURLRequest\x1a\x19.shortener.GetURLResponse\x12L\n" +
+	"\vCreateShort\x12\x1d.shortener.CreateShortRequest\x1a\x1e.shortener.CreateShortResponse\x12[\n" +
+	"\x10CreateShortBatch\x12\".shortener.CreateShortBatchRequest\x1a#.shortener.CreateShortBatchResponse\x12O\n" +
+	"\fListUserURLs\x12\x1e.shortener.ListUserURLsRequest\x1a\x1f.shortener.ListUserURLsResponse\x12I\n" +
+	"\n" +
+	"DeleteURLs\x12\x1c.shortener.DeleteURLsRequest\x1a\x1d.shortener.DeleteURLsResponse\x12O\n" +
+	"\fGetDeleteJob\x12\x1e.shortener.GetDeleteJobRequest\x1a\x1f.shortener.GetDeleteJobResponse\x12L\n" +
+	"\vRestoreURLs\x12\x1d.shortener.RestoreURLsRequest\x1a\x1e.shortener.RestoreURLsResponse\x12F\n" +
+	"\tUpdateURL\x12\x1b.shortener.UpdateURLRequest\x1a\x1c.shortener.UpdateURLResponse\x12R\n" +
+	"\rGetURLHistory\x12\x1f.shortener.GetURLHistoryRequest\x1a .shortener.GetURLHistoryResponse\x127\n" +
+	"\x04Ping\x12\x16.shortener.PingRequest\x1a\x17.shortener.PingResponse\x12C\n" +
+	"\bGetStats\x12\x1a.shortener.GetStatsRequest\x1a\x1b.shortener.GetStatsResponseB\x0eZ\fshortener/pbb\x06proto3"
 
 var (
 	file_shortener_proto_rawDescOnce sync.Once
@@ -254,23 +1845,79 @@ func file_shortener_proto_rawDescGZIP() []byte {
 	return file_shortener_proto_rawDescData
 }
 
-var file_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
 var file_shortener_proto_goTypes = []any{
-	(*GetURLRequest)(nil),       // 0: shortener.GetURLRequest
-	(*GetURLResponse)(nil),      // 1: shortener.GetURLResponse
-	(*CreateShortRequest)(nil),  // 2: shortener.CreateShortRequest
-	(*CreateShortResponse)(nil), // 3: shortener.CreateShortResponse
+	(*GetURLRequest)(nil),            // 0: shortener.GetURLRequest
+	(*GetURLResponse)(nil),           // 1: shortener.GetURLResponse
+	(*RedirectPolicy)(nil),           // 2: shortener.RedirectPolicy
+	(*CreateShortRequest)(nil),       // 3: shortener.CreateShortRequest
+	(*CreateShortResponse)(nil),      // 4: shortener.CreateShortResponse
+	(*CreateShortBatchItem)(nil),     // 5: shortener.CreateShortBatchItem
+	(*CreateShortBatchRequest)(nil),  // 6: shortener.CreateShortBatchRequest
+	(*CreateShortBatchResult)(nil),   // 7: shortener.CreateShortBatchResult
+	(*CreateShortBatchResponse)(nil), // 8: shortener.CreateShortBatchResponse
+	(*ListUserURLsRequest)(nil),      // 9: shortener.ListUserURLsRequest
+	(*UserURL)(nil),                  // 10: shortener.UserURL
+	(*ListUserURLsResponse)(nil),     // 11: shortener.ListUserURLsResponse
+	(*DeleteURLsRequest)(nil),        // 12: shortener.DeleteURLsRequest
+	(*DeleteURLsResponse)(nil),       // 13: shortener.DeleteURLsResponse
+	(*RestoreURLsRequest)(nil),       // 14: shortener.RestoreURLsRequest
+	(*RestoreURLsResponse)(nil),      // 15: shortener.RestoreURLsResponse
+	(*GetDeleteJobRequest)(nil),      // 16: shortener.GetDeleteJobRequest
+	(*DeleteJobKey)(nil),             // 17: shortener.DeleteJobKey
+	(*GetDeleteJobResponse)(nil),     // 18: shortener.GetDeleteJobResponse
+	(*UpdateURLRequest)(nil),         // 19: shortener.UpdateURLRequest
+	(*UpdateURLResponse)(nil),        // 20: shortener.UpdateURLResponse
+	(*GetURLHistoryRequest)(nil),     // 21: shortener.GetURLHistoryRequest
+	(*URLHistoryEntry)(nil),          // 22: shortener.URLHistoryEntry
+	(*GetURLHistoryResponse)(nil),    // 23: shortener.GetURLHistoryResponse
+	(*PingRequest)(nil),              // 24: shortener.PingRequest
+	(*PingResponse)(nil),             // 25: shortener.PingResponse
+	(*GetStatsRequest)(nil),          // 26: shortener.GetStatsRequest
+	(*GetStatsResponse)(nil),         // 27: shortener.GetStatsResponse
+	nil,                              // 28: shortener.RedirectPolicy.UtmEntry
 }
 var file_shortener_proto_depIdxs = []int32{
-	0, // 0: shortener.Shortener.GetURL:input_type -> shortener.GetURLRequest
-	2, // 1: shortener.Shortener.CreateShort:input_type -> shortener.CreateShortRequest
-	1, // 2: shortener.Shortener.GetURL:output_type -> shortener.GetURLResponse
-	3, // 3: shortener.Shortener.CreateShort:output_type -> shortener.CreateShortResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: shortener.GetURLResponse.redirect:type_name -> shortener.RedirectPolicy
+	28, // 1: shortener.RedirectPolicy.utm:type_name -> shortener.RedirectPolicy.UtmEntry
+	2,  // 2: shortener.CreateShortRequest.redirect:type_name -> shortener.RedirectPolicy
+	2,  // 3: shortener.CreateShortBatchItem.redirect:type_name -> shortener.RedirectPolicy
+	5,  // 4: shortener.CreateShortBatchRequest.urls:type_name -> shortener.CreateShortBatchItem
+	7,  // 5: shortener.CreateShortBatchResponse.urls:type_name -> shortener.CreateShortBatchResult
+	2,  // 6: shortener.UserURL.redirect:type_name -> shortener.RedirectPolicy
+	10, // 7: shortener.ListUserURLsResponse.urls:type_name -> shortener.UserURL
+	17, // 8: shortener.GetDeleteJobResponse.keys:type_name -> shortener.DeleteJobKey
+	2,  // 9: shortener.UpdateURLRequest.redirect:type_name -> shortener.RedirectPolicy
+	2,  // 10: shortener.UpdateURLResponse.redirect:type_name -> shortener.RedirectPolicy
+	2,  // 11: shortener.URLHistoryEntry.redirect:type_name -> shortener.RedirectPolicy
+	22, // 12: shortener.GetURLHistoryResponse.entries:type_name -> shortener.URLHistoryEntry
+	0,  // 13: shortener.Shortener.GetURL:input_type -> shortener.GetURLRequest
+	3,  // 14: shortener.Shortener.CreateShort:input_type -> shortener.CreateShortRequest
+	6,  // 15: shortener.Shortener.CreateShortBatch:input_type -> shortener.CreateShortBatchRequest
+	9,  // 16: shortener.Shortener.ListUserURLs:input_type -> shortener.ListUserURLsRequest
+	12, // 17: shortener.Shortener.DeleteURLs:input_type -> shortener.DeleteURLsRequest
+	16, // 18: shortener.Shortener.GetDeleteJob:input_type -> shortener.GetDeleteJobRequest
+	14, // 19: shortener.Shortener.RestoreURLs:input_type -> shortener.RestoreURLsRequest
+	19, // 20: shortener.Shortener.UpdateURL:input_type -> shortener.UpdateURLRequest
+	21, // 21: shortener.Shortener.GetURLHistory:input_type -> shortener.GetURLHistoryRequest
+	24, // 22: shortener.Shortener.Ping:input_type -> shortener.PingRequest
+	26, // 23: shortener.Shortener.GetStats:input_type -> shortener.GetStatsRequest
+	1,  // 24: shortener.Shortener.GetURL:output_type -> shortener.GetURLResponse
+	4,  // 25: shortener.Shortener.CreateShort:output_type -> shortener.CreateShortResponse
+	8,  // 26: shortener.Shortener.CreateShortBatch:output_type -> shortener.CreateShortBatchResponse
+	11, // 27: shortener.Shortener.ListUserURLs:output_type -> shortener.ListUserURLsResponse
+	13, // 28: shortener.Shortener.DeleteURLs:output_type -> shortener.DeleteURLsResponse
+	18, // 29: shortener.Shortener.GetDeleteJob:output_type -> shortener.GetDeleteJobResponse
+	15, // 30: shortener.Shortener.RestoreURLs:output_type -> shortener.RestoreURLsResponse
+	20, // 31: shortener.Shortener.UpdateURL:output_type -> shortener.UpdateURLResponse
+	23, // 32: shortener.Shortener.GetURLHistory:output_type -> shortener.GetURLHistoryResponse
+	25, // 33: shortener.Shortener.Ping:output_type -> shortener.PingResponse
+	27, // 34: shortener.Shortener.GetStats:output_type -> shortener.GetStatsResponse
+	24, // [24:35] is the sub-list for method output_type
+	13, // [13:24] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_shortener_proto_init() }
@@ -278,13 +1925,14 @@ func file_shortener_proto_init() {
 	if File_shortener_proto != nil {
 		return
 	}
+	file_shortener_proto_msgTypes[9].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shortener_proto_rawDesc), len(file_shortener_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   29,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
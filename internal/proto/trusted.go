@@ -0,0 +1,58 @@
+package pb
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"go.uber.org/zap"
+	codes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	status "google.golang.org/grpc/status"
+)
+
+const realIPMetadata = "x-real-ip"
+
+// checkTrustedSubnet - проверка вхождения ip клиента в доверенную подсеть. Адрес берется из соединения,
+// x-real-ip из метаданных учитывается только от доверенного прокси
+func checkTrustedSubnet(ctx context.Context) error {
+	trustedIPNet, err := netip.ParsePrefix(app.ServerConf.TrustedSubnet)
+	if err != nil {
+		ctxlog.From(ctx, app.Log).Error("Can't parse trustedIP from config", zap.Error(err))
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	addr, ok := clientIP(ctx)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		ctxlog.From(ctx, app.Log).Error("Can't parse client ip", zap.Error(err))
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	if !trustedIPNet.Contains(ip.Unmap()) {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	return nil
+}
+
+// clientIP - ip клиента: адрес соединения или x-real-ip из метаданных, если соединение установлено доверенным прокси
+func clientIP(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+
+	var realIP string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(realIPMetadata); len(values) > 0 {
+		realIP = values[0]
+	}
+	return app.TrustedProxies.ClientIP(p.Addr.String(), realIP), true
+}
@@ -0,0 +1,52 @@
+package pb
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/app"
+	"github.com/kirillmashkov/shortener.git/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestCheckTrustedSubnet(t *testing.T) {
+	restoreServerConf(t)
+	app.ServerConf.TrustedSubnet = "192.168.1.0/24"
+	proxies, err := ratelimit.ParseProxies("127.0.0.0/8")
+	require.NoError(t, err)
+	app.TrustedProxies = proxies
+	defer func() {
+		app.TrustedProxies = nil
+	}()
+
+	tests := []struct {
+		name    string
+		peer    string
+		realIP  string
+		trusted bool
+	}{
+		{name: "peer in subnet", peer: "192.168.1.5", trusted: true},
+		{name: "peer out of subnet", peer: "10.0.0.1"},
+		{name: "spoofed header of untrusted peer", peer: "10.0.0.1", realIP: "192.168.1.5"},
+		{name: "header of trusted proxy", peer: "127.0.0.1", realIP: "192.168.1.5", trusted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 1234}})
+			if tt.realIP != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(realIPMetadata, tt.realIP))
+			}
+
+			err := checkTrustedSubnet(ctx)
+			if tt.trusted {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Proxies - подсети доверенных прокси. Только им разрешено передавать ip клиента в X-Real-IP
+type Proxies []netip.Prefix
+
+// ParseProxies - разбор списка подсетей вида "10.0.0.0/8,192.168.1.10/32"
+func ParseProxies(spec string) (Proxies, error) {
+	var proxies Proxies
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// ClientIP - ip клиента для ключа лимита и статистики переходов. remoteAddr - адрес соединения в форме host:port или host,
+// realIP - значение X-Real-IP. Значение X-Real-IP используется, только если соединение
+// установлено доверенным прокси, иначе клиент мог бы обходить лимит или подделывать адрес, меняя заголовок
+func (p Proxies) ClientIP(remoteAddr string, realIP string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	if realIP == "" || !p.contains(host) {
+		return host
+	}
+
+	if ip, err := netip.ParseAddr(strings.TrimSpace(realIP)); err == nil {
+		return ip.String()
+	}
+	return host
+}
+
+func (p Proxies) contains(host string) bool {
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
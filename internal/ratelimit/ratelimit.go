@@ -0,0 +1,183 @@
+// Модуль ratelimit - ограничение частоты запросов клиента алгоритмом token bucket
+// с отдельными лимитами для групп маршрутов. Используется REST и gRPC серверами
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Группы маршрутов
+const (
+	// GroupRedirect - переход по короткой ссылке
+	GroupRedirect = "redirect"
+	// GroupShorten - создание коротких ссылок
+	GroupShorten = "shorten"
+	// GroupAPI - остальные вызовы API
+	GroupAPI = "api"
+)
+
+const cleanupInterval = time.Minute
+
+// maxClients - максимальное кол-во отслеживаемых корзин. Новые клиенты сверх него
+// делят одну общую корзину группы, пока очистка не освободит место
+const maxClients = 100000
+
+// overflowKey - ключ общей корзины клиентов сверх maxClients
+const overflowKey = "overflow"
+
+// Limit - лимит группы: пополнение в секунду и емкость корзины
+type Limit struct {
+	RPS   float64
+	Burst int
+}
+
+// Policy - лимиты по группам маршрутов. Для группы без лимита запросы не ограничиваются
+type Policy struct {
+	limits      map[string]Limit
+	mu          sync.Mutex
+	clients     map[string]*client
+	lastCleanup time.Time
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	// refill - время, за которое пустая корзина наполняется полностью
+	refill time.Duration
+}
+
+// ParsePolicy - разбор лимитов вида "redirect=100:200,shorten=5:10", где 100 - запросов в секунду, 200 - емкость
+func ParsePolicy(spec string) (*Policy, error) {
+	limits := map[string]Limit{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		group, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: expected group=rps:burst", item)
+		}
+		rpsStr, burstStr, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: expected group=rps:burst", item)
+		}
+
+		rps, err := strconv.ParseFloat(rpsStr, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("rate limit %q: rps must be positive number", item)
+		}
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("rate limit %q: burst must be positive integer", item)
+		}
+
+		limits[strings.TrimSpace(group)] = Limit{RPS: rps, Burst: burst}
+	}
+
+	return NewPolicy(limits), nil
+}
+
+// NewPolicy - конструктор
+func NewPolicy(limits map[string]Limit) *Policy {
+	return &Policy{limits: limits, clients: map[string]*client{}}
+}
+
+// Allow - проверка, может ли клиент с ключом key выполнить запрос группы group.
+// Если не может, возвращает время, через которое запрос будет разрешен
+func (p *Policy) Allow(group string, key string, now time.Time) (bool, time.Duration) {
+	limit, ok := p.limits[group]
+	if !ok {
+		return true, 0
+	}
+
+	p.mu.Lock()
+	id := group + "|" + key
+	c, ok := p.clients[id]
+	if !ok {
+		if len(p.clients) >= maxClients && now.Sub(p.lastCleanup) >= time.Second {
+			p.cleanupLocked(now)
+		}
+		if len(p.clients) >= maxClients {
+			id = group + "|" + overflowKey
+			c, ok = p.clients[id]
+		}
+	}
+	if !ok {
+		c = &client{
+			limiter: rate.NewLimiter(rate.Limit(limit.RPS), limit.Burst),
+			refill:  time.Duration(float64(limit.Burst) / limit.RPS * float64(time.Second)),
+		}
+		p.clients[id] = c
+	}
+	c.lastSeen = now
+	p.mu.Unlock()
+
+	reservation := c.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return true, 0
+	}
+
+	reservation.CancelAt(now)
+	return false, delay
+}
+
+// Run - периодическое удаление корзин клиентов, которые не делали запросов, пока корзина не наполнилась.
+// Такая корзина не отличается от новой, поэтому ее удаление не меняет лимит клиента
+func (p *Policy) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.cleanup(now)
+		}
+	}
+}
+
+func (p *Policy) cleanup(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cleanupLocked(now)
+}
+
+func (p *Policy) cleanupLocked(now time.Time) {
+	p.lastCleanup = now
+	for id, c := range p.clients {
+		if now.Sub(c.lastSeen) >= c.refill {
+			delete(p.clients, id)
+		}
+	}
+}
+
+// UserKey - ключ клиента с валидным токеном
+func UserKey(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// IPKey - ключ анонимного клиента
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// RetryAfterSeconds - значение Retry-After: задержка, округленная вверх до целых секунд
+func RetryAfterSeconds(delay time.Duration) int {
+	seconds := int((delay + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy(" redirect=100:200, shorten=0.5:1 ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		GroupRedirect: {RPS: 100, Burst: 200},
+		GroupShorten:  {RPS: 0.5, Burst: 1},
+	}, policy.limits)
+
+	for _, spec := range []string{"redirect", "redirect=100", "redirect=x:1", "redirect=1:0", "redirect=-1:1"} {
+		_, err := ParsePolicy(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestPolicyAllow(t *testing.T) {
+	policy := NewPolicy(map[string]Limit{GroupShorten: {RPS: 1, Burst: 2}})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		allowed, _ := policy.Allow(GroupShorten, UserKey(1), now)
+		assert.True(t, allowed)
+	}
+
+	allowed, delay := policy.Allow(GroupShorten, UserKey(1), now)
+	assert.False(t, allowed)
+	assert.InDelta(t, time.Second, delay, float64(10*time.Millisecond))
+
+	allowed, _ = policy.Allow(GroupShorten, UserKey(2), now)
+	assert.True(t, allowed, "other client has own bucket")
+
+	allowed, _ = policy.Allow(GroupRedirect, UserKey(1), now)
+	assert.True(t, allowed, "group without limit is not limited")
+
+	allowed, _ = policy.Allow(GroupShorten, UserKey(1), now.Add(time.Second))
+	assert.True(t, allowed, "rejected request must not consume token")
+
+	policy.cleanup(now.Add(2 * time.Second))
+	assert.Len(t, policy.clients, 1, "bucket that is not full yet must be kept")
+
+	policy.cleanup(now.Add(3 * time.Second))
+	assert.Empty(t, policy.clients, "full bucket must be evicted")
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(300*time.Millisecond))
+	assert.Equal(t, 2, RetryAfterSeconds(1100*time.Millisecond))
+}
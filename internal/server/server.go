@@ -1,6 +1,10 @@
 package server
 
+import "context"
+
+// Server - сервер приложения. Run блокируется до остановки, Shutdown дожидается
+// завершения принятых запросов, пока не отменен ctx
 type Server interface {
 	Run() error
-	Shutdown() error
-}
\ No newline at end of file
+	Shutdown(ctx context.Context) error
+}
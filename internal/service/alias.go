@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+)
+
+const (
+	aliasMinLen = 3
+	aliasMaxLen = 64
+)
+
+// reservedAliases - ключи, совпадающие с маршрутами сервиса
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"ping":    {},
+	"debug":   {},
+	"healthz": {},
+	"readyz":  {},
+	"metrics": {},
+}
+
+// validateAlias - проверка пользовательского ключа короткой ссылки
+func validateAlias(alias string) error {
+	if len(alias) < aliasMinLen || len(alias) > aliasMaxLen {
+		return fmt.Errorf("%w: length must be from %d to %d symbols", model.ErrInvalidAlias, aliasMinLen, aliasMaxLen)
+	}
+
+	for _, c := range alias {
+		if !isAliasSymbol(c) {
+			return fmt.Errorf("%w: symbol %q is not allowed, use latin letters, digits, '-' and '_'", model.ErrInvalidAlias, c)
+		}
+	}
+
+	if isReservedKey(alias) {
+		return fmt.Errorf("%w: %q is reserved", model.ErrInvalidAlias, alias)
+	}
+
+	return nil
+}
+
+// isReservedKey - совпадает ли ключ с маршрутом сервиса без учета регистра
+func isReservedKey(key string) bool {
+	_, ok := reservedAliases[strings.ToLower(key)]
+	return ok
+}
+
+func isAliasSymbol(c rune) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-' || c == '_'
+}
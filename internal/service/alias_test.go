@@ -0,0 +1,37 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "valid alias", alias: "spring-sale", wantErr: false},
+		{name: "valid alias with underscore and digits", alias: "Sale_2025", wantErr: false},
+		{name: "too short", alias: "ab", wantErr: true},
+		{name: "too long", alias: strings.Repeat("a", aliasMaxLen+1), wantErr: true},
+		{name: "slash is not allowed", alias: "spring/sale", wantErr: true},
+		{name: "non latin symbols", alias: "распродажа", wantErr: true},
+		{name: "reserved word", alias: "api", wantErr: true},
+		{name: "reserved word in other case", alias: "Debug", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateAlias(test.alias)
+			if test.wantErr {
+				assert.ErrorIs(t, err, model.ErrInvalidAlias)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
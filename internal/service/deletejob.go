@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// maxRetryBackoff - максимальная задержка перед повторной попыткой выполнения задания
+const maxRetryBackoff = time.Minute
+
+const errKeyNotFound = "url not found"
+
+// newDeleteJob - новое задание на удаление, повторяющиеся ключи удаляются
+func newDeleteJob(userID int, shortURLs []string, requestID string, now time.Time) model.DeleteJob {
+	keys := make([]model.DeleteJobKey, 0, len(shortURLs))
+	seen := make(map[string]struct{}, len(shortURLs))
+	for _, key := range shortURLs {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, model.DeleteJobKey{Key: key, Status: model.JobPending})
+	}
+
+	return model.DeleteJob{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Status:    model.JobPending,
+		Keys:      keys,
+		RequestID: requestID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// collectDeleteBatch - ожидание первого задания в очереди и добор следующих, пока не истекло окно DeleteBatchWindow
+// или не набрано DeleteBatchSize заданий. Возвращает false, когда очередь закрыта и опустошена
+func (s *Service) collectDeleteBatch() ([]string, bool) {
+	jobID, ok := <-s.deletes.items
+	if !ok {
+		return nil, false
+	}
+	metrics.DeleteQueueDepth.Dec()
+
+	size := max(s.cfg.DeleteBatchSize, 1)
+	batch := make([]string, 1, size)
+	batch[0] = jobID
+
+	var window <-chan time.Time
+	if s.cfg.DeleteBatchWindow > 0 {
+		timer := time.NewTimer(s.cfg.DeleteBatchWindow)
+		defer timer.Stop()
+		window = timer.C
+	}
+
+	for len(batch) < size {
+		if window == nil {
+			// без окна берутся только уже ожидающие задания
+			select {
+			case jobID, ok = <-s.deletes.items:
+			default:
+				return batch, true
+			}
+		} else {
+			select {
+			case jobID, ok = <-s.deletes.items:
+			case <-window:
+				return batch, true
+			}
+		}
+
+		if !ok {
+			return batch, false
+		}
+		metrics.DeleteQueueDepth.Dec()
+		batch = append(batch, jobID)
+	}
+
+	return batch, true
+}
+
+// processDeleteJobs - попытка выполнения пачки заданий одной операцией хранилища. При ошибке хранилища
+// задания остаются в ожидании и ставятся в очередь повторно с экспоненциальной задержкой, пока не исчерпаны попытки
+func (s *Service) processDeleteJobs(ctx context.Context, jobIDs []string) {
+	jobs := s.loadDeleteJobs(ctx, jobIDs)
+	if len(jobs) == 0 {
+		return
+	}
+
+	var keys []model.UserKey
+	for _, job := range jobs {
+		for _, key := range job.Keys {
+			if key.Status == model.JobPending {
+				keys = append(keys, model.UserKey{UserID: job.UserID, Key: key.Key})
+			}
+		}
+	}
+
+	spanCtx, span := startSpan(ctx, "DeleteURLs",
+		attribute.Int("shortener.jobs", len(jobs)),
+		attribute.Int("shortener.batch_size", len(keys)))
+	deleted, err := s.storage.DeleteURLs(spanCtx, keys)
+	endSpan(span, err)
+
+	if err != nil && s.deleteCtx.Err() != nil {
+		s.log.Info("Delete jobs interrupted by shutdown, left pending until restart", zap.Int("jobs", len(jobs)))
+		return
+	}
+
+	owned := make(map[model.UserKey]struct{}, len(deleted))
+	for _, key := range deleted {
+		owned[key] = struct{}{}
+	}
+	for _, job := range jobs {
+		s.finishDeleteJob(ctx, job, owned, err)
+	}
+}
+
+// loadDeleteJobs - загрузка ожидающих заданий пачки, повторяющиеся и завершенные задания пропускаются
+func (s *Service) loadDeleteJobs(ctx context.Context, jobIDs []string) []model.DeleteJob {
+	jobs := make([]model.DeleteJob, 0, len(jobIDs))
+	seen := make(map[string]struct{}, len(jobIDs))
+	for _, jobID := range jobIDs {
+		if _, ok := seen[jobID]; ok {
+			continue
+		}
+		seen[jobID] = struct{}{}
+
+		job, err := s.jobs.GetJob(ctx, jobID)
+		if err != nil {
+			s.log.Error("Error get delete job", zap.String("job", jobID), zap.Error(err))
+			if !errors.Is(err, model.ErrJobNotFound) {
+				s.retryLoadDeleteJob(ctx, jobID, err)
+			}
+			continue
+		}
+		s.resetLoadFailures(jobID)
+		if !job.Finished() {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+// retryLoadDeleteJob - повтор задания, которое не удалось прочитать из хранилища, с экспоненциальной задержкой.
+// После DeleteMaxAttempts неудачных чтений задание помечается невыполненным и больше не повторяется.
+// Остальные поля задания не перезаписываются, чтобы не потерять пользователя и ключи задания
+func (s *Service) retryLoadDeleteJob(ctx context.Context, jobID string, err error) {
+	s.loadFailuresMu.Lock()
+	s.loadFailures[jobID]++
+	attempts := s.loadFailures[jobID]
+	if attempts >= s.cfg.DeleteMaxAttempts {
+		delete(s.loadFailures, jobID)
+	}
+	s.loadFailuresMu.Unlock()
+
+	if attempts < s.cfg.DeleteMaxAttempts {
+		s.retryDeleteJob(jobID, retryDelay(s.cfg.DeleteRetryBackoff, attempts))
+		return
+	}
+
+	if failErr := s.jobs.FailJob(ctx, jobID, "load job: "+err.Error(), time.Now().UTC()); failErr != nil {
+		s.log.Error("Error save delete job", zap.String("job", jobID), zap.Error(failErr))
+	}
+	s.log.Error("Delete job failed, can't load it", zap.String("job", jobID), zap.Int("attempts", attempts), zap.Error(err))
+}
+
+func (s *Service) resetLoadFailures(jobID string) {
+	s.loadFailuresMu.Lock()
+	delete(s.loadFailures, jobID)
+	s.loadFailuresMu.Unlock()
+}
+
+// finishDeleteJob - сохранение результата попытки выполнения задания. Ссылка, не найденная
+// среди ссылок пользователя owned, считается не найденной
+func (s *Service) finishDeleteJob(ctx context.Context, job model.DeleteJob, owned map[model.UserKey]struct{}, err error) {
+	if job.RequestID != "" {
+		ctx = ctxlog.New(ctx, s.log, job.RequestID)
+		ctxlog.With(ctx, zap.Int("user_id", job.UserID))
+	}
+	log := ctxlog.From(ctx, s.log).With(zap.String("job", job.ID))
+
+	if err != nil {
+		job.Attempts++
+		job.Error = err.Error()
+		if job.Attempts >= s.cfg.DeleteMaxAttempts {
+			failPendingKeys(&job, job.Error)
+		}
+	} else {
+		for i, key := range job.Keys {
+			if key.Status != model.JobPending {
+				continue
+			}
+			if _, ok := owned[model.UserKey{UserID: job.UserID, Key: key.Key}]; ok {
+				job.Keys[i].Status = model.JobDone
+				continue
+			}
+			job.Keys[i].Status = model.JobFailed
+			job.Keys[i].Error = errKeyNotFound
+		}
+	}
+	job.Status = jobStatus(job.Keys)
+	job.UpdatedAt = time.Now().UTC()
+
+	if saveErr := s.jobs.SaveJob(ctx, job); saveErr != nil {
+		log.Error("Error save delete job", zap.Error(saveErr))
+	}
+
+	switch job.Status {
+	case model.JobPending:
+		delay := retryDelay(s.cfg.DeleteRetryBackoff, job.Attempts)
+		log.Warn("Error delete short urls, retrying", zap.Int("attempts", job.Attempts), zap.Duration("delay", delay), zap.Error(err))
+		s.retryDeleteJob(job.ID, delay)
+	case model.JobFailed:
+		log.Error("Delete job failed", zap.Int("attempts", job.Attempts), zap.String("error", job.Error))
+	default:
+		log.Debug("Delete job done", zap.Int("count", len(job.Keys)))
+	}
+}
+
+// retryDeleteJob - постановка задания в очередь после задержки. Если очередь к тому времени закрыта,
+// задание остается в ожидании до перезапуска
+func (s *Service) retryDeleteJob(jobID string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		if err := s.deletes.push(s.deleteCtx, jobID); err != nil {
+			s.log.Info("Delete job retry is postponed until restart", zap.String("job", jobID), zap.Error(err))
+		}
+	})
+}
+
+// sweepDeleteJobs - удаление завершенных заданий старше DeleteJobRetention
+func (s *Service) sweepDeleteJobs(ctx context.Context) {
+	count, err := s.jobs.DeleteFinishedJobs(ctx, time.Now().Add(-s.cfg.DeleteJobRetention))
+	if err != nil {
+		s.log.Error("Error delete finished delete jobs", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		s.log.Info("Finished delete jobs deleted", zap.Int("count", count))
+	}
+}
+
+func failPendingKeys(job *model.DeleteJob, reason string) {
+	for i := range job.Keys {
+		if job.Keys[i].Status == model.JobPending {
+			job.Keys[i].Status = model.JobFailed
+			job.Keys[i].Error = reason
+		}
+	}
+}
+
+// jobStatus - задание в ожидании, пока есть ожидающие ссылки, и не выполнено, если не удалена хотя бы одна ссылка
+func jobStatus(keys []model.DeleteJobKey) string {
+	status := model.JobDone
+	for _, key := range keys {
+		switch key.Status {
+		case model.JobPending:
+			return model.JobPending
+		case model.JobFailed:
+			status = model.JobFailed
+		}
+	}
+	return status
+}
+
+// retryDelay - задержка перед повторной попыткой: backoff, удваиваемый с каждой неудачной попыткой, не более maxRetryBackoff
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+)
+
+// deleteQueue - очередь id заданий на удаление ссылок. После закрытия новые задания не принимаются,
+// а уже принятые остаются доступными обработчику до опустошения очереди
+type deleteQueue struct {
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{}
+	// senders - push, ожидающие места в очереди. items закрывается только после их завершения
+	senders sync.WaitGroup
+	items   chan string
+}
+
+func newDeleteQueue(size int) *deleteQueue {
+	return &deleteQueue{items: make(chan string, size), closing: make(chan struct{})}
+}
+
+// push - постановка в очередь. Если очередь заполнена, ждет места, закрытия очереди или отмены ctx
+func (q *deleteQueue) push(ctx context.Context, jobID string) error {
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return model.ErrShuttingDown
+	}
+	q.senders.Add(1)
+	q.mu.RUnlock()
+	defer q.senders.Done()
+
+	select {
+	case q.items <- jobID:
+		metrics.DeleteQueueDepth.Inc()
+		return nil
+	case <-q.closing:
+		return model.ErrShuttingDown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close - прекращение приема запросов. Ожидающие места push завершаются с model.ErrShuttingDown
+func (q *deleteQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.closing)
+	q.mu.Unlock()
+
+	q.senders.Wait()
+	close(q.items)
+}
+
+func (q *deleteQueue) isClosed() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.closed
+}
+
+func (q *deleteQueue) len() int {
+	return len(q.items)
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/keygen"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// blockingStore - хранилище, удаление в котором ждет отмены контекста
+type blockingStore struct {
+	*memory.StoreURLMap
+	started chan struct{}
+}
+
+func (s *blockingStore) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// failingStore - хранилище, удаление в котором завершается ошибкой первые failures раз
+type failingStore struct {
+	*memory.StoreURLMap
+	failures atomic.Int32
+}
+
+func (s *failingStore) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	if s.failures.Add(-1) >= 0 {
+		return nil, errors.New("storage is unavailable")
+	}
+	return s.StoreURLMap.DeleteURLs(ctx, keys)
+}
+
+// slowStore - хранилище, каждая операция удаления в котором занимает latency, как запрос к БД по сети
+type slowStore struct {
+	*memory.StoreURLMap
+	latency time.Duration
+	calls   atomic.Int32
+}
+
+func (s *slowStore) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	s.calls.Add(1)
+	time.Sleep(s.latency)
+	return s.StoreURLMap.DeleteURLs(ctx, keys)
+}
+
+// unreadableJobs - хранилище заданий, чтение задания из которого всегда завершается ошибкой
+type unreadableJobs struct {
+	*memory.StoreURLMap
+	reads atomic.Int32
+}
+
+func (s *unreadableJobs) GetJob(ctx context.Context, id string) (model.DeleteJob, error) {
+	s.reads.Add(1)
+	return model.DeleteJob{}, errors.New("corrupted job")
+}
+
+func newDeleteTestService(t *testing.T, wrap func(*memory.StoreURLMap) storeURL) (*Service, *memory.StoreURLMap) {
+	t.Helper()
+
+	cfg := config.ServerConfig{DeleteQueueSize: 10, DeleteMaxAttempts: 3, DeleteRetryBackoff: time.Millisecond}
+	store, err := memory.New(&cfg, zap.NewNop(), &cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, key := range []string{"aaa", "bbb", "ccc"} {
+		require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: key, OriginalURL: "http://" + key}, 1))
+	}
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "ddd", OriginalURL: "http://ddd"}, 2))
+
+	return New(wrap(store), memory.NewStoreClick(), store, keygen.NewRandom(keygen.AlphabetUpper, 8), cfg, zap.NewNop()), store
+}
+
+// waitJobFinished - ожидание завершения задания фоновым обработчиком
+func waitJobFinished(t *testing.T, s *Service, id string) model.DeleteJob {
+	t.Helper()
+
+	var job model.DeleteJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = s.GetDeleteJob(context.Background(), id, 1)
+		require.NoError(t, err)
+		return job.Finished()
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+func TestStopDeleteWorkerDrainsQueue(t *testing.T) {
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL { return m })
+	ctx := context.Background()
+
+	// задания приняты до запуска обработчика и должны быть выполнены при остановке
+	first, err := s.DeleteURLBatch(ctx, 1, []string{"aaa"})
+	require.NoError(t, err)
+	second, err := s.DeleteURLBatch(ctx, 1, []string{"bbb", "ccc"})
+	require.NoError(t, err)
+	go s.RunDeleteWorker()
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, s.StopDeleteWorker(stopCtx))
+
+	for _, key := range []string{"aaa", "bbb", "ccc"} {
+		info, ok := store.GetURL(ctx, key)
+		require.True(t, ok)
+		assert.True(t, info.Deleted, key)
+	}
+	for _, id := range []string{first, second} {
+		job, err := s.GetDeleteJob(ctx, id, 1)
+		require.NoError(t, err)
+		assert.Equal(t, model.JobDone, job.Status)
+	}
+
+	_, err = s.DeleteURLBatch(ctx, 1, []string{"aaa"})
+	assert.ErrorIs(t, err, model.ErrShuttingDown)
+	assert.Error(t, s.CheckDeleteWorker(ctx))
+}
+
+func TestStopDeleteWorkerDeadline(t *testing.T) {
+	started := make(chan struct{}, 10)
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL {
+		return &blockingStore{StoreURLMap: m, started: started}
+	})
+	ctx := context.Background()
+
+	first, err := s.DeleteURLBatch(ctx, 1, []string{"aaa"})
+	require.NoError(t, err)
+	_, err = s.DeleteURLBatch(ctx, 1, []string{"bbb"})
+	require.NoError(t, err)
+	go s.RunDeleteWorker()
+	<-started
+
+	stopCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = s.StopDeleteWorker(stopCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "1 jobs left")
+
+	select {
+	case <-s.deleteWorkerDone:
+	case <-time.After(time.Second):
+		t.Fatal("delete worker must stop after deadline")
+	}
+	assert.Len(t, started, 0, "queued jobs must not be processed after deadline")
+
+	// прерванные задания остаются в ожидании без учета попытки и возобновляются после перезапуска
+	pending, err := store.PendingJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, first, pending[0].ID)
+	assert.Equal(t, 0, pending[0].Attempts)
+}
+
+func TestDeleteJobRetry(t *testing.T) {
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL {
+		f := &failingStore{StoreURLMap: m}
+		f.failures.Store(2)
+		return f
+	})
+	go s.RunDeleteWorker()
+
+	id, err := s.DeleteURLBatch(context.Background(), 1, []string{"aaa", "bbb", "aaa"})
+	require.NoError(t, err)
+
+	job := waitJobFinished(t, s, id)
+	assert.Equal(t, model.JobDone, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, []model.DeleteJobKey{
+		{Key: "aaa", Status: model.JobDone},
+		{Key: "bbb", Status: model.JobDone},
+	}, job.Keys)
+
+	info, ok := store.GetURL(context.Background(), "bbb")
+	require.True(t, ok)
+	assert.True(t, info.Deleted)
+}
+
+func TestDeleteJobAttemptsExhausted(t *testing.T) {
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL {
+		f := &failingStore{StoreURLMap: m}
+		f.failures.Store(10)
+		return f
+	})
+	go s.RunDeleteWorker()
+
+	id, err := s.DeleteURLBatch(context.Background(), 1, []string{"aaa"})
+	require.NoError(t, err)
+
+	job := waitJobFinished(t, s, id)
+	assert.Equal(t, model.JobFailed, job.Status)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "storage is unavailable", job.Error)
+	assert.Equal(t, []model.DeleteJobKey{{Key: "aaa", Status: model.JobFailed, Error: "storage is unavailable"}}, job.Keys)
+
+	info, ok := store.GetURL(context.Background(), "aaa")
+	require.True(t, ok)
+	assert.False(t, info.Deleted)
+}
+
+func TestDeleteJobLoadAttemptsExhausted(t *testing.T) {
+	_, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL { return m })
+	jobs := &unreadableJobs{StoreURLMap: store}
+	cfg := config.ServerConfig{DeleteQueueSize: 10, DeleteMaxAttempts: 3, DeleteRetryBackoff: time.Millisecond}
+	s := New(store, memory.NewStoreClick(), jobs, keygen.NewRandom(keygen.AlphabetUpper, 8), cfg, zap.NewNop())
+	go s.RunDeleteWorker()
+
+	id, err := s.DeleteURLBatch(context.Background(), 1, []string{"aaa"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, err := store.GetJob(context.Background(), id)
+		return err == nil && job.Status == model.JobFailed
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), jobs.reads.Load(), "job must be failed after DeleteMaxAttempts reads")
+
+	job, err := store.GetJob(context.Background(), id)
+	require.NoError(t, err)
+	assert.Contains(t, job.Error, "corrupted job")
+	assert.Equal(t, 1, job.UserID, "only status and error of stored job must be changed")
+	assert.Equal(t, []model.DeleteJobKey{{Key: "aaa", Status: model.JobPending}}, job.Keys)
+}
+
+func TestDeleteJobNotQueued(t *testing.T) {
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL { return m })
+	// очередь без места и без обработчика: постановка ждет до отмены запроса
+	s.deletes = newDeleteQueue(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.DeleteURLBatch(ctx, 1, []string{"aaa"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	pending, err := store.PendingJobs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pending, "job not queued must not stay pending")
+
+	count, err := store.DeleteFinishedJobs(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "job not queued must be failed")
+}
+
+func TestDeleteJobKeyNotFound(t *testing.T) {
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL { return m })
+	go s.RunDeleteWorker()
+	ctx := context.Background()
+
+	id, err := s.DeleteURLBatch(ctx, 1, []string{"aaa", "ddd", "zzz"})
+	require.NoError(t, err)
+
+	job := waitJobFinished(t, s, id)
+	assert.Equal(t, model.JobFailed, job.Status)
+	assert.Equal(t, []model.DeleteJobKey{
+		{Key: "aaa", Status: model.JobDone},
+		{Key: "ddd", Status: model.JobFailed, Error: "url not found"},
+		{Key: "zzz", Status: model.JobFailed, Error: "url not found"},
+	}, job.Keys)
+
+	info, ok := store.GetURL(ctx, "ddd")
+	require.True(t, ok)
+	assert.False(t, info.Deleted, "url of another user must not be deleted")
+
+	_, err = s.GetDeleteJob(ctx, id, 2)
+	assert.ErrorIs(t, err, model.ErrJobNotFound, "job of another user must not be visible")
+	_, err = s.GetDeleteJob(ctx, "unknown", 1)
+	assert.ErrorIs(t, err, model.ErrJobNotFound)
+}
+
+func TestResumeDeleteJobs(t *testing.T) {
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL { return m })
+	ctx := context.Background()
+
+	// задание сохранено до остановки сервиса, но не выполнено
+	job := newDeleteJob(1, []string{"aaa"}, "", time.Now().UTC())
+	require.NoError(t, store.SaveJob(ctx, job))
+
+	go s.RunDeleteWorker()
+	s.ResumeDeleteJobs(ctx)
+
+	done := waitJobFinished(t, s, job.ID)
+	assert.Equal(t, model.JobDone, done.Status)
+}
+
+func TestDeleteWorkerCoalescesJobs(t *testing.T) {
+	var slow *slowStore
+	s, store := newDeleteTestService(t, func(m *memory.StoreURLMap) storeURL {
+		slow = &slowStore{StoreURLMap: m}
+		return slow
+	})
+	s.cfg.DeleteBatchSize = 10
+	s.cfg.DeleteBatchWindow = 20 * time.Millisecond
+	ctx := context.Background()
+
+	// задания разных пользователей приходят в пределах окна и применяются одной операцией
+	first, err := s.DeleteURLBatch(ctx, 1, []string{"aaa", "bbb"})
+	require.NoError(t, err)
+	second, err := s.DeleteURLBatch(ctx, 2, []string{"ddd", "ccc"})
+	require.NoError(t, err)
+	go s.RunDeleteWorker()
+
+	done := waitJobFinished(t, s, first)
+	assert.Equal(t, model.JobDone, done.Status)
+	other, err := s.GetDeleteJob(ctx, second, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []model.DeleteJobKey{
+		{Key: "ddd", Status: model.JobDone},
+		{Key: "ccc", Status: model.JobFailed, Error: "url not found"},
+	}, other.Keys, "url of another user must not be deleted by coalesced batch")
+	assert.Equal(t, int32(1), slow.calls.Load())
+
+	for _, key := range []string{"aaa", "bbb", "ddd"} {
+		info, ok := store.GetURL(ctx, key)
+		require.True(t, ok)
+		assert.True(t, info.Deleted, key)
+	}
+	info, ok := store.GetURL(ctx, "ccc")
+	require.True(t, ok)
+	assert.False(t, info.Deleted)
+}
+
+// BenchmarkDeleteWorker - выполнение заданий на удаление при задержке хранилища 1ms на операцию:
+// по одному заданию на операцию и пачками заданий разных пользователей
+func BenchmarkDeleteWorker(b *testing.B) {
+	benchmarks := []struct {
+		name      string
+		workers   int
+		batchSize int
+		window    time.Duration
+	}{
+		{name: "one job per operation", workers: 1, batchSize: 1},
+		{name: "coalescing, 1 worker", workers: 1, batchSize: 100, window: time.Millisecond},
+		{name: "coalescing, 4 workers", workers: 4, batchSize: 100, window: time.Millisecond},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			cfg := config.ServerConfig{
+				DeleteQueueSize:    1000,
+				DeleteMaxAttempts:  1,
+				DeleteWorkers:      bm.workers,
+				DeleteBatchSize:    bm.batchSize,
+				DeleteBatchWindow:  bm.window,
+				DeleteRetryBackoff: time.Second,
+			}
+			store, err := memory.New(&cfg, zap.NewNop(), &cfg)
+			require.NoError(b, err)
+			ctx := context.Background()
+			for i := range 1000 {
+				key := strconv.Itoa(i)
+				require.NoError(b, store.AddURL(ctx, model.KeyOriginalURL{Key: key, OriginalURL: "http://" + key}, i%10))
+			}
+			s := New(&slowStore{StoreURLMap: store, latency: time.Millisecond}, memory.NewStoreClick(), store, keygen.NewRandom(keygen.AlphabetUpper, 8), cfg, zap.NewNop())
+			go s.RunDeleteWorker()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := strconv.Itoa(i % 1000)
+				if _, err := s.DeleteURLBatch(ctx, i%10, []string{key}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := s.StopDeleteWorker(ctx); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Second, retryDelay(time.Second, 1))
+	assert.Equal(t, 4*time.Second, retryDelay(time.Second, 3))
+	assert.Equal(t, time.Minute, retryDelay(time.Second, 100))
+}
+
+func TestDeleteQueueCloseWithBlockedPush(t *testing.T) {
+	q := newDeleteQueue(1)
+	require.NoError(t, q.push(context.Background(), "job-1"))
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- q.push(context.Background(), "job-2")
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		q.close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close must not wait for push blocked on full queue")
+	}
+	assert.ErrorIs(t, <-pushed, model.ErrShuttingDown)
+	assert.ErrorIs(t, q.push(context.Background(), "job-3"), model.ErrShuttingDown)
+
+	jobID, ok := <-q.items
+	assert.True(t, ok)
+	assert.Equal(t, "job-1", jobID, "accepted job must stay in closed queue")
+	_, ok = <-q.items
+	assert.False(t, ok)
+}
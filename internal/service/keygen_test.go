@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubKeys - генератор, выдающий ключи из списка по порядку, последний ключ повторяется
+type stubKeys struct {
+	keys     []string
+	attempts []int
+}
+
+func (g *stubKeys) Key(ctx context.Context, originalURL string, attempt int) (string, error) {
+	g.attempts = append(g.attempts, attempt)
+	return g.keys[min(len(g.attempts), len(g.keys))-1], nil
+}
+
+func TestProcessURLKeyCollision(t *testing.T) {
+	cfg := config.ServerConfig{Redirect: "http://localhost"}
+	store, err := memory.New(&cfg, zap.NewNop(), &cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "TAKEN", OriginalURL: "http://a.ru"}, 1))
+
+	t.Run("taken and reserved keys are regenerated", func(t *testing.T) {
+		keys := &stubKeys{keys: []string{"TAKEN", "API", "FREE"}}
+		s := New(store, memory.NewStoreClick(), store, keys, cfg, zap.NewNop())
+
+		shortURL, err := s.ProcessURL(ctx, model.URLToShortRequest{OriginalURL: "http://b.ru"}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost/FREE", shortURL)
+		assert.Equal(t, []int{0, 1, 2}, keys.attempts)
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		keys := &stubKeys{keys: []string{"TAKEN"}}
+		s := New(store, memory.NewStoreClick(), store, keys, cfg, zap.NewNop())
+
+		_, err := s.ProcessURL(ctx, model.URLToShortRequest{OriginalURL: "http://c.ru"}, 1)
+		assert.ErrorIs(t, err, model.ErrKeyCollision)
+		assert.Len(t, keys.attempts, keyAttempts)
+
+		results, err := s.ProcessURLBatch(ctx, []model.URLToShortBatchRequest{
+			{CorrelationID: "1", OriginalURL: "http://c.ru"},
+			{CorrelationID: "2", OriginalURL: "http://d.ru", Alias: "my-alias"},
+		}, 1)
+		require.NoError(t, err, "saved links must be returned when keys of other links are exhausted")
+		assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "1", Status: model.BatchFailed, Error: model.ErrKeyCollision.Error()}, results[0])
+		assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "2", ShortURL: "http://localhost/my-alias", Status: model.BatchCreated}, results[1])
+	})
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Размер страницы списка ссылок пользователя
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// ListURLs - страница ссылок пользователя с фильтрами и сортировкой. По умолчанию ссылки идут от новых к старым.
+// Следующая страница запрашивается с курсором из NextCursor и теми же фильтрами и сортировкой
+func (s *Service) ListURLs(ctx context.Context, userID int, request model.URLListRequest) (_ model.URLPage, err error) {
+	ctx, span := startSpan(ctx, "ListURLs", attribute.Int("shortener.user_id", userID))
+	defer func() { endSpan(span, err) }()
+
+	query, err := resolveListQuery(request, time.Now())
+	if err != nil {
+		return model.URLPage{}, err
+	}
+	limit := query.Limit
+	// лишняя ссылка показывает, что за страницей есть продолжение
+	query.Limit++
+
+	urls, err := s.storage.ListURLs(ctx, userID, query)
+	if err != nil {
+		return model.URLPage{}, err
+	}
+
+	page := model.URLPage{URLs: make([]model.UserURL, 0, min(len(urls), limit))}
+	if len(urls) > limit {
+		urls = urls[:limit]
+		page.NextCursor = encodeCursor(query, urls[limit-1])
+	}
+	for _, info := range urls {
+		page.URLs = append(page.URLs, model.UserURL{
+			ShortURL:    s.shortURL(info.Key),
+			OriginalURL: info.OriginalURL,
+			CreatedAt:   info.CreatedAt,
+			ExpiresAt:   info.ExpiresAt,
+			Deleted:     info.Deleted,
+			Redirect:    info.Redirect,
+		})
+	}
+
+	return page, nil
+}
+
+// resolveListQuery - проверка параметров запроса списка ссылок и разбор курсора
+func resolveListQuery(request model.URLListRequest, now time.Time) (model.URLListQuery, error) {
+	query := model.URLListQuery{
+		Limit:       request.Limit,
+		Search:      request.Search,
+		Deleted:     request.Deleted,
+		Expired:     request.Expired,
+		CreatedFrom: request.CreatedFrom,
+		CreatedTo:   request.CreatedTo,
+		Sort:        model.URLSortCreated,
+		Desc:        true,
+		Now:         now,
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+	if query.Limit < 0 || query.Limit > maxListLimit {
+		return model.URLListQuery{}, fmt.Errorf("%w: limit must be between 1 and %d", model.ErrInvalidListQuery, maxListLimit)
+	}
+
+	if request.Sort != "" {
+		query.Desc = strings.HasPrefix(request.Sort, "-")
+		query.Sort = strings.TrimPrefix(request.Sort, "-")
+	}
+	switch query.Sort {
+	case model.URLSortCreated, model.URLSortKey, model.URLSortURL:
+	default:
+		return model.URLListQuery{}, fmt.Errorf("%w: sort must be one of %s, %s, %s with optional - prefix",
+			model.ErrInvalidListQuery, model.URLSortCreated, model.URLSortKey, model.URLSortURL)
+	}
+
+	if !query.CreatedFrom.IsZero() && !query.CreatedTo.IsZero() && !query.CreatedFrom.Before(query.CreatedTo) {
+		return model.URLListQuery{}, fmt.Errorf("%w: created_from must be before created_to", model.ErrInvalidListQuery)
+	}
+
+	if request.Cursor != "" {
+		cursor, err := decodeCursor(request.Cursor)
+		if err != nil {
+			return model.URLListQuery{}, fmt.Errorf("%w: malformed cursor", model.ErrInvalidListQuery)
+		}
+		if cursor.Sort != query.Sort || cursor.Desc != query.Desc {
+			return model.URLListQuery{}, fmt.Errorf("%w: cursor was issued for another sort", model.ErrInvalidListQuery)
+		}
+		query.After = &cursor
+	}
+
+	return query, nil
+}
+
+// encodeCursor - курсор после ссылки info: значения поля сортировки и ключа в base64 от json
+func encodeCursor(query model.URLListQuery, info model.ShortURLInfo) string {
+	cursor := model.URLCursor{Sort: query.Sort, Desc: query.Desc, Key: info.Key}
+	switch query.Sort {
+	case model.URLSortURL:
+		cursor.OriginalURL = info.OriginalURL
+	case model.URLSortCreated:
+		cursor.CreatedAt = info.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (model.URLCursor, error) {
+	var cursor model.URLCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, err
+	}
+	if cursor.Key == "" {
+		return cursor, errors.New("cursor without key")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveListQuery(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-time.Hour).UTC()
+	byURL := encodeCursor(model.URLListQuery{Sort: model.URLSortURL}, model.ShortURLInfo{Key: "k1", OriginalURL: "http://a.ru"})
+	byCreated := encodeCursor(model.URLListQuery{Sort: model.URLSortCreated, Desc: true}, model.ShortURLInfo{Key: "k2", CreatedAt: created})
+
+	tests := []struct {
+		name    string
+		request model.URLListRequest
+		want    model.URLListQuery
+		wantErr bool
+	}{
+		{name: "defaults", want: model.URLListQuery{Limit: defaultListLimit, Sort: model.URLSortCreated, Desc: true, Now: now}},
+		{
+			name:    "sort by url with cursor",
+			request: model.URLListRequest{Limit: 10, Sort: "url", Cursor: byURL},
+			want: model.URLListQuery{Limit: 10, Sort: model.URLSortURL, Now: now,
+				After: &model.URLCursor{Sort: model.URLSortURL, Key: "k1", OriginalURL: "http://a.ru"}},
+		},
+		{
+			name:    "default sort with cursor",
+			request: model.URLListRequest{Cursor: byCreated},
+			want: model.URLListQuery{Limit: defaultListLimit, Sort: model.URLSortCreated, Desc: true, Now: now,
+				After: &model.URLCursor{Sort: model.URLSortCreated, Desc: true, Key: "k2", CreatedAt: created}},
+		},
+		{name: "limit too big", request: model.URLListRequest{Limit: maxListLimit + 1}, wantErr: true},
+		{name: "negative limit", request: model.URLListRequest{Limit: -1}, wantErr: true},
+		{name: "unknown sort", request: model.URLListRequest{Sort: "-clicks"}, wantErr: true},
+		{name: "cursor of other sort", request: model.URLListRequest{Sort: "key", Cursor: byURL}, wantErr: true},
+		{name: "malformed cursor", request: model.URLListRequest{Cursor: "not a cursor"}, wantErr: true},
+		{name: "empty created range", request: model.URLListRequest{CreatedFrom: now, CreatedTo: now}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveListQuery(tt.request, now)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, model.ErrInvalidListQuery)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
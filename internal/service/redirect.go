@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+)
+
+// utmParams - допустимые utm метки политики перехода
+var utmParams = map[string]struct{}{
+	"source":   {},
+	"medium":   {},
+	"campaign": {},
+	"term":     {},
+	"content":  {},
+}
+
+// resolveRedirect - проверка политики перехода из запроса. Политика без настроек заменяется на nil
+func resolveRedirect(redirect *model.Redirect) (*model.Redirect, error) {
+	if redirect == nil {
+		return nil, nil
+	}
+
+	switch redirect.Code {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("%w: code must be 301, 302, 307 or 308", model.ErrInvalidRedirect)
+	}
+
+	for name, value := range redirect.UTM {
+		if _, ok := utmParams[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown utm parameter %q, use source, medium, campaign, term or content", model.ErrInvalidRedirect, name)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("%w: utm parameter %q is empty", model.ErrInvalidRedirect, name)
+		}
+	}
+
+	if redirect.Code == 0 && !redirect.PassQuery && len(redirect.UTM) == 0 {
+		return nil, nil
+	}
+
+	return redirect, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRedirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		redirect *model.Redirect
+		want     *model.Redirect
+		wantErr  bool
+	}{
+		{name: "not set"},
+		{name: "empty policy", redirect: &model.Redirect{}},
+		{name: "permanent", redirect: &model.Redirect{Code: 301}, want: &model.Redirect{Code: 301}},
+		{name: "utm", redirect: &model.Redirect{UTM: map[string]string{"source": "mail"}}, want: &model.Redirect{UTM: map[string]string{"source": "mail"}}},
+		{name: "not redirect code", redirect: &model.Redirect{Code: 200}, wantErr: true},
+		{name: "unknown utm", redirect: &model.Redirect{UTM: map[string]string{"utm_source": "mail"}}, wantErr: true},
+		{name: "empty utm", redirect: &model.Redirect{UTM: map[string]string{"source": ""}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRedirect(tt.redirect)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, model.ErrInvalidRedirect)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
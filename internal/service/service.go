@@ -5,118 +5,530 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/kirillmashkov/shortener.git/internal/analytics"
 	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/keygen"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 type storeURL interface {
-	AddURL(ctx context.Context, url string, keyURL string, userID int) error
-	GetURL(ctx context.Context, keyURL string) (string, bool, bool)
-	GetAllURL(ctx context.Context, userID int) ([]model.KeyOriginalURL, error)
-	AddBatchURL(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) error
-	DeleteURLBatchProcessor(ctx context.Context)
+	AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error
+	GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool)
+	ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error)
+	AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error)
+	DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error)
+	RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error)
 	GetShortURL(ctx context.Context, originalURL string) (string, error)
+	UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error)
+	GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error)
 	GetStats(ctx context.Context) (int, int, error)
+	DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error)
+	PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+type storeJob interface {
+	SaveJob(ctx context.Context, job model.DeleteJob) error
+	GetJob(ctx context.Context, id string) (model.DeleteJob, error)
+	PendingJobs(ctx context.Context) ([]model.DeleteJob, error)
+	DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int, error)
+	FailJob(ctx context.Context, id string, reason string, at time.Time) error
+}
+
+type storeClick interface {
+	GetClickStats(ctx context.Context, key string, query model.ClickStatsQuery) (model.ClickStats, error)
 }
 
 // Service - тип для сервисного слоя по управлению ссылками
 type Service struct {
 	storage storeURL
+	clicks  storeClick
+	jobs    storeJob
+	keys    keygen.Generator
 	cfg     config.ServerConfig
 	log     *zap.Logger
+	deletes *deleteQueue
+	// deleteCtx - контекст удаления ссылок из очереди, отменяется, если очередь не успела опустеть при остановке
+	deleteCtx    context.Context
+	cancelDelete context.CancelFunc
+	// deleteWorkerRunning - запущен ли RunDeleteWorker
+	deleteWorkerRunning atomic.Bool
+	deleteWorkerDone    chan struct{}
+	// loadFailures - кол-во подряд неудачных чтений задания из хранилища по id задания
+	loadFailuresMu sync.Mutex
+	loadFailures   map[string]int
 }
 
+const defaultStatsBuckets = 30
+
+// keyAttempts - кол-во попыток сохранить ссылку со сгенерированным ключом, если ключ оказался занят
+const keyAttempts = 5
+const maxStatsBuckets = 1000
+
 // New - конструктор
-func New(storage storeURL, config config.ServerConfig, log *zap.Logger) *Service {
-	return &Service{storage: storage, cfg: config, log: log}
+func New(storage storeURL, clicks storeClick, jobs storeJob, keys keygen.Generator, config config.ServerConfig, log *zap.Logger) *Service {
+	deleteCtx, cancelDelete := context.WithCancel(context.Background())
+	return &Service{
+		storage:          storage,
+		clicks:           clicks,
+		jobs:             jobs,
+		keys:             keys,
+		cfg:              config,
+		log:              log,
+		deletes:          newDeleteQueue(config.DeleteQueueSize),
+		deleteCtx:        deleteCtx,
+		cancelDelete:     cancelDelete,
+		deleteWorkerDone: make(chan struct{}),
+		loadFailures:     make(map[string]int),
+	}
 }
 
-// GetShortURL возвращает исходную ссылку по короткому названию
-func (s *Service) GetShortURL(ctx context.Context, key string) (string, bool, bool) {
-	url, deleted, exist := s.storage.GetURL(ctx, key)
+// GetShortURL возвращает исходную ссылку по короткому названию.
+// Для отсутствующей, удаленной и просроченной ссылки возвращает
+// model.ErrURLNotFound, model.ErrURLDeleted и model.ErrURLExpired соответственно
+func (s *Service) GetShortURL(ctx context.Context, key string) (_ model.ShortURLInfo, err error) {
+	ctx, span := startSpan(ctx, "GetShortURL", attribute.String("shortener.key", key))
+	defer func() { endSpan(span, err) }()
+
+	info, exist := s.storage.GetURL(ctx, key)
 
 	if !exist {
-		return "", false, false
+		return model.ShortURLInfo{}, model.ErrURLNotFound
 	}
 
-	if deleted {
-		return "", true, true
+	if info.Deleted {
+		return model.ShortURLInfo{}, model.ErrURLDeleted
 	}
 
-	return url, false, true
+	if info.Expired(time.Now()) {
+		return model.ShortURLInfo{}, model.ErrURLExpired
+	}
+
+	return info, nil
 }
 
-// GetAllURL - возвращает все ссылки для пользователя
-func (s *Service) GetAllURL(ctx context.Context, userID int) ([]model.ShortOriginalURL, error) {
-	keyShortURL, err := s.storage.GetAllURL(ctx, userID)
+// GetURLStats - статистика переходов по короткой ссылке пользователя.
+// Для чужой или отсутствующей ссылки возвращает model.ErrURLNotFound
+func (s *Service) GetURLStats(ctx context.Context, key string, userID int, query model.ClickStatsQuery) (_ model.ClickStats, err error) {
+	ctx, span := startSpan(ctx, "GetURLStats", attribute.String("shortener.key", key), attribute.Int("shortener.user_id", userID))
+	defer func() { endSpan(span, err) }()
+
+	info, exist := s.storage.GetURL(ctx, key)
+	if !exist || info.UserID != userID {
+		return model.ClickStats{}, model.ErrURLNotFound
+	}
+
+	query, err = normalizeStatsQuery(query, time.Now())
 	if err != nil {
-		return nil, err
+		return model.ClickStats{}, err
+	}
+
+	stats, err := s.clicks.GetClickStats(ctx, key, query)
+	if err != nil {
+		return model.ClickStats{}, err
+	}
+
+	stats.Bucket = query.Bucket
+	stats.From = query.From
+	stats.To = query.To
+	return stats, nil
+}
+
+// normalizeStatsQuery - проверка параметров статистики и заполнение значений по умолчанию
+func normalizeStatsQuery(query model.ClickStatsQuery, now time.Time) (model.ClickStatsQuery, error) {
+	if query.Bucket == "" {
+		query.Bucket = analytics.BucketDay
 	}
 
-	result := make([]model.ShortOriginalURL, 0, len(keyShortURL))
-	for _, j := range keyShortURL {
-		result = append(result, model.ShortOriginalURL{Short: s.shortURL(j.Key), OriginalURL: j.OriginalURL})
+	bucket := analytics.BucketDuration(query.Bucket)
+	if bucket == 0 {
+		return query, fmt.Errorf("%w: unknown bucket %q", model.ErrInvalidStatsQuery, query.Bucket)
 	}
 
-	return result, nil
+	if query.To.IsZero() {
+		query.To = now
+	}
+
+	if query.From.IsZero() {
+		query.From = analytics.BucketStart(query.To, query.Bucket).Add(-(defaultStatsBuckets - 1) * bucket)
+	}
+
+	query.From = query.From.UTC()
+	query.To = query.To.UTC()
+
+	if !query.From.Before(query.To) {
+		return query, fmt.Errorf("%w: from must be before to", model.ErrInvalidStatsQuery)
+	}
+
+	if query.To.Sub(query.From)/bucket > maxStatsBuckets {
+		return query, fmt.Errorf("%w: too many buckets, max %d", model.ErrInvalidStatsQuery, maxStatsBuckets)
+	}
+
+	return query, nil
 }
 
-// ProcessURL - сохраняет исходную ссылку, возвращает короткую ссылку
-func (s *Service) ProcessURL(ctx context.Context, originalURL string, userID int) (string, error) {
-	keyURL := s.keyURL()
-	shortURL := s.shortURL(keyURL)
-	if err := s.storage.AddURL(ctx, originalURL, keyURL, userID); err != nil {
+// ProcessURL - сохраняет исходную ссылку, возвращает короткую ссылку.
+// Если в запросе задан alias, он используется в качестве ключа короткой ссылки,
+// иначе ключ генерируется заново, пока не найдется свободный, но не больше keyAttempts раз
+func (s *Service) ProcessURL(ctx context.Context, request model.URLToShortRequest, userID int) (_ string, err error) {
+	ctx, span := startSpan(ctx, "ProcessURL", attribute.Int("shortener.user_id", userID))
+	defer func() { endSpan(span, err) }()
+
+	keyURL, err := s.resolveKey(ctx, request.Alias, request.OriginalURL, 0)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt, err := resolveExpiry(request.ExpiresAt, request.TTL, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	redirect, err := resolveRedirect(request.Redirect)
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 1; ; attempt++ {
+		soURL := model.KeyOriginalURL{Key: keyURL, OriginalURL: request.OriginalURL, ExpiresAt: expiresAt, Redirect: redirect}
+		err = s.storage.AddURL(ctx, soURL, userID)
+		if err == nil {
+			return s.shortURL(keyURL), nil
+		}
 		if errors.Is(err, model.ErrDuplicateURL) {
-			key, errGetShortURL := s.storage.GetShortURL(ctx, originalURL)
+			key, errGetShortURL := s.storage.GetShortURL(ctx, request.OriginalURL)
 			if errGetShortURL != nil {
+				ctxlog.From(ctx, s.log).Error("Can't get short url of duplicate", zap.Error(errGetShortURL))
 				return "", errors.New("can't get short url")
 			}
 
 			return s.shortURL(key), err
 		}
+		if !errors.Is(err, model.ErrDuplicateKey) {
+			return "", err
+		}
+		if request.Alias != "" {
+			return "", model.ErrAliasTaken
+		}
+
+		ctxlog.From(ctx, s.log).Debug("Generated short key is taken", zap.String("key", keyURL), zap.Int("attempt", attempt))
+		if attempt == keyAttempts {
+			return "", model.ErrKeyCollision
+		}
+		if keyURL, err = s.generateKey(ctx, request.OriginalURL, attempt); err != nil {
+			return "", err
+		}
+	}
+}
+
+// ProcessURLBatch - сохранение массива ссылок, возвращает результат по каждой ссылке в порядке запроса:
+// короткая ссылка создана, исходная ссылка уже сокращена (с существующей короткой ссылкой), параметры ссылки некорректны
+// или не удалось подобрать свободный ключ. Ошибка возвращается только при недоступности хранилища или генератора ключей
+func (s *Service) ProcessURLBatch(ctx context.Context, originalURLs []model.URLToShortBatchRequest, userID int) (_ []model.ShortToURLBatchResponse, err error) {
+	ctx, span := startSpan(ctx, "ProcessURLBatch", attribute.Int("shortener.user_id", userID), attribute.Int("shortener.batch_size", len(originalURLs)))
+	defer func() { endSpan(span, err) }()
+
+	results := make([]model.ShortToURLBatchResponse, len(originalURLs))
+	soURLs := make([]model.KeyOriginalURL, len(originalURLs))
+	// pending - индексы ссылок, еще не сохраненных в хранилище
+	pending := make([]int, 0, len(originalURLs))
+
+	now := time.Now()
+	aliases := make(map[string]struct{})
+	for i, originalURL := range originalURLs {
+		results[i].CorrelationID = originalURL.CorrelationID
+
+		soURL, err := s.resolveBatchItem(ctx, originalURL, now)
+		if errors.Is(err, errKeyGenerator) {
+			return nil, err
+		}
+		if err != nil {
+			results[i].Status = model.BatchInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+
+		// повтор alias в массиве: сохраняется только первая ссылка
+		if originalURL.Alias != "" {
+			if _, ok := aliases[soURL.Key]; ok {
+				results[i].Status = model.BatchInvalid
+				results[i].Error = model.ErrAliasTaken.Error()
+				continue
+			}
+			aliases[soURL.Key] = struct{}{}
+		}
+
+		soURLs[i] = soURL
+		pending = append(pending, i)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		batch := make([]model.KeyOriginalURL, 0, len(pending))
+		for _, i := range pending {
+			batch = append(batch, soURLs[i])
+		}
+
+		added, err := s.storage.AddURLs(ctx, batch, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		// ссылки со сгенерированным ключом, занятым другой ссылкой, сохраняются повторно с новым ключом
+		var collided []int
+		for j, i := range pending {
+			switch {
+			case added[j].Err == nil:
+				results[i].Status = model.BatchCreated
+				results[i].ShortURL = s.shortURL(added[j].Key)
+			case errors.Is(added[j].Err, model.ErrDuplicateURL):
+				results[i].Status = model.BatchExists
+				results[i].ShortURL = s.shortURL(added[j].Key)
+			case originalURLs[i].Alias != "":
+				results[i].Status = model.BatchInvalid
+				results[i].Error = model.ErrAliasTaken.Error()
+			case attempt < keyAttempts:
+				if soURLs[i].Key, err = s.generateKey(ctx, originalURLs[i].OriginalURL, attempt); err != nil {
+					return nil, err
+				}
+				collided = append(collided, i)
+			default:
+				// ссылки предыдущих попыток уже сохранены, поэтому не сохраненной отмечается только эта ссылка
+				results[i].Status = model.BatchFailed
+				results[i].Error = model.ErrKeyCollision.Error()
+			}
+		}
+		pending = collided
+	}
+
+	return results, nil
+}
+
+// resolveBatchItem - проверка ссылки из массива, возвращает ссылку для сохранения
+func (s *Service) resolveBatchItem(ctx context.Context, request model.URLToShortBatchRequest, now time.Time) (model.KeyOriginalURL, error) {
+	if request.OriginalURL == "" {
+		return model.KeyOriginalURL{}, errors.New("original_url is required")
+	}
+
+	keyURL, err := s.resolveKey(ctx, request.Alias, request.OriginalURL, 0)
+	if err != nil {
+		return model.KeyOriginalURL{}, err
+	}
+
+	expiresAt, err := resolveExpiry(request.ExpiresAt, request.TTL, now)
+	if err != nil {
+		return model.KeyOriginalURL{}, err
+	}
+
+	redirect, err := resolveRedirect(request.Redirect)
+	if err != nil {
+		return model.KeyOriginalURL{}, err
+	}
+
+	return model.KeyOriginalURL{Key: keyURL, OriginalURL: request.OriginalURL, ExpiresAt: expiresAt, Redirect: redirect}, nil
+}
+
+// DeleteURLBatch - сохранение задания на удаление массива ссылок пользователя и постановка его в очередь.
+// Возвращает id задания. После начала остановки сервиса возвращает model.ErrShuttingDown
+func (s *Service) DeleteURLBatch(ctx context.Context, userID int, shortURLs []string) (string, error) {
+	if s.deletes.isClosed() {
+		return "", model.ErrShuttingDown
+	}
+
+	job := newDeleteJob(userID, shortURLs, ctxlog.RequestID(ctx), time.Now().UTC())
+	if err := s.jobs.SaveJob(ctx, job); err != nil {
+		return "", err
+	}
+
+	// задание уже сохранено: если очередь закрылась, оно будет выполнено после перезапуска.
+	// Иначе задание, не попавшее в очередь, помечается невыполненным, чтобы не остаться в ожидании до перезапуска
+	if err := s.deletes.push(ctx, job.ID); err != nil && !errors.Is(err, model.ErrShuttingDown) {
+		if failErr := s.jobs.FailJob(context.WithoutCancel(ctx), job.ID, "queue job: "+err.Error(), time.Now().UTC()); failErr != nil {
+			ctxlog.From(ctx, s.log).Error("Error save delete job", zap.String("job", job.ID), zap.Error(failErr))
+		}
 		return "", err
 	}
-	return shortURL, nil
+
+	ctxlog.From(ctx, s.log).Debug("Short urls queued for deletion", zap.String("job", job.ID), zap.Int("count", len(job.Keys)))
+	return job.ID, nil
 }
 
-// ProcessURLBatch - сохранение массива ссылок, возвращает ключ и короткую ссылку
-func (s *Service) ProcessURLBatch(ctx context.Context, originalURLs []model.URLToShortBatchRequest, userID int) ([]model.ShortToURLBatchResponse, error) {
-	var soURLs []model.KeyOriginalURL
-	var results []model.ShortToURLBatchResponse
+// GetDeleteJob - получение задания на удаление. Задание другого пользователя не возвращается
+func (s *Service) GetDeleteJob(ctx context.Context, id string, userID int) (_ model.DeleteJob, err error) {
+	ctx, span := startSpan(ctx, "GetDeleteJob", attribute.Int("shortener.user_id", userID))
+	defer func() { endSpan(span, err) }()
 
-	for _, originalURL := range originalURLs {
-		keyURL := s.keyURL()
-		shortURL := s.shortURL(keyURL)
-		soURLs = append(soURLs, model.KeyOriginalURL{Key: keyURL, OriginalURL: originalURL.OriginalURL})
-		results = append(results, model.ShortToURLBatchResponse{CorrelationID: originalURL.CorrelationID, ShortURL: shortURL})
+	job, err := s.jobs.GetJob(ctx, id)
+	if err != nil {
+		return model.DeleteJob{}, err
+	}
+	if job.UserID != userID {
+		return model.DeleteJob{}, model.ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+// RestoreURLs - восстановление удаленных ссылок пользователя, пока они не удалены окончательно.
+// Возвращает ключи ссылок пользователя, которые после вызова не помечены удаленными.
+// Чужие, отсутствующие и окончательно удаленные ссылки в результат не попадают
+func (s *Service) RestoreURLs(ctx context.Context, userID int, shortURLs []string) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "RestoreURLs", attribute.Int("shortener.user_id", userID), attribute.Int("shortener.batch_size", len(shortURLs)))
+	defer func() { endSpan(span, err) }()
+
+	if len(shortURLs) == 0 {
+		return []string{}, nil
 	}
 
-	err := s.storage.AddBatchURL(ctx, soURLs, userID)
+	restored, err := s.storage.RestoreURLs(ctx, shortURLs, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return results, nil
+	ctxlog.From(ctx, s.log).Debug("Short urls restored", zap.Int("requested", len(shortURLs)), zap.Int("restored", len(restored)))
+	return restored, nil
+}
+
+// ResumeDeleteJobs - постановка в очередь заданий, не выполненных до остановки сервиса
+func (s *Service) ResumeDeleteJobs(ctx context.Context) {
+	jobs, err := s.jobs.PendingJobs(ctx)
+	if err != nil {
+		s.log.Error("Error get pending delete jobs", zap.Error(err))
+		return
+	}
+
+	for _, job := range jobs {
+		if err := s.deletes.push(ctx, job.ID); err != nil {
+			s.log.Info("Resuming delete jobs interrupted", zap.Error(err))
+			return
+		}
+	}
+
+	if len(jobs) > 0 {
+		s.log.Info("Pending delete jobs resumed", zap.Int("count", len(jobs)))
+	}
+}
+
+// RunDeleteWorker - фоновое выполнение заданий на удаление, поставленных в очередь, DeleteWorkers обработчиками.
+// Каждый обработчик набирает задания разных пользователей в пачку и применяет ее одной операцией хранилища.
+// Завершается, когда очередь закрыта StopDeleteWorker и опустошена
+func (s *Service) RunDeleteWorker() {
+	s.deleteWorkerRunning.Store(true)
+	defer close(s.deleteWorkerDone)
+	defer s.deleteWorkerRunning.Store(false)
+
+	var wg sync.WaitGroup
+	var left atomic.Int64
+	for range max(s.cfg.DeleteWorkers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				batch, open := s.collectDeleteBatch()
+				if s.deleteCtx.Err() != nil {
+					left.Add(int64(len(batch)))
+				} else if len(batch) > 0 {
+					s.processDeleteJobs(s.deleteCtx, batch)
+				}
+				if !open {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if left.Load() > 0 {
+		s.log.Error("Delete queue is not drained before deadline, jobs are left pending until restart", zap.Int64("left", left.Load()))
+	}
+	s.log.Info("Delete worker stopped")
+}
+
+// StopDeleteWorker - прекращение приема заданий на удаление и ожидание выполнения уже принятых.
+// Если ctx отменен раньше, оставшиеся задания будут выполнены после перезапуска
+func (s *Service) StopDeleteWorker(ctx context.Context) error {
+	s.deletes.close()
+
+	select {
+	case <-s.deleteWorkerDone:
+		return nil
+	case <-ctx.Done():
+		left := s.deletes.len()
+		s.cancelDelete()
+		return fmt.Errorf("drain delete queue, %d jobs left: %w", left, ctx.Err())
+	}
+}
+
+// CheckDeleteWorker - проверка готовности: запущено ли фоновое удаление ссылок
+func (s *Service) CheckDeleteWorker(ctx context.Context) error {
+	if !s.deleteWorkerRunning.Load() {
+		return errors.New("delete worker is not running")
+	}
+	return nil
+}
+
+// resolveKey - проверенный alias или сгенерированный ключ для попытки attempt
+func (s *Service) resolveKey(ctx context.Context, alias string, originalURL string, attempt int) (string, error) {
+	if alias == "" {
+		return s.generateKey(ctx, originalURL, attempt)
+	}
+
+	if err := validateAlias(alias); err != nil {
+		return "", err
+	}
+
+	return alias, nil
 }
 
-// DeleteURLBatch - удаление массива ссылок для пользователя
-func (s *Service) DeleteURLBatch(userID int, shortURLs []string) {
-	shortURLUser := model.ShortURLUserID{ShortURLs: shortURLs, UserID: userID}
-	model.ShortURLchan <- shortURLUser
+// errKeyGenerator - генератор не смог выдать ключ, например, недоступна последовательность хранилища
+var errKeyGenerator = errors.New("generate short key")
+
+// generateKey - ключ от генератора для попытки attempt. Ключ, совпадающий с маршрутом сервиса,
+// считается занятым и заменяется ключом следующей попытки
+func (s *Service) generateKey(ctx context.Context, originalURL string, attempt int) (string, error) {
+	for ; attempt < keyAttempts; attempt++ {
+		key, err := s.keys.Key(ctx, originalURL, attempt)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", errKeyGenerator, err)
+		}
+		if !isReservedKey(key) {
+			return key, nil
+		}
+	}
+
+	return "", model.ErrKeyCollision
 }
 
-func (s *Service) keyURL() string {
-	const dictionary = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const keyLen = 8
+// resolveExpiry - вычисляет момент истечения ссылки по абсолютному времени или TTL в секундах
+func resolveExpiry(expiresAt *time.Time, ttl int64, now time.Time) (*time.Time, error) {
+	if expiresAt != nil && ttl != 0 {
+		return nil, fmt.Errorf("%w: set either expires_at or ttl", model.ErrInvalidExpiry)
+	}
+
+	if ttl < 0 {
+		return nil, fmt.Errorf("%w: ttl must be positive", model.ErrInvalidExpiry)
+	}
+
+	if ttl > 0 {
+		t := now.Add(time.Duration(ttl) * time.Second).UTC()
+		return &t, nil
+	}
 
-	keyURL := make([]byte, keyLen)
-	for i := range keyURL {
-		keyURL[i] = dictionary[rand.Intn(len(dictionary))]
+	if expiresAt == nil {
+		return nil, nil
 	}
-	return string(keyURL)
+
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", model.ErrInvalidExpiry)
+	}
+
+	t := expiresAt.UTC()
+	return &t, nil
 }
 
 func (s *Service) shortURL(key string) string {
@@ -125,6 +537,77 @@ func (s *Service) shortURL(key string) string {
 
 // GetStats - получения кол-ва пользователей и кол-ва коротких ссылок
 func (s *Service) GetStats(ctx context.Context) (model.Stats, error) {
+	ctx, span := startSpan(ctx, "GetStats")
 	usersCount, urlsCount, err := s.storage.GetStats(ctx)
+	endSpan(span, err)
 	return model.Stats{UrlsCount: urlsCount, UsersCount: usersCount}, err
 }
+
+// SweepExpiredURL - фоновое удаление ссылок, срок действия которых истек более ExpiredRetention назад
+func (s *Service) SweepExpiredURL(ctx context.Context) {
+	s.runPeriodic(ctx, "sweep expired urls", s.cfg.ExpiredSweepInterval, s.sweepExpiredURL)
+}
+
+// SweepDeletedURL - фоновое удаление ссылок, удаленных более DeletedRetention назад.
+// При DeletedRetention <= 0 удаленные ссылки хранятся без ограничения срока
+func (s *Service) SweepDeletedURL(ctx context.Context) {
+	if s.cfg.DeletedRetention <= 0 {
+		s.log.Info("Periodic task is disabled", zap.String("task", "sweep deleted urls"))
+		return
+	}
+	s.runPeriodic(ctx, "sweep deleted urls", s.cfg.DeletedSweepInterval, s.sweepDeletedURL)
+}
+
+// SweepDeleteJobs - фоновое удаление завершенных заданий на удаление старше DeleteJobRetention
+func (s *Service) SweepDeleteJobs(ctx context.Context) {
+	s.runPeriodic(ctx, "sweep delete jobs", s.cfg.DeleteJobSweepInterval, s.sweepDeleteJobs)
+}
+
+// runPeriodic - выполнение task каждые interval до отмены ctx. При interval <= 0 задача отключена
+func (s *Service) runPeriodic(ctx context.Context, name string, interval time.Duration, task func(ctx context.Context)) {
+	if interval <= 0 {
+		s.log.Info("Periodic task is disabled", zap.String("task", name))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("Periodic task stopped: graceful shutdown", zap.String("task", name))
+			return
+		case <-ticker.C:
+			task(ctx)
+		}
+	}
+}
+
+// sweepExpiredURL - удаление ссылок, срок действия которых истек более ExpiredRetention назад
+func (s *Service) sweepExpiredURL(ctx context.Context) {
+	ctx, span := startSpan(ctx, "SweepExpiredURL")
+	count, err := s.storage.DeleteExpiredURL(ctx, time.Now().Add(-s.cfg.ExpiredRetention))
+	span.SetAttributes(attribute.Int("shortener.deleted", count))
+	endSpan(span, err)
+	if err != nil {
+		s.log.Error("Error delete expired urls", zap.Error(err))
+		return
+	}
+	s.log.Info("Expired urls deleted", zap.Int("count", count))
+}
+
+// sweepDeletedURL - окончательное удаление ссылок, помеченных удаленными более DeletedRetention назад
+func (s *Service) sweepDeletedURL(ctx context.Context) {
+	ctx, span := startSpan(ctx, "SweepDeletedURL")
+	count, err := s.storage.PurgeDeletedURL(ctx, time.Now().Add(-s.cfg.DeletedRetention))
+	span.SetAttributes(attribute.Int("shortener.deleted", count))
+	endSpan(span, err)
+	if err != nil {
+		s.log.Error("Error purge deleted urls", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		s.log.Info("Deleted urls purged", zap.Int("count", count))
+	}
+}
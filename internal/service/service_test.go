@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"log"
 	"math/rand/v2"
 	"os"
@@ -8,9 +9,14 @@ import (
 	"runtime"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/keygen"
+	"github.com/kirillmashkov/shortener.git/internal/model"
 	"github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -27,7 +33,7 @@ func Init(log *zap.Logger, b *testing.B) (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
-	return New(Storage, ServerConf, log), nil
+	return New(Storage, memory.NewStoreClick(), Storage, keygen.NewRandom(keygen.AlphabetUpper, 8), ServerConf, log), nil
 }
 
 func changeWorkingDir(log *zap.Logger, b *testing.B) error {
@@ -55,6 +61,137 @@ func BenchmarkPostHandler(b *testing.B) {
 
 	strconv.Itoa(rand.IntN(100000))
 	for i := 0; i < b.N; i++ {
-		ServiceShort.ProcessURL(b.Context(), originalURLPrefix+strconv.Itoa(rand.IntN(100000)), 1)
+		ServiceShort.ProcessURL(b.Context(), model.URLToShortRequest{OriginalURL: originalURLPrefix + strconv.Itoa(rand.IntN(100000))}, 1)
 	}
 }
+
+func TestResolveExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		ttl       int64
+		want      *time.Time
+		wantErr   bool
+	}{
+		{name: "no expiry", want: nil},
+		{name: "ttl", ttl: 60, want: ptrTime(now.Add(time.Minute))},
+		{name: "absolute expiry", expiresAt: &future, want: &future},
+		{name: "expiry in the past", expiresAt: &past, wantErr: true},
+		{name: "negative ttl", ttl: -1, wantErr: true},
+		{name: "both ttl and expires_at", expiresAt: &future, ttl: 60, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := resolveExpiry(test.expiresAt, test.ttl, now)
+			if test.wantErr {
+				assert.ErrorIs(t, err, model.ErrInvalidExpiry)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+// collidingStore - хранилище, в котором при первом сохранении массива все сгенерированные ключи оказываются заняты
+type collidingStore struct {
+	*memory.StoreURLMap
+	calls int
+}
+
+func (s *collidingStore) AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error) {
+	s.calls++
+	if s.calls == 1 {
+		results := make([]model.AddURLResult, len(shortOriginalURL))
+		for i, soURL := range shortOriginalURL {
+			results[i] = model.AddURLResult{Key: soURL.Key, Err: model.ErrDuplicateKey}
+		}
+		return results, nil
+	}
+	return s.StoreURLMap.AddURLs(ctx, shortOriginalURL, userID)
+}
+
+func TestProcessURLBatch(t *testing.T) {
+	cfg := config.ServerConfig{Redirect: "http://localhost"}
+	store, err := memory.New(&cfg, zap.NewNop(), &cfg)
+	require.NoError(t, err)
+	s := New(store, memory.NewStoreClick(), store, keygen.NewRandom(keygen.AlphabetUpper, 8), cfg, zap.NewNop())
+	ctx := context.Background()
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "exists", OriginalURL: "http://a.ru"}, 2))
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "taken", OriginalURL: "http://t.ru"}, 2))
+
+	results, err := s.ProcessURLBatch(ctx, []model.URLToShortBatchRequest{
+		{CorrelationID: "1", OriginalURL: "http://b.ru", Alias: "my-link"},
+		{CorrelationID: "2", OriginalURL: "http://a.ru"},
+		{CorrelationID: "3", OriginalURL: "http://c.ru", Alias: "taken"},
+		{CorrelationID: "4", OriginalURL: "http://d.ru", Alias: "a"},
+		{CorrelationID: "5", OriginalURL: ""},
+		{CorrelationID: "6", OriginalURL: "http://b.ru"},
+		{CorrelationID: "7", OriginalURL: "http://e.ru"},
+		{CorrelationID: "8", OriginalURL: "http://g.ru", Alias: "my-link"},
+	}, 1)
+	require.NoError(t, err)
+	require.Len(t, results, 8)
+
+	assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "1", ShortURL: "http://localhost/my-link", Status: model.BatchCreated}, results[0])
+	assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "2", ShortURL: "http://localhost/exists", Status: model.BatchExists}, results[1])
+	assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "3", Status: model.BatchInvalid, Error: model.ErrAliasTaken.Error()}, results[2])
+	assert.Equal(t, model.BatchInvalid, results[3].Status)
+	assert.Contains(t, results[3].Error, "length must be")
+	assert.Equal(t, model.BatchInvalid, results[4].Status)
+	assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "6", ShortURL: "http://localhost/my-link", Status: model.BatchExists}, results[5])
+	assert.Equal(t, model.BatchCreated, results[6].Status)
+	assert.Equal(t, model.ShortToURLBatchResponse{CorrelationID: "8", Status: model.BatchInvalid, Error: model.ErrAliasTaken.Error()}, results[7],
+		"repeated alias in batch must be rejected")
+
+	t.Run("generated key collision", func(t *testing.T) {
+		colliding := &collidingStore{StoreURLMap: store}
+		s := New(colliding, memory.NewStoreClick(), store, keygen.NewRandom(keygen.AlphabetUpper, 8), cfg, zap.NewNop())
+
+		results, err := s.ProcessURLBatch(ctx, []model.URLToShortBatchRequest{{CorrelationID: "1", OriginalURL: "http://f.ru"}}, 1)
+		require.NoError(t, err)
+		assert.Equal(t, model.BatchCreated, results[0].Status)
+		assert.Equal(t, 2, colliding.calls)
+	})
+}
+
+func TestSweepDeletedURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		wantExist bool
+	}{
+		{name: "purge", retention: time.Nanosecond},
+		{name: "zero retention disables purging", wantExist: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.ServerConfig{DeletedRetention: tt.retention, DeletedSweepInterval: time.Millisecond}
+			store, err := memory.New(&cfg, zap.NewNop(), &cfg)
+			require.NoError(t, err)
+			s := New(store, memory.NewStoreClick(), store, keygen.NewRandom(keygen.AlphabetUpper, 8), cfg, zap.NewNop())
+			ctx := context.Background()
+
+			require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+			_, err = store.DeleteURLs(ctx, []model.UserKey{{UserID: 1, Key: "aaa"}})
+			require.NoError(t, err)
+
+			sweepCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			s.SweepDeletedURL(sweepCtx)
+
+			_, exist := store.GetURL(ctx, "aaa")
+			assert.Equal(t, tt.wantExist, exist)
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
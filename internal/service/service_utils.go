@@ -3,25 +3,28 @@ package service
 import (
 	"context"
 
-	"github.com/kirillmashkov/shortener.git/internal/storage/database"
 	"go.uber.org/zap"
 )
 
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
 // ServiceUtils - тип для утильных функций
 type ServiceUtils struct {
-	db  *database.Database
-	log *zap.Logger
+	storage pinger
+	log     *zap.Logger
 }
 
 // NewServiceUtils - конструктор
-func NewServiceUtils(db *database.Database, log *zap.Logger) *ServiceUtils {
-	return &ServiceUtils{db: db, log: log}
+func NewServiceUtils(storage pinger, log *zap.Logger) *ServiceUtils {
+	return &ServiceUtils{storage: storage, log: log}
 }
 
-// PingDB - проверка доступнгсти БД
+// PingDB - проверка доступности хранилища
 func (su *ServiceUtils) PingDB(ctx context.Context) error {
-	if err := su.db.Ping(ctx); err != nil {
-		su.log.Error("Error ping DB", zap.Error(err))
+	if err := su.storage.Ping(ctx); err != nil {
+		su.log.Error("Error ping storage", zap.Error(err))
 		return err
 	}
 
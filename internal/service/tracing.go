@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/kirillmashkov/shortener.git/internal/service")
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "service."+name, trace.WithAttributes(attrs...))
+}
+
+// endSpan - завершение span. Ожидаемые ошибки бизнес логики (ссылка не найдена, дубль и т.п.)
+// записываются атрибутом, остальные - как ошибка span
+func endSpan(span trace.Span, err error) {
+	switch {
+	case err == nil:
+	case isExpected(err):
+		span.SetAttributes(attribute.String("shortener.result", err.Error()))
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func isExpected(err error) bool {
+	for _, target := range []error{
+		model.ErrURLNotFound, model.ErrURLDeleted, model.ErrURLExpired,
+		model.ErrDuplicateURL, model.ErrAliasTaken, model.ErrInvalidAlias,
+		model.ErrInvalidExpiry, model.ErrInvalidStatsQuery,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
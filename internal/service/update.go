@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// UpdateURL - изменение исходной ссылки, политики перехода или срока действия ссылки владельцем.
+// Прежнее состояние сохраняется в историю изменений. Для чужой или отсутствующей ссылки возвращает
+// model.ErrURLNotFound, для удаленной - model.ErrURLDeleted, если новая исходная ссылка уже сокращена - model.ErrDuplicateURL
+func (s *Service) UpdateURL(ctx context.Context, key string, userID int, request model.URLUpdateRequest) (_ model.URLResponse, err error) {
+	ctx, span := startSpan(ctx, "UpdateURL", attribute.String("shortener.key", key), attribute.Int("shortener.user_id", userID))
+	defer func() { endSpan(span, err) }()
+
+	if request.OriginalURL == "" && request.Redirect == nil && request.ExpiresAt == nil && request.TTL == 0 && !request.NoExpiry {
+		return model.URLResponse{}, fmt.Errorf("%w: set url, redirect, expires_at, ttl or no_expiry", model.ErrInvalidUpdate)
+	}
+	if request.NoExpiry && (request.ExpiresAt != nil || request.TTL != 0) {
+		return model.URLResponse{}, fmt.Errorf("%w: set either no_expiry or expires_at and ttl", model.ErrInvalidUpdate)
+	}
+
+	now := time.Now()
+	change := model.URLChange{Key: key, OriginalURL: request.OriginalURL, UserID: userID, RequestID: ctxlog.RequestID(ctx), ChangedAt: now.UTC()}
+	if request.Redirect != nil {
+		change.SetRedirect = true
+		if change.Redirect, err = resolveRedirect(request.Redirect); err != nil {
+			return model.URLResponse{}, err
+		}
+	}
+	if request.NoExpiry {
+		change.SetExpiry = true
+	} else if request.ExpiresAt != nil || request.TTL != 0 {
+		change.SetExpiry = true
+		if change.ExpiresAt, err = resolveExpiry(request.ExpiresAt, request.TTL, now); err != nil {
+			return model.URLResponse{}, err
+		}
+	}
+
+	// изменяются только заданные поля: хранилище применяет их к ссылке под блокировкой,
+	// поэтому параллельные изменения разных полей не теряются
+	updated, err := s.storage.UpdateURL(ctx, change)
+	if err != nil {
+		return model.URLResponse{}, err
+	}
+
+	return model.URLResponse{
+		ShortURL:    s.shortURL(key),
+		OriginalURL: updated.OriginalURL,
+		ExpiresAt:   updated.ExpiresAt,
+		Redirect:    updated.Redirect,
+	}, nil
+}
+
+// GetURLHistory - прежние состояния ссылки пользователя в порядке изменения.
+// Для чужой или отсутствующей ссылки возвращает model.ErrURLNotFound
+func (s *Service) GetURLHistory(ctx context.Context, key string, userID int) (_ []model.URLHistory, err error) {
+	ctx, span := startSpan(ctx, "GetURLHistory", attribute.String("shortener.key", key), attribute.Int("shortener.user_id", userID))
+	defer func() { endSpan(span, err) }()
+
+	info, exist := s.storage.GetURL(ctx, key)
+	if !exist || info.UserID != userID {
+		return nil, model.ErrURLNotFound
+	}
+
+	return s.storage.GetURLHistory(ctx, key)
+}
@@ -0,0 +1,491 @@
+// Модуль boltdb - хранение ссылок во встроенной key-value БД bbolt для установок без Postgres
+package boltdb
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// Бакеты БД
+var (
+	// bucketURLs - ключ короткой ссылки -> urlRecord
+	bucketURLs = []byte("urls")
+	// bucketOriginals - исходная ссылка -> ключ короткой ссылки, для поиска дублей
+	bucketOriginals = []byte("originals")
+	// bucketUsers - вложенный бакет на пользователя: ключ короткой ссылки -> пусто
+	bucketUsers = []byte("users")
+	// bucketDeleted - ключ удаленной короткой ссылки -> время удаления в наносекундах
+	bucketDeleted = []byte("deleted")
+	// bucketClicks - вложенный бакет на короткую ссылку с переходами по ней
+	bucketClicks = []byte("clicks")
+	// bucketJobs - id задания на удаление -> model.DeleteJob
+	bucketJobs = []byte("jobs")
+	// bucketHistory - вложенный бакет на короткую ссылку: порядковый номер -> model.URLHistory
+	bucketHistory = []byte("history")
+)
+
+const openTimeout = time.Second
+
+// urlRecord - значение в бакете urls
+type urlRecord struct {
+	OriginalURL string          `json:"original_url"`
+	UserID      int             `json:"user_id"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	Redirect    *model.Redirect `json:"redirect,omitempty"`
+	CreatedAt   time.Time       `json:"created_at,omitzero"`
+}
+
+// StoreURLBolt - доступ к хранению ссылок в bbolt
+type StoreURLBolt struct {
+	db     *bolt.DB
+	logger *zap.Logger
+}
+
+// Open - открытие файла БД и создание бакетов. Если файл занят другим процессом, через секунду возвращает ошибку
+func Open(path string, logger *zap.Logger) (*StoreURLBolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		logger.Error("Can't open bolt db", zap.String("file", path), zap.Error(err))
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketURLs, bucketOriginals, bucketUsers, bucketDeleted, bucketClicks, bucketJobs, bucketHistory} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return stampDeleted(tx, time.Now())
+	})
+	if err != nil {
+		logger.Error("Can't create bolt buckets", zap.Error(err))
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return &StoreURLBolt{db: db, logger: logger}, nil
+}
+
+// stampDeleted - проставление времени удаления ссылкам, удаленным до его появления в бакете deleted.
+// Срок хранения таких ссылок отсчитывается от открытия БД
+func stampDeleted(tx *bolt.Tx, now time.Time) error {
+	deleted := tx.Bucket(bucketDeleted)
+	var legacy [][]byte
+	err := deleted.ForEach(func(key, value []byte) error {
+		if len(value) == 0 {
+			legacy = append(legacy, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range legacy {
+		if err := deleted.Put(key, deletedValue(now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deletedValue(t time.Time) []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(t.UnixNano()))
+	return value
+}
+
+func deletedTime(value []byte) *time.Time {
+	if len(value) != 8 {
+		return nil
+	}
+	t := time.Unix(0, int64(binary.BigEndian.Uint64(value))).UTC()
+	return &t
+}
+
+func userBucketName(userID int) []byte {
+	return []byte(strconv.Itoa(userID))
+}
+
+// AddURL - сохранение ссылки
+func (s *StoreURLBolt) AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error {
+	results, err := s.AddURLs(ctx, []model.KeyOriginalURL{soURL}, userID)
+	if err != nil {
+		return err
+	}
+	return results[0].Err
+}
+
+// AddURLs - сохранение массива ссылок в одной транзакции. Ссылки с дублем ключа или исходной ссылки пропускаются
+func (s *StoreURLBolt) AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error) {
+	var results []model.AddURLResult
+	now := time.Now().UTC()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		results = make([]model.AddURLResult, len(shortOriginalURL))
+		urls := tx.Bucket(bucketURLs)
+		originals := tx.Bucket(bucketOriginals)
+		user, err := tx.Bucket(bucketUsers).CreateBucketIfNotExists(userBucketName(userID))
+		if err != nil {
+			return err
+		}
+
+		for i, soURL := range shortOriginalURL {
+			if key := originals.Get([]byte(soURL.OriginalURL)); key != nil {
+				results[i] = model.AddURLResult{Key: string(key), Err: model.ErrDuplicateURL}
+				continue
+			}
+			if urls.Get([]byte(soURL.Key)) != nil {
+				results[i] = model.AddURLResult{Key: soURL.Key, Err: model.ErrDuplicateKey}
+				continue
+			}
+
+			createdAt := now
+			if !soURL.CreatedAt.IsZero() {
+				createdAt = soURL.CreatedAt.UTC()
+			}
+			data, err := json.Marshal(urlRecord{OriginalURL: soURL.OriginalURL, UserID: userID, ExpiresAt: soURL.ExpiresAt, Redirect: soURL.Redirect, CreatedAt: createdAt})
+			if err != nil {
+				return err
+			}
+			if err := urls.Put([]byte(soURL.Key), data); err != nil {
+				return err
+			}
+			if err := originals.Put([]byte(soURL.OriginalURL), []byte(soURL.Key)); err != nil {
+				return err
+			}
+			if err := user.Put([]byte(soURL.Key), nil); err != nil {
+				return err
+			}
+			results[i] = model.AddURLResult{Key: soURL.Key}
+		}
+
+		if k, _ := user.Cursor().First(); k == nil {
+			return tx.Bucket(bucketUsers).DeleteBucket(userBucketName(userID))
+		}
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error save urls to bolt db", zap.Error(err))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetURL - получение ссылки
+func (s *StoreURLBolt) GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool) {
+	info, err := s.LookupURL(ctx, keyURL)
+	if err != nil {
+		return model.ShortURLInfo{}, false
+	}
+
+	return info, true
+}
+
+// LookupURL - получение ссылки. Если ссылки нет - model.ErrURLNotFound, остальные ошибки - ошибки чтения из БД
+func (s *StoreURLBolt) LookupURL(ctx context.Context, keyURL string) (model.ShortURLInfo, error) {
+	var info model.ShortURLInfo
+	var exist bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		info, exist, err = getURL(tx, []byte(keyURL))
+		return err
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error get url from bolt db", zap.String("shortUrl", keyURL), zap.Error(err))
+		return model.ShortURLInfo{}, err
+	}
+	if !exist {
+		return model.ShortURLInfo{}, model.ErrURLNotFound
+	}
+
+	return info, nil
+}
+
+func getURL(tx *bolt.Tx, key []byte) (model.ShortURLInfo, bool, error) {
+	data := tx.Bucket(bucketURLs).Get(key)
+	if data == nil {
+		return model.ShortURLInfo{}, false, nil
+	}
+
+	var record urlRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return model.ShortURLInfo{}, false, err
+	}
+
+	deleted := tx.Bucket(bucketDeleted).Get(key)
+	return model.ShortURLInfo{
+		Key:         string(key),
+		OriginalURL: record.OriginalURL,
+		UserID:      record.UserID,
+		Deleted:     deleted != nil,
+		DeletedAt:   deletedTime(deleted),
+		ExpiresAt:   record.ExpiresAt,
+		Redirect:    record.Redirect,
+		CreatedAt:   record.CreatedAt,
+	}, true, nil
+}
+
+// ListURLs - страница ссылок пользователя. Перебираются только ссылки из бакета пользователя
+func (s *StoreURLBolt) ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error) {
+	res := make([]model.ShortURLInfo, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		user := tx.Bucket(bucketUsers).Bucket(userBucketName(userID))
+		if user == nil {
+			return nil
+		}
+
+		return user.ForEach(func(key, _ []byte) error {
+			info, exist, err := getURL(tx, key)
+			if err != nil || !exist {
+				return err
+			}
+			if query.Match(info) {
+				res = append(res, info)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error list urls from bolt db", zap.Error(err))
+		return nil, err
+	}
+
+	slices.SortFunc(res, query.Compare)
+	if query.Limit > 0 && len(res) > query.Limit {
+		res = res[:query.Limit]
+	}
+	return res, nil
+}
+
+// GetShortURL - получение ключа короткой ссылки по исходной ссылке
+func (s *StoreURLBolt) GetShortURL(ctx context.Context, originalURL string) (string, error) {
+	var key string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketOriginals).Get([]byte(originalURL))
+		if data == nil {
+			return model.ErrURLNotFound
+		}
+		key = string(data)
+		return nil
+	})
+
+	return key, err
+}
+
+// DeleteURLs - пометка удаленными ссылок разных пользователей в одной транзакции
+func (s *StoreURLBolt) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	var owned []model.UserKey
+	now := time.Now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		owned = make([]model.UserKey, 0, len(keys))
+		users := tx.Bucket(bucketUsers)
+		deleted := tx.Bucket(bucketDeleted)
+		for _, key := range keys {
+			user := users.Bucket(userBucketName(key.UserID))
+			if user == nil || user.Get([]byte(key.Key)) == nil {
+				continue
+			}
+			owned = append(owned, key)
+			if deleted.Get([]byte(key.Key)) != nil {
+				continue
+			}
+			if err := deleted.Put([]byte(key.Key), deletedValue(now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error delete urls in bolt db", zap.Error(err))
+		return nil, err
+	}
+
+	return owned, nil
+}
+
+// RestoreURLs - снятие пометки удаления со ссылок пользователя в одной транзакции. Чужие и отсутствующие ссылки пропускаются
+func (s *StoreURLBolt) RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error) {
+	var owned []string
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		owned = make([]string, 0, len(shortURL))
+		user := tx.Bucket(bucketUsers).Bucket(userBucketName(userID))
+		if user == nil {
+			return nil
+		}
+		deleted := tx.Bucket(bucketDeleted)
+		for _, key := range shortURL {
+			if user.Get([]byte(key)) == nil {
+				continue
+			}
+			if err := deleted.Delete([]byte(key)); err != nil {
+				return err
+			}
+			owned = append(owned, key)
+		}
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error restore urls in bolt db", zap.Error(err))
+		return nil, err
+	}
+
+	return owned, nil
+}
+
+// GetStats - получение кол-ва пользователей и кол-ва коротких ссылок
+func (s *StoreURLBolt) GetStats(ctx context.Context) (int, int, error) {
+	var users, urls int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		urls = tx.Bucket(bucketURLs).Stats().KeyN
+		return tx.Bucket(bucketUsers).ForEachBucket(func(_ []byte) error {
+			users++
+			return nil
+		})
+	})
+
+	return users, urls, err
+}
+
+// DeleteExpiredURL - удаление ссылок, срок действия которых истек до expiredBefore
+func (s *StoreURLBolt) DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var expired []model.ShortURLInfo
+		err := tx.Bucket(bucketURLs).ForEach(func(key, _ []byte) error {
+			info, _, err := getURL(tx, key)
+			if err != nil {
+				return err
+			}
+			if info.Expired(expiredBefore) {
+				expired = append(expired, info)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, info := range expired {
+			if err := deleteURL(tx, info); err != nil {
+				return err
+			}
+		}
+		count = len(expired)
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error delete expired urls from bolt db", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// PurgeDeletedURL - окончательное удаление ссылок, помеченных удаленными до deletedBefore
+func (s *StoreURLBolt) PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var purged []model.ShortURLInfo
+		err := tx.Bucket(bucketDeleted).ForEach(func(key, _ []byte) error {
+			info, exist, err := getURL(tx, key)
+			if err != nil {
+				return err
+			}
+			if exist && info.DeletedBefore(deletedBefore) {
+				purged = append(purged, info)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, info := range purged {
+			if err := deleteURL(tx, info); err != nil {
+				return err
+			}
+		}
+		count = len(purged)
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error purge deleted urls from bolt db", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// deleteURL - удаление ссылки, истории ее изменений и переходов по ней из всех бакетов. Пустой бакет пользователя удаляется
+func deleteURL(tx *bolt.Tx, info model.ShortURLInfo) error {
+	key := []byte(info.Key)
+	if err := tx.Bucket(bucketURLs).Delete(key); err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketDeleted).Delete(key); err != nil {
+		return err
+	}
+	for _, name := range [][]byte{bucketHistory, bucketClicks} {
+		if nested := tx.Bucket(name); nested.Bucket(key) != nil {
+			if err := nested.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	originals := tx.Bucket(bucketOriginals)
+	if string(originals.Get([]byte(info.OriginalURL))) == info.Key {
+		if err := originals.Delete([]byte(info.OriginalURL)); err != nil {
+			return err
+		}
+	}
+
+	users := tx.Bucket(bucketUsers)
+	user := users.Bucket(userBucketName(info.UserID))
+	if user == nil {
+		return nil
+	}
+	if err := user.Delete(key); err != nil {
+		return err
+	}
+	if k, _ := user.Cursor().First(); k == nil {
+		return users.DeleteBucket(userBucketName(info.UserID))
+	}
+
+	return nil
+}
+
+// NextSequence - следующий номер последовательности ключей коротких ссылок, хранится в бакете ссылок
+func (s *StoreURLBolt) NextSequence(ctx context.Context) (uint64, error) {
+	var n uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.Bucket(bucketURLs).NextSequence()
+		return err
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error get next key sequence from bolt db", zap.Error(err))
+	}
+
+	return n, err
+}
+
+// Ping - проверка доступности БД
+func (s *StoreURLBolt) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+// Close - закрытие БД
+func (s *StoreURLBolt) Close() error {
+	return s.db.Close()
+}
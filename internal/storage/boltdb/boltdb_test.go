@@ -0,0 +1,59 @@
+package boltdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"github.com/kirillmashkov/shortener.git/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestStore(t *testing.T, path string) *StoreURLBolt {
+	t.Helper()
+
+	store, err := Open(path, zap.NewNop())
+	require.NoError(t, err)
+	return store
+}
+
+func TestStoreURLBolt(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+	})
+	storagetest.RunPersistent(t, func(t *testing.T, dir string) storage.Storage {
+		return newTestStore(t, filepath.Join(dir, "shortener.db"))
+	})
+}
+
+func TestStoreURLBoltPersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shortener.db")
+
+	store := newTestStore(t, path)
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	_, err := store.DeleteURLs(ctx, []model.UserKey{{UserID: 1, Key: "aaa"}})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	reloaded := newTestStore(t, path)
+	defer func() {
+		assert.NoError(t, reloaded.Close())
+	}()
+
+	info, exist := reloaded.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	assert.True(t, info.Deleted)
+	assert.ErrorIs(t, reloaded.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://a.ru"}, 2), model.ErrDuplicateURL)
+}
+
+func TestStoreClickBolt(t *testing.T) {
+	storagetest.RunClicks(t, func(t *testing.T) (storage.Storage, storage.ClickStorage) {
+		store := newTestStore(t, filepath.Join(t.TempDir(), "shortener.db"))
+		return store, NewStoreClick(store)
+	})
+}
@@ -0,0 +1,112 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/analytics"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// clickRecord - значение в бакете переходов, время перехода хранится в ключе
+type clickRecord struct {
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// StoreClickBolt - хранение переходов по коротким ссылкам в bbolt.
+// Ключ перехода - время в наносекундах и порядковый номер, так что переходы упорядочены по времени
+type StoreClickBolt struct {
+	db     *bolt.DB
+	logger *zap.Logger
+}
+
+// NewStoreClick - конструктор, использует БД хранилища ссылок
+func NewStoreClick(store *StoreURLBolt) *StoreClickBolt {
+	return &StoreClickBolt{db: store.db, logger: store.logger}
+}
+
+func clickKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func clickTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key))).UTC()
+}
+
+// AddClicks - сохранение переходов в одной транзакции. Переходы по окончательно удаленным ссылкам не сохраняются
+func (s *StoreClickBolt) AddClicks(ctx context.Context, clicks []model.Click) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(bucketClicks)
+		urls := tx.Bucket(bucketURLs)
+		for _, click := range clicks {
+			if urls.Get([]byte(click.Key)) == nil {
+				continue
+			}
+			bucket, err := root.CreateBucketIfNotExists([]byte(click.Key))
+			if err != nil {
+				return err
+			}
+
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(clickRecord{Referrer: click.Referrer, UserAgent: click.UserAgent, IP: click.IP})
+			if err != nil {
+				return err
+			}
+
+			if err := bucket.Put(clickKey(click.Time, seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error save clicks to bolt db", zap.Error(err))
+	}
+
+	return err
+}
+
+// GetClickStats - общее кол-во переходов по ссылке и кол-во переходов по интервалам в пределах [From, To)
+func (s *StoreClickBolt) GetClickStats(ctx context.Context, key string, query model.ClickStatsQuery) (model.ClickStats, error) {
+	stats := model.ClickStats{Key: key, Series: []model.ClickBucket{}}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketClicks).Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+		stats.Total = bucket.Stats().KeyN
+
+		to := clickKey(query.To, 0)
+		c := bucket.Cursor()
+		for k, _ := c.Seek(clickKey(query.From, 0)); k != nil && bytes.Compare(k, to) < 0; k, _ = c.Next() {
+			start := analytics.BucketStart(clickTime(k), query.Bucket)
+			if n := len(stats.Series); n > 0 && stats.Series[n-1].Time.Equal(start) {
+				stats.Series[n-1].Count++
+				continue
+			}
+			stats.Series = append(stats.Series, model.ClickBucket{Time: start, Count: 1})
+		}
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error get click stats from bolt db", zap.String("key", key), zap.Error(err))
+		return model.ClickStats{}, err
+	}
+
+	return stats, nil
+}
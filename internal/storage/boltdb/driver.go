@@ -0,0 +1,28 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"go.uber.org/zap"
+)
+
+func init() {
+	storage.Register(storage.DriverBolt, open)
+}
+
+// open - драйвер bolt, требует путь к файлу БД
+func open(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (storage.Storage, storage.ClickStorage, error) {
+	if cfg.BoltPath == "" {
+		return nil, nil, errors.New("bolt db path is not configured")
+	}
+
+	store, err := Open(cfg.BoltPath, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, NewStoreClick(store), nil
+}
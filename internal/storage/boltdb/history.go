@@ -0,0 +1,114 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// UpdateURL - изменение ссылки пользователя и сохранение прежнего состояния в одной транзакции.
+// Изменение применяется к состоянию ссылки, прочитанному в той же транзакции
+func (s *StoreURLBolt) UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error) {
+	key := []byte(change.Key)
+	var updated model.ShortURLInfo
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		info, exist, err := getURL(tx, key)
+		if err != nil {
+			return err
+		}
+		if !exist || info.UserID != change.UserID {
+			return model.ErrURLNotFound
+		}
+		if info.Deleted {
+			return model.ErrURLDeleted
+		}
+		updated = change.Apply(info)
+
+		originals := tx.Bucket(bucketOriginals)
+		if other := originals.Get([]byte(updated.OriginalURL)); other != nil && string(other) != change.Key {
+			return model.ErrDuplicateURL
+		}
+
+		history, err := tx.Bucket(bucketHistory).CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		seq, err := history.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry, err := json.Marshal(model.URLHistory{
+			OriginalURL: info.OriginalURL,
+			ExpiresAt:   info.ExpiresAt,
+			Redirect:    info.Redirect,
+			RequestID:   change.RequestID,
+			ChangedAt:   change.ChangedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := history.Put(binary.BigEndian.AppendUint64(nil, seq), entry); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(urlRecord{
+			OriginalURL: updated.OriginalURL,
+			UserID:      updated.UserID,
+			ExpiresAt:   updated.ExpiresAt,
+			Redirect:    updated.Redirect,
+			CreatedAt:   updated.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketURLs).Put(key, data); err != nil {
+			return err
+		}
+
+		if string(originals.Get([]byte(info.OriginalURL))) == info.Key {
+			if err := originals.Delete([]byte(info.OriginalURL)); err != nil {
+				return err
+			}
+		}
+		return originals.Put([]byte(updated.OriginalURL), key)
+	})
+	if err != nil {
+		if !errors.Is(err, model.ErrURLNotFound) && !errors.Is(err, model.ErrURLDeleted) && !errors.Is(err, model.ErrDuplicateURL) {
+			ctxlog.From(ctx, s.logger).Error("Error update url in bolt db", zap.String("key", change.Key), zap.Error(err))
+		}
+		return model.ShortURLInfo{}, err
+	}
+
+	return updated, nil
+}
+
+// GetURLHistory - прежние состояния ссылки в порядке изменения
+func (s *StoreURLBolt) GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error) {
+	history := []model.URLHistory{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketHistory).Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, data []byte) error {
+			var entry model.URLHistory
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			history = append(history, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error get url history from bolt db", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -0,0 +1,140 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// SaveJob - сохранение состояния задания на удаление
+func (s *StoreURLBolt) SaveJob(ctx context.Context, job model.DeleteJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketJobs).Put([]byte(job.ID), data)
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error save delete job to bolt db", zap.String("job", job.ID), zap.Error(err))
+	}
+
+	return err
+}
+
+// GetJob - получение задания на удаление
+func (s *StoreURLBolt) GetJob(ctx context.Context, id string) (model.DeleteJob, error) {
+	var job model.DeleteJob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketJobs).Get([]byte(id))
+		if data == nil {
+			return model.ErrJobNotFound
+		}
+		return json.Unmarshal(data, &job)
+	})
+
+	return job, err
+}
+
+// PendingJobs - невыполненные задания в порядке создания
+func (s *StoreURLBolt) PendingJobs(ctx context.Context) ([]model.DeleteJob, error) {
+	var jobs []model.DeleteJob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketJobs).ForEach(func(_, data []byte) error {
+			var job model.DeleteJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if !job.Finished() {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error get pending delete jobs from bolt db", zap.Error(err))
+		return nil, err
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+// FailJob - пометка задания окончательно не выполненным. Поля записи меняются без разбора задания целиком,
+// чтобы сохранить остальные поля записи, которую не удается прочитать как задание
+func (s *StoreURLBolt) FailJob(ctx context.Context, id string, reason string, at time.Time) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketJobs)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return model.ErrJobNotFound
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		for name, value := range map[string]any{"status": model.JobFailed, "error": reason, "updated_at": at} {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			fields[name] = raw
+		}
+
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(id), data)
+	})
+	if err != nil && !errors.Is(err, model.ErrJobNotFound) {
+		ctxlog.From(ctx, s.logger).Error("Error fail delete job in bolt db", zap.String("job", id), zap.Error(err))
+	}
+
+	return err
+}
+
+// DeleteFinishedJobs - удаление завершенных заданий, измененных до finishedBefore
+func (s *StoreURLBolt) DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketJobs)
+		var finished [][]byte
+		err := bucket.ForEach(func(id, data []byte) error {
+			var job model.DeleteJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if job.Finished() && job.UpdatedAt.Before(finishedBefore) {
+				finished = append(finished, id)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range finished {
+			if err := bucket.Delete(id); err != nil {
+				return err
+			}
+		}
+		count = len(finished)
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, s.logger).Error("Error delete finished jobs from bolt db", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,229 @@
+// Модуль cache - кэш чтения ссылок поверх любого драйвера хранилища
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Config - размеры и время жизни записей кэша
+type Config struct {
+	// Size - максимальное кол-во найденных ссылок в кэше
+	Size int
+	// TTL - время жизни найденной ссылки, 0 - без ограничения
+	TTL time.Duration
+	// NegativeSize - максимальное кол-во ключей, для которых ссылка не найдена
+	NegativeSize int
+	// NegativeTTL - время жизни ключа, для которого ссылка не найдена
+	NegativeTTL time.Duration
+}
+
+// Stats - счетчики кэша
+type Stats struct {
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"`
+	NegativeHits int64 `json:"negative_hits"`
+	Size         int   `json:"size"`
+	MaxSize      int   `json:"max_size"`
+	NegativeSize int   `json:"negative_size"`
+	MaxNegative  int   `json:"max_negative_size"`
+}
+
+// Storage - хранилище с кэшем GetURL. Остальные методы передаются обернутому хранилищу,
+// изменяющие методы сбрасывают затронутые записи кэша. Каждый метод storage.Storage
+// реализован явно, чтобы новый изменяющий метод не обходил сброс кэша
+type Storage struct {
+	inner    storage.Storage
+	cfg      Config
+	found    *lru[model.ShortURLInfo]
+	notFound *lru[struct{}]
+	// gen - поколение кэша, увеличивается при каждом сбросе записей.
+	// Результат чтения из хранилища не кэшируется, если за время чтения был сброс
+	gen          atomic.Uint64
+	hits         atomic.Int64
+	misses       atomic.Int64
+	negativeHits atomic.Int64
+	log          *zap.Logger
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// New - конструктор
+func New(inner storage.Storage, cfg Config, log *zap.Logger) *Storage {
+	return &Storage{
+		inner:    inner,
+		cfg:      cfg,
+		found:    newLRU[model.ShortURLInfo](cfg.Size, cfg.TTL),
+		notFound: newLRU[struct{}](cfg.NegativeSize, cfg.NegativeTTL),
+		log:      log,
+	}
+}
+
+// GetURL - получение ссылки из кэша, при промахе - из хранилища с сохранением результата в кэш
+func (s *Storage) GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool) {
+	now := time.Now()
+	if info, ok := s.found.get(keyURL, now); ok {
+		s.hits.Add(1)
+		return info, true
+	}
+	if _, ok := s.notFound.get(keyURL, now); ok {
+		s.negativeHits.Add(1)
+		return model.ShortURLInfo{}, false
+	}
+
+	s.misses.Add(1)
+	gen := s.gen.Load()
+	info, err := storage.LookupURL(ctx, s.inner, keyURL)
+	switch {
+	case err == nil:
+		s.found.addIfGen(keyURL, info, now, &s.gen, gen)
+		return info, true
+	case errors.Is(err, model.ErrURLNotFound):
+		s.notFound.addIfGen(keyURL, struct{}{}, now, &s.gen, gen)
+		return model.ShortURLInfo{}, false
+	default:
+		// ошибка чтения не кэшируется, иначе существующая ссылка считалась бы отсутствующей до истечения NegativeTTL
+		return model.ShortURLInfo{}, false
+	}
+}
+
+// AddURL - сохранение ссылки, ключ убирается из кэша ненайденных
+func (s *Storage) AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error {
+	err := s.inner.AddURL(ctx, soURL, userID)
+	s.invalidate(soURL.Key)
+	return err
+}
+
+// AddURLs - сохранение массива ссылок с результатом по каждой ссылке, ключи убираются из кэша ненайденных
+func (s *Storage) AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error) {
+	results, err := s.inner.AddURLs(ctx, shortOriginalURL, userID)
+
+	keys := make([]string, 0, len(shortOriginalURL))
+	for _, soURL := range shortOriginalURL {
+		keys = append(keys, soURL.Key)
+	}
+	s.invalidate(keys...)
+
+	return results, err
+}
+
+// DeleteURLs - пометка удаленными ссылок разных пользователей и сброс их из кэша
+func (s *Storage) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	deleted, err := s.inner.DeleteURLs(ctx, keys)
+
+	invalidated := make([]string, 0, len(keys))
+	for _, key := range keys {
+		invalidated = append(invalidated, key.Key)
+	}
+	s.invalidate(invalidated...)
+
+	return deleted, err
+}
+
+// RestoreURLs - снятие пометки удаления со ссылок и сброс их из кэша
+func (s *Storage) RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error) {
+	restored, err := s.inner.RestoreURLs(ctx, shortURL, userID)
+	s.invalidate(shortURL...)
+	return restored, err
+}
+
+// UpdateURL - изменение ссылки и сброс ее из кэша
+func (s *Storage) UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error) {
+	updated, err := s.inner.UpdateURL(ctx, change)
+	s.invalidate(change.Key)
+	return updated, err
+}
+
+// DeleteExpiredURL - удаление просроченных ссылок, кэш сбрасывается целиком
+func (s *Storage) DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error) {
+	count, err := s.inner.DeleteExpiredURL(ctx, expiredBefore)
+	if count > 0 {
+		s.purge()
+	}
+	return count, err
+}
+
+// PurgeDeletedURL - окончательное удаление ссылок, помеченных удаленными, кэш сбрасывается целиком
+func (s *Storage) PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error) {
+	count, err := s.inner.PurgeDeletedURL(ctx, deletedBefore)
+	if count > 0 {
+		s.purge()
+	}
+	return count, err
+}
+
+// Compact - компактизация обернутого хранилища, кэш сбрасывается целиком
+func (s *Storage) Compact(ctx context.Context) error {
+	compactor, ok := s.inner.(storage.Compactor)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+
+	err := compactor.Compact(ctx)
+	s.purge()
+	return err
+}
+
+// ListURLs - страница ссылок пользователя из обернутого хранилища
+func (s *Storage) ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error) {
+	return s.inner.ListURLs(ctx, userID, query)
+}
+
+// GetShortURL - ключ по исходной ссылке из обернутого хранилища
+func (s *Storage) GetShortURL(ctx context.Context, originalURL string) (string, error) {
+	return s.inner.GetShortURL(ctx, originalURL)
+}
+
+// GetURLHistory - история изменений ссылки из обернутого хранилища
+func (s *Storage) GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error) {
+	return s.inner.GetURLHistory(ctx, key)
+}
+
+// GetStats - статистика обернутого хранилища
+func (s *Storage) GetStats(ctx context.Context) (int, int, error) {
+	return s.inner.GetStats(ctx)
+}
+
+// Ping - проверка доступности обернутого хранилища
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.inner.Ping(ctx)
+}
+
+// Close - закрытие обернутого хранилища
+func (s *Storage) Close() error {
+	return s.inner.Close()
+}
+
+// Stats - счетчики попаданий и промахов и текущие размеры кэша
+func (s *Storage) Stats() Stats {
+	return Stats{
+		Hits:         s.hits.Load(),
+		Misses:       s.misses.Load(),
+		NegativeHits: s.negativeHits.Load(),
+		Size:         s.found.len(),
+		MaxSize:      s.cfg.Size,
+		NegativeSize: s.notFound.len(),
+		MaxNegative:  s.cfg.NegativeSize,
+	}
+}
+
+func (s *Storage) invalidate(keys ...string) {
+	s.gen.Add(1)
+	for _, key := range keys {
+		s.found.remove(key)
+		s.notFound.remove(key)
+	}
+}
+
+func (s *Storage) purge() {
+	s.gen.Add(1)
+	s.found.purge()
+	s.notFound.purge()
+	s.log.Debug("Cache purged")
+}
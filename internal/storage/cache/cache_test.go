@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/kirillmashkov/shortener.git/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestCache(t *testing.T, cfg Config) *Storage {
+	t.Helper()
+
+	inner, err := memory.New(&config.ServerConfig{}, zap.NewNop(), &config.ServerConfig{})
+	require.NoError(t, err)
+	return New(inner, cfg, zap.NewNop())
+}
+
+func TestStorageBehavior(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestCache(t, Config{Size: 10, NegativeSize: 10, NegativeTTL: time.Minute})
+	})
+}
+
+func TestStorageHitMiss(t *testing.T) {
+	ctx := context.Background()
+	s := newTestCache(t, Config{Size: 10, NegativeSize: 10, NegativeTTL: time.Minute})
+
+	_, exist := s.GetURL(ctx, "aaa")
+	assert.False(t, exist)
+	_, exist = s.GetURL(ctx, "aaa")
+	assert.False(t, exist)
+	assert.Equal(t, Stats{Misses: 1, NegativeHits: 1, NegativeSize: 1, MaxSize: 10, MaxNegative: 10}, s.Stats())
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	_, exist = s.GetURL(ctx, "aaa")
+	assert.True(t, exist, "created link must not be hidden by negative cache")
+
+	info, exist := s.GetURL(ctx, "aaa")
+	assert.True(t, exist)
+	assert.False(t, info.Deleted)
+	assert.Equal(t, int64(1), s.Stats().Hits)
+
+	_, err := s.DeleteURLs(ctx, []model.UserKey{{UserID: 1, Key: "aaa"}})
+	require.NoError(t, err)
+	info, exist = s.GetURL(ctx, "aaa")
+	assert.True(t, exist)
+	assert.True(t, info.Deleted, "deletion must invalidate cached link")
+}
+
+// failingStorage - хранилище, чтение ссылок из которого завершается ошибкой, пока задан err
+type failingStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s *failingStorage) LookupURL(ctx context.Context, keyURL string) (model.ShortURLInfo, error) {
+	if s.err != nil {
+		return model.ShortURLInfo{}, s.err
+	}
+	return storage.LookupURL(ctx, s.Storage, keyURL)
+}
+
+func TestStorageReadErrorNotCached(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&config.ServerConfig{}, zap.NewNop(), &config.ServerConfig{})
+	require.NoError(t, err)
+	require.NoError(t, inner.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+
+	failing := &failingStorage{Storage: inner, err: errors.New("connection refused")}
+	s := New(failing, Config{Size: 10, NegativeSize: 10, NegativeTTL: time.Minute}, zap.NewNop())
+
+	_, exist := s.GetURL(ctx, "aaa")
+	assert.False(t, exist)
+	assert.Equal(t, 0, s.Stats().NegativeSize, "read error must not be cached as missing link")
+
+	failing.err = nil
+	_, exist = s.GetURL(ctx, "aaa")
+	assert.True(t, exist)
+}
+
+func TestLRUAddIfGen(t *testing.T) {
+	now := time.Now()
+	c := newLRU[int](10, 0)
+	var gen atomic.Uint64
+
+	c.addIfGen("aaa", 1, now, &gen, gen.Load())
+	_, ok := c.get("aaa", now)
+	assert.True(t, ok)
+
+	want := gen.Load()
+	gen.Add(1)
+	c.addIfGen("bbb", 2, now, &gen, want)
+	_, ok = c.get("bbb", now)
+	assert.False(t, ok, "value read before invalidation must not be cached")
+}
+
+func TestStorageEviction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestCache(t, Config{Size: 2})
+
+	_, err := s.AddURLs(ctx, []model.KeyOriginalURL{
+		{Key: "aaa", OriginalURL: "http://a.ru"},
+		{Key: "bbb", OriginalURL: "http://b.ru"},
+		{Key: "ccc", OriginalURL: "http://c.ru"},
+	}, 1)
+	require.NoError(t, err)
+
+	for _, key := range []string{"aaa", "bbb", "aaa", "ccc", "aaa", "bbb"} {
+		_, exist := s.GetURL(ctx, key)
+		require.True(t, exist)
+	}
+
+	stats := s.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, int64(2), stats.Hits, "aaa is hit twice, bbb is evicted by ccc")
+	assert.Equal(t, int64(4), stats.Misses)
+}
+
+func TestLRUTTL(t *testing.T) {
+	c := newLRU[int](10, time.Minute)
+	now := time.Now()
+
+	c.add("a", 1, now)
+	v, ok := c.get("a", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	_, ok = c.get("a", now.Add(time.Minute))
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.len())
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+// RepositoryClick - слой доступа к БД, работа с переходами по коротким ссылкам
+type RepositoryClick struct {
+	db  *Database
+	log *zap.Logger
+}
+
+// NewRepositoryClick - конструктор
+func NewRepositoryClick(db *Database, log *zap.Logger) *RepositoryClick {
+	return &RepositoryClick{db: db, log: log}
+}
+
+// AddClicks - сохранение пачки переходов одним многострочным INSERT. Переходы по окончательно удаленным ссылкам
+// пропускаются, иначе внешний ключ на shorturl отклонил бы всю пачку
+func (r *RepositoryClick) AddClicks(ctx context.Context, clicks []model.Click) error {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	keys := make([]string, 0, len(clicks))
+	times := make([]time.Time, 0, len(clicks))
+	referrers := make([]string, 0, len(clicks))
+	agents := make([]string, 0, len(clicks))
+	ips := make([]string, 0, len(clicks))
+	for _, c := range clicks {
+		keys = append(keys, c.Key)
+		times = append(times, c.Time)
+		referrers = append(referrers, c.Referrer)
+		agents = append(agents, c.UserAgent)
+		ips = append(ips, c.IP)
+	}
+
+	_, err := r.db.dbpool.Exec(ctx,
+		`insert into click (short_url, clicked_at, referrer, user_agent, ip)
+		select u.short_url, u.clicked_at, u.referrer, u.user_agent, u.ip
+		from unnest($1::varchar[], $2::timestamptz[], $3::varchar[], $4::varchar[], $5::varchar[]) as u(short_url, clicked_at, referrer, user_agent, ip)
+		join shorturl s on s.short_url = u.short_url`,
+		keys, times, referrers, agents, ips)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error insert clicks", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetClickStats - общее кол-во переходов по ссылке и кол-во переходов по интервалам в пределах [From, To)
+func (r *RepositoryClick) GetClickStats(ctx context.Context, key string, query model.ClickStatsQuery) (model.ClickStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	stats := model.ClickStats{Key: key}
+	err := r.db.dbpool.QueryRow(ctx, "select count(*) from click where short_url = $1", key).Scan(&stats.Total)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error count clicks", zap.String("key", key), zap.Error(err))
+		return model.ClickStats{}, err
+	}
+
+	rows, err := r.db.dbpool.Query(ctx,
+		`select date_trunc($2, clicked_at, 'UTC') as bucket, count(*)
+		from click
+		where short_url = $1 and clicked_at >= $3 and clicked_at < $4
+		group by bucket
+		order by bucket`,
+		key, query.Bucket, query.From, query.To)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get click series", zap.String("key", key), zap.Error(err))
+		return model.ClickStats{}, err
+	}
+	defer rows.Close()
+
+	stats.Series, err = pgx.CollectRows(rows, pgx.RowToStructByPos[model.ClickBucket])
+	if err != nil {
+		return model.ClickStats{}, err
+	}
+
+	for i := range stats.Series {
+		stats.Series[i].Time = stats.Series[i].Time.UTC()
+	}
+
+	return stats, nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -23,6 +24,8 @@ type Database struct {
 	conn   *pgx.Conn
 	dbpool *pgxpool.Pool
 	logger *zap.Logger
+	// migrationVersion - версия схемы после миграции при старте
+	migrationVersion uint
 }
 
 // New - констуктор
@@ -30,33 +33,73 @@ func New(config *config.ServerConfig, logger *zap.Logger) *Database {
 	return &Database{cfg: config, logger: logger}
 }
 
-// Ping - проверка работоспособности БД
+// Ping - проверка работоспособности БД: соединения и пула соединений
 func (d *Database) Ping(ctx context.Context) error {
-	if d.conn == nil {
+	if d.conn == nil || d.dbpool == nil {
 		return errors.New("no connection to db")
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeoutPindDB)
 	defer cancel()
 
-	return d.conn.Ping(ctx)
+	if err := d.conn.Ping(ctx); err != nil {
+		return err
+	}
+	return d.dbpool.Ping(ctx)
+}
+
+// CheckMigrations - проверка, что схема БД не откатывалась и не осталась в незавершенной миграции
+func (d *Database) CheckMigrations(ctx context.Context) error {
+	if d.dbpool == nil {
+		return errors.New("no connection to db")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeoutPindDB)
+	defer cancel()
+
+	var version int64
+	var dirty bool
+	err := d.dbpool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if uint(version) != d.migrationVersion {
+		return fmt.Errorf("schema version %d, expected %d", version, d.migrationVersion)
+	}
+	return nil
 }
 
 // Open - открытие соединения с БД
 func (d *Database) Open() error {
-	var err error
-	d.conn, err = pgx.Connect(context.Background(), d.cfg.Connection)
-	d.dbpool, _ = pgxpool.New(context.Background(), d.cfg.Connection)
+	connConfig, err := pgx.ParseConfig(d.cfg.Connection)
+	if err != nil {
+		return err
+	}
+	connConfig.Tracer = queryTracer{}
+
+	poolConfig, err := pgxpool.ParseConfig(d.cfg.Connection)
+	if err != nil {
+		return err
+	}
+	poolConfig.ConnConfig.Tracer = queryTracer{}
+
+	d.conn, err = pgx.ConnectConfig(context.Background(), connConfig)
+	d.dbpool, _ = pgxpool.NewWithConfig(context.Background(), poolConfig)
 	return err
 }
 
 // Close - закрытие соединения с БД
 func (d *Database) Close() error {
+	if d.dbpool != nil {
+		pools.set(nil)
+		d.dbpool.Close()
+	}
 	if d.conn == nil {
 		return nil
 	}
-	err := d.conn.Close(context.Background())
-	d.dbpool.Close()
-	return err
+	return d.conn.Close(context.Background())
 }
 
 // Migrate - миграция
@@ -68,11 +111,16 @@ func (d *Database) Migrate() error {
 	}
 
 	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			d.logger.Info("No migrations need")
-			return nil
+		if !errors.Is(err, migrate.ErrNoChange) {
+			d.logger.Error("Something went wrong while migrations", zap.Error(err))
+			return err
 		}
-		d.logger.Error("Something went wrong while migrations", zap.Error(err))
+		d.logger.Info("No migrations need")
+	}
+
+	d.migrationVersion, _, err = m.Version()
+	if err != nil {
+		d.logger.Error("Can't get migration version", zap.Error(err))
 		return err
 	}
 	return nil
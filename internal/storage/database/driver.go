@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"go.uber.org/zap"
+)
+
+func init() {
+	storage.Register(storage.DriverPostgres, open)
+}
+
+// open - драйвер postgres: подключение к БД и миграция
+func open(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (storage.Storage, storage.ClickStorage, error) {
+	if cfg.Connection == "" {
+		return nil, nil, errors.New("db connection string is not configured")
+	}
+
+	db := New(cfg, log)
+	if err := db.Open(); err != nil {
+		log.Error("Error open connection db", zap.Error(err))
+		return nil, nil, errors.Join(err, db.Close())
+	}
+
+	if err := db.Migrate(); err != nil {
+		return nil, nil, errors.Join(err, db.Close())
+	}
+
+	pools.set(db.dbpool)
+	return NewRepositoryShortURL(db, log), NewRepositoryClick(db, log), nil
+}
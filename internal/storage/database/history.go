@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+// UpdateURL - изменение ссылки пользователя. Строка ссылки блокируется до конца транзакции, изменение применяется
+// к прочитанному под блокировкой состоянию, прежнее состояние копируется в shorturl_history в той же транзакции
+func (r *RepositoryShortURL) UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	var updated model.ShortURLInfo
+	err := pgx.BeginFunc(ctx, r.db.dbpool, func(tx pgx.Tx) error {
+		info := model.ShortURLInfo{Key: change.Key}
+		err := tx.QueryRow(ctx,
+			"select original_url, user_id, deleted, expires_at, redirect, created_at from shorturl where short_url = $1 for update",
+			change.Key).
+			Scan(&info.OriginalURL, &info.UserID, &info.Deleted, &info.ExpiresAt, &info.Redirect, &info.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) || err == nil && info.UserID != change.UserID {
+			return model.ErrURLNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if info.Deleted {
+			return model.ErrURLDeleted
+		}
+
+		_, err = tx.Exec(ctx,
+			`insert into shorturl_history (short_url, original_url, redirect, expires_at, request_id, changed_at)
+			values ($1, $2, $3, $4, $5, $6)`,
+			change.Key, info.OriginalURL, info.Redirect, info.ExpiresAt, change.RequestID, change.ChangedAt)
+		if err != nil {
+			return err
+		}
+
+		updated = change.Apply(info)
+		_, err = tx.Exec(ctx, "update shorturl set original_url = $2, redirect = $3, expires_at = $4 where short_url = $1",
+			change.Key, updated.OriginalURL, updated.Redirect, updated.ExpiresAt)
+		return uniqueViolationToErr(err)
+	})
+	if err != nil {
+		if !errors.Is(err, model.ErrURLNotFound) && !errors.Is(err, model.ErrURLDeleted) && !errors.Is(err, model.ErrDuplicateURL) {
+			ctxlog.From(ctx, r.log).Error("Error update short url", zap.String("key", change.Key), zap.Error(err))
+		}
+		return model.ShortURLInfo{}, err
+	}
+
+	return updated, nil
+}
+
+// GetURLHistory - прежние состояния ссылки в порядке изменения
+func (r *RepositoryShortURL) GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	rows, err := r.db.dbpool.Query(ctx,
+		"select original_url, expires_at, redirect, request_id, changed_at from shorturl_history where short_url = $1 order by id", key)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get url history", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	history, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.URLHistory])
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get url history", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	return history, nil
+}
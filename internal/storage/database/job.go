@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+const selectJob = "select id, user_id, status, keys, attempts, error, request_id, created_at, updated_at from delete_job"
+
+// SaveJob - сохранение состояния задания на удаление
+func (r *RepositoryShortURL) SaveJob(ctx context.Context, job model.DeleteJob) error {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	keys, err := json.Marshal(job.Keys)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.dbpool.Exec(ctx,
+		`insert into delete_job (id, user_id, status, keys, attempts, error, request_id, created_at, updated_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		on conflict (id) do update set status = excluded.status, keys = excluded.keys, attempts = excluded.attempts,
+			error = excluded.error, updated_at = excluded.updated_at`,
+		job.ID, job.UserID, job.Status, keys, job.Attempts, job.Error, job.RequestID, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error save delete job", zap.String("job", job.ID), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// GetJob - получение задания на удаление
+func (r *RepositoryShortURL) GetJob(ctx context.Context, id string) (model.DeleteJob, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	job, err := scanJob(r.db.dbpool.QueryRow(ctx, selectJob+" where id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.DeleteJob{}, model.ErrJobNotFound
+	}
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get delete job", zap.String("job", id), zap.Error(err))
+		return model.DeleteJob{}, err
+	}
+
+	return job, nil
+}
+
+// PendingJobs - невыполненные задания в порядке создания
+func (r *RepositoryShortURL) PendingJobs(ctx context.Context) ([]model.DeleteJob, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	rows, err := r.db.dbpool.Query(ctx, selectJob+" where status = $1 order by created_at", model.JobPending)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get pending delete jobs", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []model.DeleteJob
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// DeleteFinishedJobs - удаление завершенных заданий, измененных до finishedBefore
+func (r *RepositoryShortURL) DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	tag, err := r.db.dbpool.Exec(ctx, "delete from delete_job where status <> $1 and updated_at < $2", model.JobPending, finishedBefore)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error delete finished jobs", zap.Error(err))
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// FailJob - пометка задания окончательно не выполненным, остальные поля задания не меняются
+func (r *RepositoryShortURL) FailJob(ctx context.Context, id string, reason string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	tag, err := r.db.dbpool.Exec(ctx, "update delete_job set status = $2, error = $3, updated_at = $4 where id = $1",
+		id, model.JobFailed, reason, at)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error fail delete job", zap.String("job", id), zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrJobNotFound
+	}
+
+	return nil
+}
+
+func scanJob(row pgx.Row) (model.DeleteJob, error) {
+	var job model.DeleteJob
+	var keys []byte
+	err := row.Scan(&job.ID, &job.UserID, &job.Status, &keys, &job.Attempts, &job.Error, &job.RequestID, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return model.DeleteJob{}, err
+	}
+
+	if err := json.Unmarshal(keys, &job.Keys); err != nil {
+		return model.DeleteJob{}, err
+	}
+
+	return job, nil
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+// listSortColumns - колонки сортировки списка ссылок пользователя
+var listSortColumns = map[string]string{
+	model.URLSortCreated: "created_at",
+	model.URLSortKey:     "short_url",
+	model.URLSortURL:     "original_url",
+}
+
+// likeEscaper - экранирование спецсимволов шаблона like
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListURLs - страница ссылок пользователя. Позиция страницы задается условием по полю сортировки и ключу,
+// так что выборка идет по индексу без пропуска строк предыдущих страниц
+func (r *RepositoryShortURL) ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
+
+	sql, args := listURLsQuery(userID, query)
+	rows, err := r.db.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error list urls from db", zap.Error(err))
+		return nil, err
+	}
+
+	urls, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.ShortURLInfo, error) {
+		info := model.ShortURLInfo{UserID: userID}
+		err := row.Scan(&info.Key, &info.OriginalURL, &info.Deleted, &info.DeletedAt, &info.ExpiresAt, &info.Redirect, &info.CreatedAt)
+		return info, err
+	})
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error list urls from db", zap.Error(err))
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// listURLsQuery - текст запроса страницы ссылок и его параметры
+func listURLsQuery(userID int, query model.URLListQuery) (string, []any) {
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"user_id = $1"}
+	if query.Deleted != nil {
+		where = append(where, "deleted = "+arg(*query.Deleted))
+	}
+	if query.Expired != nil {
+		if *query.Expired {
+			where = append(where, "expires_at <= "+arg(query.Now))
+		} else {
+			where = append(where, "(expires_at is null or expires_at > "+arg(query.Now)+")")
+		}
+	}
+	if !query.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= "+arg(query.CreatedFrom))
+	}
+	if !query.CreatedTo.IsZero() {
+		where = append(where, "created_at < "+arg(query.CreatedTo))
+	}
+	if query.Search != "" {
+		where = append(where, "original_url ilike "+arg("%"+likeEscaper.Replace(query.Search)+"%"))
+	}
+
+	column, ok := listSortColumns[query.Sort]
+	if !ok {
+		column = listSortColumns[model.URLSortCreated]
+	}
+	direction, compare := "asc", ">"
+	if query.Desc {
+		direction, compare = "desc", "<"
+	}
+
+	orderBy := "short_url " + direction
+	if column != "short_url" {
+		orderBy = column + " " + direction + ", " + orderBy
+	}
+
+	if after := query.After; after != nil {
+		switch column {
+		case "short_url":
+			where = append(where, "short_url "+compare+" "+arg(after.Key))
+		case "original_url":
+			where = append(where, "(original_url, short_url) "+compare+" ("+arg(after.OriginalURL)+", "+arg(after.Key)+")")
+		default:
+			where = append(where, "(created_at, short_url) "+compare+" ("+arg(after.CreatedAt)+", "+arg(after.Key)+")")
+		}
+	}
+
+	sql := "select short_url, original_url, deleted, deleted_at, expires_at, redirect, created_at from shorturl where " +
+		strings.Join(where, " and ") + " order by " + orderBy
+	if query.Limit > 0 {
+		sql += " limit " + arg(query.Limit)
+	}
+
+	return sql, args
+}
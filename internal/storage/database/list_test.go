@@ -0,0 +1,33 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListURLsQuery(t *testing.T) {
+	now := time.Now()
+	deleted := false
+
+	sql, args := listURLsQuery(1, model.URLListQuery{
+		Limit:   11,
+		Sort:    model.URLSortURL,
+		Desc:    true,
+		Deleted: &deleted,
+		Search:  "50%_off",
+		After:   &model.URLCursor{Key: "k1", OriginalURL: "http://a.ru"},
+		Now:     now,
+	})
+	assert.Equal(t, "select short_url, original_url, deleted, deleted_at, expires_at, redirect, created_at from shorturl "+
+		"where user_id = $1 and deleted = $2 and original_url ilike $3 and (original_url, short_url) < ($4, $5) "+
+		"order by original_url desc, short_url desc limit $6", sql)
+	assert.Equal(t, []any{1, false, `%50\%\_off%`, "http://a.ru", "k1", 11}, args)
+
+	sql, args = listURLsQuery(2, model.URLListQuery{Sort: model.URLSortKey, After: &model.URLCursor{Key: "k1"}})
+	assert.Equal(t, "select short_url, original_url, deleted, deleted_at, expires_at, redirect, created_at from shorturl "+
+		"where user_id = $1 and short_url > $2 order by short_url asc", sql)
+	assert.Equal(t, []any{2, "k1"}, args)
+}
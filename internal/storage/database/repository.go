@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"github.com/kirillmashkov/shortener.git/internal/model"
 	"go.uber.org/zap"
 )
@@ -21,142 +23,245 @@ type RepositoryShortURL struct {
 
 const timeoutOperationDB = 1 * time.Second
 
+// timeoutBatchDB - таймаут операций с большими массивами ссылок
+const timeoutBatchDB = 30 * time.Second
+
+const shortURLUniqueConstraint = "short_url_unique"
+
 // NewRepositoryShortURL - конструктор
 func NewRepositoryShortURL(db *Database, log *zap.Logger) *RepositoryShortURL {
 	return &RepositoryShortURL{db: db, log: log}
 }
 
 // AddURL - сохранение ссылки
-func (r *RepositoryShortURL) AddURL(ctx context.Context, url string, keyURL string, userID int) error {
+func (r *RepositoryShortURL) AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error {
 	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
 	defer cancel()
 
 	tx, err := r.db.dbpool.Begin(ctx)
 	if err != nil {
-		r.log.Error("Error open tran", zap.Error(err))
+		ctxlog.From(ctx, r.log).Error("Error open tran", zap.Error(err))
 		return err
 	}
 	defer func() {
 		if err == nil {
 			if errCommit := tx.Commit(ctx); errCommit != nil {
-				r.log.Error("Error commit tran", zap.Error(err))
+				ctxlog.From(ctx, r.log).Error("Error commit tran", zap.Error(err))
 			}
 		} else {
 			if errRollback := tx.Rollback(ctx); errRollback != nil {
-				r.log.Error("Error rollback tx", zap.Error(errRollback))
+				ctxlog.From(ctx, r.log).Error("Error rollback tx", zap.Error(errRollback))
 			}
 		}
 	}()
 
-	err = r.insertShortURL(ctx, tx, keyURL, url, userID)
+	err = r.insertShortURL(ctx, tx, soURL, userID)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == pgerrcode.UniqueViolation {
-				return model.ErrDuplicateURL
-			}
-		}
-
-		return err
+		return uniqueViolationToErr(err)
 	}
 
 	return nil
 }
 
-// AddBatchURL - сохранение множества ссылок
-func (r *RepositoryShortURL) AddBatchURL(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) error {
-	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+// AddURLs - сохранение массива ссылок одним многострочным INSERT ... ON CONFLICT DO NOTHING.
+// Сохраненные ссылки определяются по id строки, а не по ключу, так как ключ может повторяться в массиве.
+// Для пропущенных ссылок ключ существующей ссылки ищется вторым запросом по исходным ссылкам
+func (r *RepositoryShortURL) AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutBatchDB)
 	defer cancel()
 
-	tx, err := r.db.dbpool.Begin(ctx)
+	ids := make([]string, 0, len(shortOriginalURL))
+	keys := make([]string, 0, len(shortOriginalURL))
+	originals := make([]string, 0, len(shortOriginalURL))
+	expires := make([]*time.Time, 0, len(shortOriginalURL))
+	redirects := make([]*string, 0, len(shortOriginalURL))
+	createdAts := make([]*time.Time, 0, len(shortOriginalURL))
+	for _, soURL := range shortOriginalURL {
+		ids = append(ids, uuid.NewString())
+		keys = append(keys, soURL.Key)
+		originals = append(originals, soURL.OriginalURL)
+		expires = append(expires, soURL.ExpiresAt)
+		createdAts = append(createdAts, createdAt(soURL))
+
+		redirect, err := redirectJSON(soURL.Redirect)
+		if err != nil {
+			return nil, err
+		}
+		redirects = append(redirects, redirect)
+	}
+
+	rows, err := r.db.dbpool.Query(ctx,
+		`insert into shorturl (id, short_url, original_url, user_id, expires_at, redirect, created_at)
+		select u.id, u.short_url, u.original_url, $4::bigint, u.expires_at, u.redirect::jsonb, coalesce(u.created_at, now())
+		from unnest($1::uuid[], $2::varchar[], $3::varchar[], $5::timestamptz[], $6::text[], $7::timestamptz[]) with ordinality as u(id, short_url, original_url, expires_at, redirect, created_at, n)
+		order by u.n
+		on conflict do nothing
+		returning id::text`,
+		ids, keys, originals, userID, expires, redirects, createdAts)
 	if err != nil {
-		r.log.Error("Error open tran", zap.Error(err))
-		return err
+		ctxlog.From(ctx, r.log).Error("Error insert short urls", zap.Int("count", len(shortOriginalURL)), zap.Error(err))
+		return nil, err
 	}
-	defer func() {
-		if err == nil {
-			if errCommit := tx.Commit(ctx); errCommit != nil {
-				r.log.Error("Error commit tran", zap.Error(err))
-			}
-		} else {
-			if errRollback := tx.Rollback(ctx); errRollback != nil {
-				r.log.Error("Error rollback tx", zap.Error(errRollback))
-			}
+	inserted, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error insert short urls", zap.Int("count", len(shortOriginalURL)), zap.Error(err))
+		return nil, err
+	}
+
+	created := make(map[string]struct{}, len(inserted))
+	for _, id := range inserted {
+		created[id] = struct{}{}
+	}
+
+	results := make([]model.AddURLResult, len(shortOriginalURL))
+	var skipped []string
+	for i, soURL := range shortOriginalURL {
+		if _, ok := created[ids[i]]; ok {
+			results[i] = model.AddURLResult{Key: soURL.Key}
+			continue
 		}
-	}()
+		skipped = append(skipped, soURL.OriginalURL)
+	}
+	if len(skipped) == 0 {
+		return results, nil
+	}
 
-	for _, soURL := range shortOriginalURL {
-		err = r.insertShortURL(ctx, tx, soURL.Key, soURL.OriginalURL, userID)
-		if err != nil {
-			return err
+	rows, err = r.db.dbpool.Query(ctx, "select original_url, short_url from shorturl where original_url = any($1)", skipped)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get existing short urls", zap.Error(err))
+		return nil, err
+	}
+	existing := make(map[string]string, len(skipped))
+	var original, key string
+	_, err = pgx.ForEachRow(rows, []any{&original, &key}, func() error {
+		existing[original] = key
+		return nil
+	})
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get existing short urls", zap.Error(err))
+		return nil, err
+	}
+
+	for i, soURL := range shortOriginalURL {
+		if results[i].Key != "" {
+			continue
+		}
+		if key, ok := existing[soURL.OriginalURL]; ok {
+			results[i] = model.AddURLResult{Key: key, Err: model.ErrDuplicateURL}
+			continue
 		}
+		results[i] = model.AddURLResult{Key: soURL.Key, Err: model.ErrDuplicateKey}
 	}
 
-	return nil
+	return results, nil
 }
 
-func (r *RepositoryShortURL) deleteURLBatch(shortURL []string, userID int) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeoutOperationDB)
+// redirectJSON - политика перехода в виде текста json для массива параметров, nil - NULL
+func redirectJSON(redirect *model.Redirect) (*string, error) {
+	if redirect == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(redirect)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	return &text, nil
+}
+
+// uniqueViolationToErr - приводит нарушение уникальности к ошибке модели в зависимости от ограничения
+func uniqueViolationToErr(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgerrcode.UniqueViolation {
+		return err
+	}
+
+	if pgErr.ConstraintName == shortURLUniqueConstraint {
+		return model.ErrDuplicateKey
+	}
+
+	return model.ErrDuplicateURL
+}
+
+// DeleteURLs - пометка удаленными ссылок разных пользователей одним запросом по массивам ключей и пользователей
+func (r *RepositoryShortURL) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	// пачка объединяет задания многих пользователей, поэтому таймаут как у пакетного добавления
+	ctx, cancel := context.WithTimeout(ctx, timeoutBatchDB)
 	defer cancel()
 
-	tx, err := r.db.dbpool.Begin(ctx)
+	shortURLs := make([]string, 0, len(keys))
+	userIDs := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		shortURLs = append(shortURLs, key.Key)
+		userIDs = append(userIDs, int64(key.UserID))
+	}
+
+	rows, err := r.db.dbpool.Query(ctx,
+		`update shorturl s set deleted = true, deleted_at = coalesce(s.deleted_at, now())
+		from unnest($1::varchar[], $2::bigint[]) as d(short_url, user_id)
+		where s.short_url = d.short_url and s.user_id = d.user_id
+		returning s.short_url, s.user_id`,
+		shortURLs, userIDs)
 	if err != nil {
-		r.log.Error("Error open tran", zap.Error(err))
-		return
+		ctxlog.From(ctx, r.log).Error("Error delete short urls", zap.Int("count", len(keys)), zap.Error(err))
+		return nil, err
 	}
-	defer func() {
-		if err == nil {
-			if errCommit := tx.Commit(ctx); errCommit != nil {
-				r.log.Error("Error commit tran", zap.Error(err))
-			}
-		} else {
-			if errRollback := tx.Rollback(ctx); errRollback != nil {
-				r.log.Error("Error rollback tx", zap.Error(errRollback))
-			}
-		}
-	}()
+	defer rows.Close()
 
-	batch := &pgx.Batch{}
-	for _, data := range shortURL {
-		r.log.Info("Set deleted = true", zap.String("short_url", data), zap.Int("userID", userID))
-		batch.Queue("update shorturl set deleted = true where short_url = $1 and user_id = $2", data, userID)
+	owned := make([]model.UserKey, 0, len(keys))
+	for rows.Next() {
+		var key model.UserKey
+		if err := rows.Scan(&key.Key, &key.UserID); err != nil {
+			return nil, err
+		}
+		owned = append(owned, key)
+	}
+	if err := rows.Err(); err != nil {
+		ctxlog.From(ctx, r.log).Error("Error delete short urls", zap.Int("count", len(keys)), zap.Error(err))
+		return nil, err
 	}
 
-	res := tx.SendBatch(ctx, batch)
+	return owned, nil
+}
+
+// RestoreURLs - снятие пометки удаления со ссылок пользователя одним запросом
+func (r *RepositoryShortURL) RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
 
-	errClose := res.Close()
-	if errClose != nil {
-		r.log.Error("Error close batch delete short url", zap.Error(err))
+	rows, err := r.db.dbpool.Query(ctx,
+		"update shorturl set deleted = false, deleted_at = null where short_url = any($1) and user_id = $2 returning short_url",
+		shortURL, userID)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error restore short urls", zap.Int("count", len(shortURL)), zap.Error(err))
+		return nil, err
+	}
+
+	owned, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error restore short urls", zap.Int("count", len(shortURL)), zap.Error(err))
+		return nil, err
 	}
+
+	return owned, nil
 }
 
-// DeleteURLBatchProcessor - удаление множества ссылок
-func (r *RepositoryShortURL) DeleteURLBatchProcessor(ctx context.Context) {
-	defer model.Wg.Done()
-	for {
-		select {
-		case <-ctx.Done():
-			r.log.Info("DeleteURLBatchProcessor: graceful shutdown")
-			return
-		case s, ok := <-model.ShortURLchan:
-			if !ok {
-				// Канал закрыт, ждём только завершения контекста
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
-			if s.ShortURLs != nil {
-				r.deleteURLBatch(s.ShortURLs, s.UserID)
-			}
-		}
+// createdAt - время создания ссылки для запроса. nil - время сохранения в базе
+func createdAt(soURL model.KeyOriginalURL) *time.Time {
+	if soURL.CreatedAt.IsZero() {
+		return nil
 	}
+	return &soURL.CreatedAt
 }
 
-func (r *RepositoryShortURL) insertShortURL(ctx context.Context, tx pgx.Tx, keyURL string, url string, userID int) error {
-	_, err := tx.Exec(ctx, "insert into shorturl (id, short_url, original_url, user_id) values ($1, $2, $3, $4)", uuid.NewString(), keyURL, url, userID)
+func (r *RepositoryShortURL) insertShortURL(ctx context.Context, tx pgx.Tx, soURL model.KeyOriginalURL, userID int) error {
+	_, err := tx.Exec(ctx, "insert into shorturl (id, short_url, original_url, user_id, expires_at, redirect, created_at) values ($1, $2, $3, $4, $5, $6, coalesce($7::timestamptz, now()))",
+		uuid.NewString(), soURL.Key, soURL.OriginalURL, userID, soURL.ExpiresAt, soURL.Redirect, createdAt(soURL))
 	if err != nil {
-		r.log.Error("Error insert short url ",
-			zap.String("key", keyURL),
-			zap.String("original url", url),
+		ctxlog.From(ctx, r.log).Error("Error insert short url ",
+			zap.String("key", soURL.Key),
+			zap.String("original url", soURL.OriginalURL),
 			zap.Error(err))
 		return err
 	}
@@ -165,19 +270,32 @@ func (r *RepositoryShortURL) insertShortURL(ctx context.Context, tx pgx.Tx, keyU
 }
 
 // GetURL - получение исходной ссылки
-func (r *RepositoryShortURL) GetURL(ctx context.Context, keyURL string) (string, bool, bool) {
+func (r *RepositoryShortURL) GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool) {
+	info, err := r.LookupURL(ctx, keyURL)
+	if err != nil {
+		return model.ShortURLInfo{}, false
+	}
+
+	return info, true
+}
+
+// LookupURL - получение ссылки. Если ссылки нет - model.ErrURLNotFound, остальные ошибки - ошибки чтения из БД
+func (r *RepositoryShortURL) LookupURL(ctx context.Context, keyURL string) (model.ShortURLInfo, error) {
 	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
-	var originalURL string
-	var deleted bool
-	err := r.db.dbpool.QueryRow(ctx, "select original_url, deleted from shorturl where short_url = $1", keyURL).Scan(&originalURL, &deleted)
+	info := model.ShortURLInfo{Key: keyURL}
+	err := r.db.dbpool.QueryRow(ctx, "select original_url, user_id, deleted, deleted_at, expires_at, redirect, created_at from shorturl where short_url = $1", keyURL).
+		Scan(&info.OriginalURL, &info.UserID, &info.Deleted, &info.DeletedAt, &info.ExpiresAt, &info.Redirect, &info.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.ShortURLInfo{}, model.ErrURLNotFound
+	}
 	if err != nil {
-		r.log.Error("Error get originalUrl from db", zap.String("shortUrl", keyURL), zap.Error(err))
-		return "", false, false
+		ctxlog.From(ctx, r.log).Error("Error get originalUrl from db", zap.String("shortUrl", keyURL), zap.Error(err))
+		return model.ShortURLInfo{}, err
 	}
 
-	return originalURL, deleted, true
+	return info, nil
 }
 
 // GetShortURL - получение короткой ссылки
@@ -187,6 +305,9 @@ func (r *RepositoryShortURL) GetShortURL(ctx context.Context, originalURL string
 
 	var key string
 	err := r.db.dbpool.QueryRow(ctx, "select short_url from shorturl where original_url = $1", originalURL).Scan(&key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", model.ErrURLNotFound
+	}
 	if err != nil {
 		return "", err
 	}
@@ -194,33 +315,69 @@ func (r *RepositoryShortURL) GetShortURL(ctx context.Context, originalURL string
 	return key, nil
 }
 
-// GetAllURL - получение всех ссылок в рамках пользователя
-func (r *RepositoryShortURL) GetAllURL(ctx context.Context, userID int) ([]model.KeyOriginalURL, error) {
+// GetStats - получение их БД кол-ва коротких ссылок и кол-ва пользователей из БД
+func (r *RepositoryShortURL) GetStats(ctx context.Context) (int, int, error) {
+	var urlsCount int
+	var usersCount int
+
+	err := r.db.dbpool.QueryRow(ctx, "select count(*), count(distinct user_id) from shorturl").Scan(&urlsCount, &usersCount)
+
+	return usersCount, urlsCount, err
+}
+
+// DeleteExpiredURL - удаление из БД ссылок, срок действия которых истек до expiredBefore
+func (r *RepositoryShortURL) DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
 	defer cancel()
 
-	rows, err := r.db.dbpool.Query(ctx, "select short_url, original_url from shorturl where user_id = $1", userID)
+	tag, err := r.db.dbpool.Exec(ctx, "delete from shorturl where expires_at < $1", expiredBefore)
 	if err != nil {
-		r.log.Error("Error get all urls from db", zap.Error(err))
-		return nil, err
+		ctxlog.From(ctx, r.log).Error("Error delete expired urls", zap.Error(err))
+		return 0, err
 	}
 
-	defer rows.Close()
+	return int(tag.RowsAffected()), nil
+}
+
+// PurgeDeletedURL - удаление из БД ссылок, помеченных удаленными до deletedBefore
+func (r *RepositoryShortURL) PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
 
-	res, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.KeyOriginalURL])
-	for _, j := range res {
-		r.log.Info("Row", zap.String("key", j.Key), zap.String("original", j.OriginalURL))
+	tag, err := r.db.dbpool.Exec(ctx, "delete from shorturl where deleted and deleted_at < $1", deletedBefore)
+	if err != nil {
+		ctxlog.From(ctx, r.log).Error("Error purge deleted urls", zap.Error(err))
+		return 0, err
 	}
 
-	return res, err
+	return int(tag.RowsAffected()), nil
 }
 
-// GetStats - получение их БД кол-ва коротких ссылок и кол-ва пользователей из БД
-func (r *RepositoryShortURL) GetStats(ctx context.Context) (int, int, error) {
-	var urlsCount int
-	var usersCount int
+// NextSequence - следующий номер последовательности ключей коротких ссылок
+func (r *RepositoryShortURL) NextSequence(ctx context.Context) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOperationDB)
+	defer cancel()
 
-	err := r.db.dbpool.QueryRow(ctx, "select count(*), count(distinct created_by) from shorturl").Scan(&urlsCount, &usersCount)
+	var n int64
+	if err := r.db.dbpool.QueryRow(ctx, "select nextval('shorturl_key_seq')").Scan(&n); err != nil {
+		ctxlog.From(ctx, r.log).Error("Error get next key sequence from db", zap.Error(err))
+		return 0, err
+	}
 
-	return usersCount, urlsCount, err
+	return uint64(n), nil
+}
+
+// Ping - проверка доступности БД
+func (r *RepositoryShortURL) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+// CheckMigrations - проверка версии схемы БД
+func (r *RepositoryShortURL) CheckMigrations(ctx context.Context) error {
+	return r.db.CheckMigrations(ctx)
+}
+
+// Close - закрытие соединения с БД
+func (r *RepositoryShortURL) Close() error {
+	return r.db.Close()
 }
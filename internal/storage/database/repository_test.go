@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"github.com/kirillmashkov/shortener.git/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestRepositoryShortURL - поведенческие тесты на реальной БД, строка подключения берется из TEST_DATABASE_DSN
+func TestRepositoryShortURL(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	// миграции лежат в корне репозитория
+	t.Chdir("../../..")
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewRepositoryShortURL(newTestDatabase(t, dsn), zap.NewNop())
+	})
+	storagetest.RunClicks(t, func(t *testing.T) (storage.Storage, storage.ClickStorage) {
+		db := newTestDatabase(t, dsn)
+		return NewRepositoryShortURL(db, zap.NewNop()), NewRepositoryClick(db, zap.NewNop())
+	})
+}
+
+// newTestDatabase - подключение к БД с пустыми таблицами ссылок, переходов и истории изменений
+func newTestDatabase(t *testing.T, dsn string) *Database {
+	t.Helper()
+
+	db := New(&config.ServerConfig{Connection: dsn}, zap.NewNop())
+	require.NoError(t, db.Open())
+	require.NoError(t, db.Migrate())
+
+	_, err := db.dbpool.Exec(context.Background(), "truncate table shorturl cascade")
+	require.NoError(t, err)
+
+	return db
+}
+
+// BenchmarkDeleteURLs - удаление 1000 ссылок 10 пользователей: отдельный UPDATE на ключ в pgx.Batch
+// и один UPDATE по массивам ключей. Строка подключения берется из TEST_DATABASE_DSN
+func BenchmarkDeleteURLs(b *testing.B) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		b.Skip("TEST_DATABASE_DSN is not set")
+	}
+	b.Chdir("../../..")
+
+	db := New(&config.ServerConfig{Connection: dsn}, zap.NewNop())
+	require.NoError(b, db.Open())
+	require.NoError(b, db.Migrate())
+	b.Cleanup(func() { _ = db.Close() })
+	repo := NewRepositoryShortURL(db, zap.NewNop())
+	ctx := context.Background()
+
+	_, err := db.dbpool.Exec(ctx, "truncate table shorturl")
+	require.NoError(b, err)
+	keys := make([]model.UserKey, 0, 1000)
+	for i := range 1000 {
+		key := model.UserKey{UserID: i % 10, Key: "bench" + strconv.Itoa(i)}
+		require.NoError(b, repo.AddURL(ctx, model.KeyOriginalURL{Key: key.Key, OriginalURL: "http://" + key.Key}, key.UserID))
+		keys = append(keys, key)
+	}
+
+	b.Run("batch of updates per key", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			batch := &pgx.Batch{}
+			for _, key := range keys {
+				batch.Queue("update shorturl set deleted = true where short_url = $1 and user_id = $2", key.Key, key.UserID)
+			}
+			require.NoError(b, db.dbpool.SendBatch(ctx, batch).Close())
+		}
+	})
+
+	b.Run("update from unnest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := repo.DeleteURLs(ctx, keys)
+			require.NoError(b, err)
+		}
+	})
+}
+
+// BenchmarkAddURLs - сохранение 2000 ссылок: INSERT на каждую ссылку и один многострочный INSERT.
+// Строка подключения берется из TEST_DATABASE_DSN
+func BenchmarkAddURLs(b *testing.B) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		b.Skip("TEST_DATABASE_DSN is not set")
+	}
+	b.Chdir("../../..")
+
+	db := New(&config.ServerConfig{Connection: dsn}, zap.NewNop())
+	require.NoError(b, db.Open())
+	require.NoError(b, db.Migrate())
+	b.Cleanup(func() { _ = db.Close() })
+	repo := NewRepositoryShortURL(db, zap.NewNop())
+	ctx := context.Background()
+
+	urls := make([]model.KeyOriginalURL, 0, 2000)
+	for i := range 2000 {
+		key := "import" + strconv.Itoa(i)
+		urls = append(urls, model.KeyOriginalURL{Key: key, OriginalURL: "http://" + key})
+	}
+
+	benchmarks := []struct {
+		name string
+		add  func() error
+	}{
+		{name: "insert per url", add: func() error {
+			for _, soURL := range urls {
+				if err := repo.AddURL(ctx, soURL, 1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{name: "multi-row insert", add: func() error {
+			_, err := repo.AddURLs(ctx, urls, 1)
+			return err
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				_, err := db.dbpool.Exec(ctx, "truncate table shorturl")
+				require.NoError(b, err)
+				b.StartTimer()
+
+				require.NoError(b, bm.add())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/analytics"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+)
+
+// clickCounter - счетчики переходов по ссылке: общее кол-во и кол-во по минутам.
+// Сами переходы не хранятся, поэтому память растет только с кол-вом минут, в которые были переходы
+type clickCounter struct {
+	total   int
+	minutes map[int64]int
+}
+
+// StoreClickMap - хранение счетчиков переходов по коротким ссылкам в памяти.
+// Интервалы статистики считаются с точностью до минуты
+type StoreClickMap struct {
+	mu     sync.RWMutex
+	clicks map[string]*clickCounter
+	// links - хранилище ссылок, переходы по отсутствующим в нем ссылкам не сохраняются. nil - проверки нет
+	links *StoreURLMap
+}
+
+// NewStoreClick - конструктор
+func NewStoreClick() *StoreClickMap {
+	return &StoreClickMap{clicks: map[string]*clickCounter{}}
+}
+
+// AddClicks - сохранение переходов
+func (s *StoreClickMap) AddClicks(ctx context.Context, clicks []model.Click) error {
+	if s.links != nil {
+		// ссылка могла быть удалена окончательно, пока переход ждал записи в буфере
+		clicks = s.existing(ctx, clicks)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, click := range clicks {
+		counter, ok := s.clicks[click.Key]
+		if !ok {
+			counter = &clickCounter{minutes: map[int64]int{}}
+			s.clicks[click.Key] = counter
+		}
+		counter.total++
+		counter.minutes[analytics.BucketStart(click.Time, analytics.BucketMinute).Unix()]++
+	}
+
+	return nil
+}
+
+func (s *StoreClickMap) existing(ctx context.Context, clicks []model.Click) []model.Click {
+	res := make([]model.Click, 0, len(clicks))
+	for _, click := range clicks {
+		if _, exist := s.links.GetURL(ctx, click.Key); exist {
+			res = append(res, click)
+		}
+	}
+	return res
+}
+
+// GetClickStats - общее кол-во переходов по ссылке и кол-во переходов по интервалам в пределах [From, To)
+func (s *StoreClickMap) GetClickStats(ctx context.Context, key string, query model.ClickStatsQuery) (model.ClickStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counter, ok := s.clicks[key]
+	if !ok {
+		return model.ClickStats{Key: key, Series: []model.ClickBucket{}}, nil
+	}
+
+	counts := map[int64]int{}
+	for minute, count := range counter.minutes {
+		t := time.Unix(minute, 0)
+		if t.Before(analytics.BucketStart(query.From, analytics.BucketMinute)) || !t.Before(query.To) {
+			continue
+		}
+		counts[analytics.BucketStart(t, query.Bucket).Unix()] += count
+	}
+
+	return model.ClickStats{Key: key, Total: counter.total, Series: sortedBuckets(counts)}, nil
+}
+
+// remove - удаление переходов по ссылке вместе с ней, чтобы новый владелец ключа не видел чужую статистику
+func (s *StoreClickMap) remove(key string) {
+	s.mu.Lock()
+	delete(s.clicks, key)
+	s.mu.Unlock()
+}
+
+func sortedBuckets(counts map[int64]int) []model.ClickBucket {
+	res := make([]model.ClickBucket, 0, len(counts))
+	for t, c := range counts {
+		res = append(res, model.ClickBucket{Time: time.Unix(t, 0).UTC(), Count: c})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Time.Before(res[j].Time) })
+	return res
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"go.uber.org/zap"
+)
+
+func init() {
+	storage.Register(storage.DriverMemory, openMemory)
+	storage.Register(storage.DriverFile, openFile)
+}
+
+// openMemory - драйвер memory, файл хранилища не используется даже если задан
+func openMemory(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (storage.Storage, storage.ClickStorage, error) {
+	memoryCfg := *cfg
+	memoryCfg.FileStorage = ""
+
+	store, err := New(&memoryCfg, log, &memoryCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, store.Clicks(), nil
+}
+
+// openFile - драйвер file, требует путь к файлу хранилища
+func openFile(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (storage.Storage, storage.ClickStorage, error) {
+	if cfg.FileStorage == "" {
+		return nil, nil, errors.New("file storage path is not configured")
+	}
+
+	store, err := New(cfg, log, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	go store.Maintain(ctx)
+
+	return store, store.Clicks(), nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+func historyStoreFile(key string, entry model.URLHistory) StoreFile {
+	return StoreFile{UUID: uuid.NewString(), ShortURL: key, History: &entry}
+}
+
+// UpdateURL - изменение ссылки пользователя под блокировкой хранилища.
+// Прежнее и новое состояние дописываются в файл хранилища одной операцией записи
+func (storeMap *StoreURLMap) UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error) {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	key := change.Key
+	info, exist := storeMap.urls[key]
+	if !exist || info.UserID != change.UserID {
+		return model.ShortURLInfo{}, model.ErrURLNotFound
+	}
+	if info.Deleted {
+		return model.ShortURLInfo{}, model.ErrURLDeleted
+	}
+	updated := change.Apply(info)
+	if other, exist := storeMap.originals[updated.OriginalURL]; exist && other != key {
+		return model.ShortURLInfo{}, model.ErrDuplicateURL
+	}
+
+	entry := model.URLHistory{
+		OriginalURL: info.OriginalURL,
+		ExpiresAt:   info.ExpiresAt,
+		Redirect:    info.Redirect,
+		RequestID:   change.RequestID,
+		ChangedAt:   change.ChangedAt,
+	}
+	if err := storeMap.saveToFile([]StoreFile{historyStoreFile(key, entry), toStoreFile(updated)}); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save updated link into file", zap.String("key", key), zap.Error(err))
+		return model.ShortURLInfo{}, err
+	}
+
+	if storeMap.originals[info.OriginalURL] == key {
+		delete(storeMap.originals, info.OriginalURL)
+	}
+	storeMap.originals[updated.OriginalURL] = key
+	storeMap.urls[key] = updated
+	storeMap.history[key] = append(storeMap.history[key], entry)
+
+	return updated, nil
+}
+
+// GetURLHistory - прежние состояния ссылки в порядке изменения
+func (storeMap *StoreURLMap) GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error) {
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
+
+	return append([]model.URLHistory{}, storeMap.history[key]...), nil
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+func jobStoreFile(job model.DeleteJob) StoreFile {
+	return StoreFile{UUID: uuid.NewString(), Job: &job}
+}
+
+// SaveJob - сохранение состояния задания на удаление. Если задан файл хранилища, состояние дописывается в него
+func (storeMap *StoreURLMap) SaveJob(ctx context.Context, job model.DeleteJob) error {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	if err := storeMap.saveToFile([]StoreFile{jobStoreFile(job)}); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save delete job into file", zap.String("job", job.ID), zap.Error(err))
+		return err
+	}
+
+	job.Keys = append([]model.DeleteJobKey(nil), job.Keys...)
+	storeMap.jobs[job.ID] = job
+	return nil
+}
+
+// GetJob - получение задания на удаление
+func (storeMap *StoreURLMap) GetJob(ctx context.Context, id string) (model.DeleteJob, error) {
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
+
+	job, ok := storeMap.jobs[id]
+	if !ok {
+		return model.DeleteJob{}, model.ErrJobNotFound
+	}
+
+	job.Keys = append([]model.DeleteJobKey(nil), job.Keys...)
+	return job, nil
+}
+
+// PendingJobs - невыполненные задания в порядке создания
+func (storeMap *StoreURLMap) PendingJobs(ctx context.Context) ([]model.DeleteJob, error) {
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
+
+	var jobs []model.DeleteJob
+	for _, job := range storeMap.jobs {
+		if !job.Finished() {
+			job.Keys = append([]model.DeleteJobKey(nil), job.Keys...)
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	return jobs, nil
+}
+
+// FailJob - пометка задания окончательно не выполненным, остальные поля задания не меняются
+func (storeMap *StoreURLMap) FailJob(ctx context.Context, id string, reason string, at time.Time) error {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	job, ok := storeMap.jobs[id]
+	if !ok {
+		return model.ErrJobNotFound
+	}
+	job.Status = model.JobFailed
+	job.Error = reason
+	job.UpdatedAt = at
+
+	if err := storeMap.saveToFile([]StoreFile{jobStoreFile(job)}); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save delete job into file", zap.String("job", id), zap.Error(err))
+		return err
+	}
+
+	storeMap.jobs[id] = job
+	return nil
+}
+
+// DeleteFinishedJobs - удаление из памяти завершенных заданий. Из файла хранилища они удаляются при компактизации
+func (storeMap *StoreURLMap) DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int, error) {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	count := 0
+	for id, job := range storeMap.jobs {
+		if job.Finished() && job.UpdatedAt.Before(finishedBefore) {
+			delete(storeMap.jobs, id)
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,283 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// Режимы сброса файла хранилища на диск
+const (
+	// FileSyncAlways - fsync после каждой записи
+	FileSyncAlways = "always"
+	// FileSyncInterval - fsync в фоне с интервалом FileSyncInterval
+	FileSyncInterval = "interval"
+	// FileSyncNone - сброс на диск на усмотрение ОС
+	FileSyncNone = "none"
+)
+
+const compactSuffix = ".compact"
+
+// journal - файл хранилища в формате json строк: запись до изменения данных в памяти,
+// восстановление после сбоя и компактизация
+type journal struct {
+	path     string
+	file     *os.File
+	size     int64
+	syncMode string
+	dirty    bool
+	logger   *zap.Logger
+}
+
+// openJournal - открытие файла хранилища и чтение записей.
+// Поврежденные строки в середине файла пропускаются, оборванная последняя строка отрезается
+func openJournal(path string, syncMode string, logger *zap.Logger) (*journal, []StoreFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, validSize, needNewLine, err := readRecords(file, logger)
+	if err != nil {
+		return nil, nil, errors.Join(err, file.Close())
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, errors.Join(err, file.Close())
+	}
+
+	if validSize < info.Size() {
+		logger.Warn("Truncate torn tail of storage file",
+			zap.String("file", path),
+			zap.Int64("size", info.Size()),
+			zap.Int64("valid size", validSize))
+		if err := file.Truncate(validSize); err != nil {
+			return nil, nil, errors.Join(err, file.Close())
+		}
+	}
+
+	j := &journal{path: path, file: file, size: validSize, syncMode: syncMode, logger: logger}
+	if needNewLine {
+		if err := j.write([]byte{'\n'}); err != nil {
+			return nil, nil, errors.Join(err, file.Close())
+		}
+	}
+
+	return j, records, nil
+}
+
+// readRecords - чтение записей, возвращает размер корректной части файла
+// и признак того, что последняя корректная запись не завершена переводом строки
+func readRecords(r io.Reader, logger *zap.Logger) ([]StoreFile, int64, bool, error) {
+	var records []StoreFile
+	var offset int64
+	var validSize int64
+	needNewLine := false
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, false, err
+		}
+
+		complete := len(line) > 0 && line[len(line)-1] == '\n'
+		offset += int64(len(line))
+
+		if data := bytes.TrimSpace(line); len(data) > 0 {
+			record := StoreFile{}
+			if errParse := json.Unmarshal(data, &record); errParse != nil || (record.ShortURL == "" && record.Job == nil && record.Sequence == 0) {
+				if !complete {
+					// оборванная запись в конце файла, отрезается
+					break
+				}
+				logger.Warn("Skip corrupt line of storage file", zap.Int64("offset", offset-int64(len(line))), zap.Error(errParse))
+			} else {
+				records = append(records, record)
+				needNewLine = !complete
+			}
+		}
+		validSize = offset
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	return records, validSize, needNewLine, nil
+}
+
+// append - дозапись записей одним вызовом write. При ошибке файл возвращается к прежнему размеру
+func (j *journal) append(records []StoreFile) error {
+	var buf bytes.Buffer
+	for _, record := range records {
+		j.logger.Debug("Write short url", zap.Any("short url", record))
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	return j.write(buf.Bytes())
+}
+
+func (j *journal) write(data []byte) error {
+	n, err := j.file.Write(data)
+	if err != nil {
+		if errTruncate := j.file.Truncate(j.size); errTruncate != nil {
+			j.logger.Error("Can't rollback partial write of storage file", zap.Error(errTruncate))
+		}
+		return err
+	}
+
+	if j.syncMode == FileSyncAlways {
+		if err := j.file.Sync(); err != nil {
+			// запись без fsync могла не попасть на диск, данные в памяти не меняются, поэтому и файл возвращается назад
+			if errTruncate := j.file.Truncate(j.size); errTruncate != nil {
+				j.logger.Error("Can't rollback unsynced write of storage file", zap.Error(errTruncate))
+			}
+			return err
+		}
+		j.size += int64(n)
+		return nil
+	}
+
+	j.size += int64(n)
+
+	j.dirty = true
+	return nil
+}
+
+// sync - сброс на диск записей, сделанных после предыдущего сброса
+func (j *journal) sync() error {
+	if !j.dirty {
+		return nil
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+
+	j.dirty = false
+	return nil
+}
+
+// compaction - компактизация файла хранилища в процессе: временный файл с актуальными записями
+// и размер файла хранилища на момент снятия записей
+type compaction struct {
+	tmp     *os.File
+	tmpPath string
+	from    int64
+	size    int64
+}
+
+// startCompact - запись актуальных записей во временный файл. Вызывается без блокировки хранилища,
+// from - размер файла хранилища на момент снятия records, записи после него переносятся в finishCompact
+func (j *journal) startCompact(records []StoreFile, from int64) (*compaction, error) {
+	tmpPath := j.path + compactSuffix
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compaction{tmp: tmp, tmpPath: tmpPath, from: from}
+	c.size, err = writeRecords(tmp, records)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		return nil, errors.Join(err, c.abort())
+	}
+
+	return c, nil
+}
+
+// finishCompact - дозапись во временный файл записей, сделанных после снятия актуальных записей,
+// и атомарная замена им файла хранилища. Вызывается под блокировкой хранилища
+func (j *journal) finishCompact(c *compaction) error {
+	tail, err := io.Copy(c.tmp, io.NewSectionReader(j.file, c.from, j.size-c.from))
+	if err == nil {
+		err = c.tmp.Sync()
+	}
+	if errClose := c.tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(c.tmpPath))
+	}
+
+	if err := os.Rename(c.tmpPath, j.path); err != nil {
+		return errors.Join(err, os.Remove(c.tmpPath))
+	}
+	syncDir(filepath.Dir(j.path), j.logger)
+
+	file, err := os.OpenFile(j.path, os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	if err := j.file.Close(); err != nil {
+		j.logger.Error("Can't close storage file replaced by compaction", zap.Error(err))
+	}
+
+	j.file = file
+	j.size = c.size + tail
+	j.dirty = false
+	return nil
+}
+
+// abort - отказ от компактизации, временный файл удаляется
+func (c *compaction) abort() error {
+	return errors.Join(c.tmp.Close(), os.Remove(c.tmpPath))
+}
+
+func writeRecords(file *os.File, records []StoreFile) (int64, error) {
+	writer := bufio.NewWriter(file)
+	var size int64
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return 0, err
+		}
+		data = append(data, '\n')
+		n, err := writer.Write(data)
+		if err != nil {
+			return 0, err
+		}
+		size += int64(n)
+	}
+
+	return size, writer.Flush()
+}
+
+// syncDir - сброс на диск каталога, чтобы переименование файла пережило сбой
+func syncDir(dir string, logger *zap.Logger) {
+	d, err := os.Open(dir)
+	if err != nil {
+		logger.Warn("Can't open storage dir for sync", zap.Error(err))
+		return
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			logger.Warn("Can't close storage dir", zap.Error(err))
+		}
+	}()
+
+	if err := d.Sync(); err != nil {
+		logger.Warn("Can't sync storage dir", zap.Error(err))
+	}
+}
+
+// close - сброс на диск и закрытие файла
+func (j *journal) close() error {
+	return errors.Join(j.sync(), j.file.Close())
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreURLMapRecoversTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	content := `{"uuid":"1","short_url":"aaa","original_url":"http://a.ru","user_id":1}` + "\n" +
+		`not a json line` + "\n" +
+		`{"uuid":"2","short_url":"bbb","original_url":"http://b.ru","user_id":1}` + "\n" +
+		`{"uuid":"3","short_url":"ccc","orig`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0666))
+
+	store := newTestStore(t, path)
+
+	_, exist := store.GetURL(context.Background(), "aaa")
+	assert.True(t, exist)
+	_, exist = store.GetURL(context.Background(), "bbb")
+	assert.True(t, exist)
+	_, exist = store.GetURL(context.Background(), "ccc")
+	assert.False(t, exist)
+
+	require.NoError(t, store.AddURL(context.Background(), model.KeyOriginalURL{Key: "ddd", OriginalURL: "http://d.ru"}, 1))
+	require.NoError(t, store.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"ccc"`, "torn tail must be truncated")
+	assert.True(t, strings.HasSuffix(string(data), "\n"))
+
+	reloaded := newTestStore(t, path)
+	_, exist = reloaded.GetURL(context.Background(), "ddd")
+	assert.True(t, exist)
+}
+
+func TestStoreURLMapAppendsNewLineAfterUnterminatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	content := `{"uuid":"1","short_url":"aaa","original_url":"http://a.ru","user_id":1}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0666))
+
+	store := newTestStore(t, path)
+	require.NoError(t, store.AddURL(context.Background(), model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 1))
+	require.NoError(t, store.Close())
+
+	reloaded := newTestStore(t, path)
+	_, exist := reloaded.GetURL(context.Background(), "aaa")
+	assert.True(t, exist)
+	_, exist = reloaded.GetURL(context.Background(), "bbb")
+	assert.True(t, exist)
+}
+
+func TestStoreURLMapCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	_, err := store.AddURLs(ctx, []model.KeyOriginalURL{
+		{Key: "aaa", OriginalURL: "http://a.ru"},
+		{Key: "bbb", OriginalURL: "http://b.ru"},
+	}, 1)
+	require.NoError(t, err)
+	_, err = store.DeleteURLs(ctx, []model.UserKey{{UserID: 1, Key: "aaa"}})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Compact(ctx))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"), "only the last state of each link is kept")
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "ccc", OriginalURL: "http://c.ru"}, 1))
+	require.NoError(t, store.Close())
+
+	reloaded := newTestStore(t, path)
+	info, exist := reloaded.GetURL(ctx, "aaa")
+	require.True(t, exist, "deleted link is kept until purged")
+	assert.True(t, info.Deleted)
+	assert.NotNil(t, info.DeletedAt)
+	_, exist = reloaded.GetURL(ctx, "bbb")
+	assert.True(t, exist)
+	_, exist = reloaded.GetURL(ctx, "ccc")
+	assert.True(t, exist)
+
+	_, err = os.Stat(path + compactSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStoreURLMapCompactKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+
+	records, from := store.snapshot()
+	c, err := store.journal.startCompact(records, from)
+	require.NoError(t, err)
+
+	// запись, сделанная пока временный файл пишется без блокировки хранилища
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 1))
+
+	store.mu.Lock()
+	require.NoError(t, store.journal.finishCompact(c))
+	store.mu.Unlock()
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "ccc", OriginalURL: "http://c.ru"}, 1))
+	require.NoError(t, store.Close())
+
+	reloaded := newTestStore(t, path)
+	for _, key := range []string{"aaa", "bbb", "ccc"} {
+		_, exist := reloaded.GetURL(ctx, key)
+		assert.True(t, exist, key)
+	}
+}
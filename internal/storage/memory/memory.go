@@ -1,218 +1,546 @@
 package memory
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"errors"
-	"os"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
 	"github.com/kirillmashkov/shortener.git/internal/model"
 	"go.uber.org/zap"
 )
 
 // StoreURLMap - доступ к хранения в памяти ссылок
 type StoreURLMap struct {
-	mu     sync.RWMutex
-	urls   map[string]string
-	logger *zap.Logger
-	cfg    *config.ServerConfig
+	mu        sync.RWMutex
+	urls      map[string]model.ShortURLInfo
+	originals map[string]string
+	// users - ключи ссылок по пользователям
+	users    map[int]map[string]struct{}
+	jobs     map[string]model.DeleteJob
+	history  map[string][]model.URLHistory
+	sequence sequence
+	clicks   *StoreClickMap
+	journal  *journal
+	// compactMu - одна компактизация файла хранилища за раз
+	compactMu sync.Mutex
+	logger    *zap.Logger
+	cfg       *config.ServerConfig
 }
 
-// StoreFile - json для сохранения ссылок в файл
+// StoreFile - json для сохранения ссылок в файл. Файл только дополняется,
+// при чтении более поздняя запись с тем же short_url заменяет предыдущую.
+// Запись с заполненным Job - состояние задания на удаление, более поздняя заменяет предыдущую с тем же ID.
+// Запись с заполненным History - прежнее состояние ссылки short_url, записывается при ее изменении.
+// Запись с заполненным Sequence - граница номеров последовательности ключей, выданных до перезапуска.
+// Запись с Removed - ссылка short_url удалена окончательно вместе с историей изменений
 type StoreFile struct {
-	UUID        string `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	UUID        string            `json:"uuid"`
+	ShortURL    string            `json:"short_url,omitempty"`
+	OriginalURL string            `json:"original_url,omitempty"`
+	UserID      int               `json:"user_id,omitempty"`
+	Deleted     bool              `json:"deleted,omitempty"`
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	Redirect    *model.Redirect   `json:"redirect,omitempty"`
+	CreatedAt   *time.Time        `json:"created_at,omitempty"`
+	History     *model.URLHistory `json:"history,omitempty"`
+	Job         *model.DeleteJob  `json:"job,omitempty"`
+	Sequence    uint64            `json:"sequence,omitempty"`
+	Removed     bool              `json:"removed,omitempty"`
 }
 
-// New - конструктор
+// New - конструктор. Если путь к файлу хранилища не задан, ссылки хранятся только в памяти
 func New(conf *config.ServerConfig, logger *zap.Logger, config *config.ServerConfig) (*StoreURLMap, error) {
-	urls := map[string]string{}
-
-	logger.Info("Read storage file", zap.String("file", conf.FileStorage))
-	file, err := os.OpenFile(conf.FileStorage, os.O_RDONLY|os.O_CREATE, 0666)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if errClose := file.Close(); errClose != nil {
-			logger.Error("Can't close storage file when read")
-			err = errors.Join(errClose)
-		}
-	}()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		shortURL := StoreFile{}
-		err = json.Unmarshal(scanner.Bytes(), &shortURL)
+	urls := map[string]model.ShortURLInfo{}
+	originals := map[string]string{}
+	users := map[int]map[string]struct{}{}
+	jobs := map[string]model.DeleteJob{}
+	history := map[string][]model.URLHistory{}
+	var seq sequence
+	loadedAt := time.Now().UTC()
+
+	var journal *journal
+	var records []StoreFile
+	if conf.FileStorage != "" {
+		logger.Info("Read storage file", zap.String("file", conf.FileStorage))
+		var err error
+		journal, records, err = openJournal(conf.FileStorage, conf.FileSync, logger)
 		if err != nil {
-			logger.Error("Can't parse storage file")
+			logger.Error("Can't open storage file", zap.Error(err))
 			return nil, err
 		}
+	}
+
+	storeMap := &StoreURLMap{
+		urls:      urls,
+		originals: originals,
+		users:     users,
+		jobs:      jobs,
+		history:   history,
+		journal:   journal,
+		logger:    logger,
+		cfg:       config,
+	}
+	storeMap.clicks = &StoreClickMap{clicks: map[string]*clickCounter{}, links: storeMap}
 
-		logger.Info("Read short ulr",
+	for _, shortURL := range records {
+		if shortURL.Job != nil {
+			jobs[shortURL.Job.ID] = *shortURL.Job
+			continue
+		}
+		if shortURL.Sequence > 0 {
+			seq.restore(shortURL.Sequence)
+			continue
+		}
+		if shortURL.History != nil {
+			history[shortURL.ShortURL] = append(history[shortURL.ShortURL], *shortURL.History)
+			continue
+		}
+		if shortURL.Removed {
+			if prev, ok := urls[shortURL.ShortURL]; ok {
+				storeMap.removeURL(prev)
+			}
+			continue
+		}
+		logger.Debug("Read short ulr",
 			zap.String("shortURL", shortURL.ShortURL),
 			zap.String("OriginalURL", shortURL.OriginalURL),
 			zap.String("id", shortURL.UUID))
-		urls[shortURL.ShortURL] = shortURL.OriginalURL
-
+		if prev, ok := urls[shortURL.ShortURL]; ok {
+			// после изменения ссылки прежняя исходная ссылка больше не занята
+			if prev.OriginalURL != shortURL.OriginalURL && originals[prev.OriginalURL] == shortURL.ShortURL {
+				delete(originals, prev.OriginalURL)
+			}
+			// ключ окончательно удаленной ссылки мог быть занят другим пользователем
+			if prev.UserID != shortURL.UserID {
+				delete(users[prev.UserID], prev.Key)
+			}
+		}
+		info := model.ShortURLInfo{
+			Key:         shortURL.ShortURL,
+			OriginalURL: shortURL.OriginalURL,
+			UserID:      shortURL.UserID,
+			Deleted:     shortURL.Deleted,
+			DeletedAt:   shortURL.DeletedAt,
+			ExpiresAt:   shortURL.ExpiresAt,
+			Redirect:    shortURL.Redirect,
+		}
+		if shortURL.CreatedAt != nil {
+			info.CreatedAt = *shortURL.CreatedAt
+		}
+		// в записях, сделанных до появления deleted_at, срок хранения удаленной ссылки отсчитывается от загрузки
+		if info.Deleted && info.DeletedAt == nil {
+			info.DeletedAt = &loadedAt
+		}
+		urls[shortURL.ShortURL] = info
+		originals[shortURL.OriginalURL] = shortURL.ShortURL
+		addUserKey(users, info.UserID, info.Key)
 	}
+	storeMap.sequence = seq
 
-	if err := scanner.Err(); err != nil {
-		logger.Error("Error read file", zap.Error(err))
-	}
+	return storeMap, nil
+}
 
-	return &StoreURLMap{
-		urls:   urls,
-		logger: logger,
-		cfg:    config,
-	}, nil
+// Clicks - хранилище переходов по ссылкам хранилища. Переходы удаляются при окончательном удалении ссылки
+func (storeMap *StoreURLMap) Clicks() *StoreClickMap {
+	return storeMap.clicks
 }
 
 // AddURL - сохранение ссылки
-func (storeMap *StoreURLMap) AddURL(ctx context.Context, url string, keyURL string, userID int) error {
-	storeMap.mu.Lock()
-	defer storeMap.mu.Unlock()
-
-	err := storeMap.saveShortURLToFile(keyURL, url)
+func (storeMap *StoreURLMap) AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error {
+	results, err := storeMap.AddURLs(ctx, []model.KeyOriginalURL{soURL}, userID)
 	if err != nil {
-		storeMap.logger.Error("Can't save link into file")
 		return err
 	}
-
-	storeMap.urls[keyURL] = url
-	return nil
+	return results[0].Err
 }
 
-// AddBatchURL - сохранение массива ссылок
-func (storeMap *StoreURLMap) AddBatchURL(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) error {
+// AddURLs - сохранение массива ссылок одной записью в файл. Ссылки с дублем ключа или исходной ссылки пропускаются
+func (storeMap *StoreURLMap) AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error) {
 	storeMap.mu.Lock()
 	defer storeMap.mu.Unlock()
-	err := storeMap.saveShortURLToFileBatch(shortOriginalURL)
-	if err != nil {
-		storeMap.logger.Error("Can't save links into file")
-		return err
+
+	now := time.Now().UTC()
+	results := make([]model.AddURLResult, len(shortOriginalURL))
+	keys := make(map[string]struct{}, len(shortOriginalURL))
+	originals := make(map[string]string, len(shortOriginalURL))
+	records := make([]StoreFile, 0, len(shortOriginalURL))
+	for i, soURL := range shortOriginalURL {
+		if key, exist := storeMap.originals[soURL.OriginalURL]; exist {
+			results[i] = model.AddURLResult{Key: key, Err: model.ErrDuplicateURL}
+			continue
+		}
+		if key, exist := originals[soURL.OriginalURL]; exist {
+			results[i] = model.AddURLResult{Key: key, Err: model.ErrDuplicateURL}
+			continue
+		}
+		_, exist := storeMap.urls[soURL.Key]
+		if _, inBatch := keys[soURL.Key]; exist || inBatch {
+			results[i] = model.AddURLResult{Key: soURL.Key, Err: model.ErrDuplicateKey}
+			continue
+		}
+		keys[soURL.Key] = struct{}{}
+		originals[soURL.OriginalURL] = soURL.Key
+
+		results[i] = model.AddURLResult{Key: soURL.Key}
+		records = append(records, toStoreFile(newShortURLInfo(soURL, userID, now)))
 	}
 
-	for _, soURL := range shortOriginalURL {
-		storeMap.urls[soURL.Key] = soURL.OriginalURL
+	if err := storeMap.saveToFile(records); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save links into file", zap.Error(err))
+		return nil, err
 	}
 
-	return nil
+	for i, soURL := range shortOriginalURL {
+		if results[i].Err == nil {
+			storeMap.urls[soURL.Key] = newShortURLInfo(soURL, userID, now)
+			storeMap.originals[soURL.OriginalURL] = soURL.Key
+			addUserKey(storeMap.users, userID, soURL.Key)
+		}
+	}
+
+	return results, nil
+}
+
+func newShortURLInfo(soURL model.KeyOriginalURL, userID int, createdAt time.Time) model.ShortURLInfo {
+	if !soURL.CreatedAt.IsZero() {
+		createdAt = soURL.CreatedAt.UTC()
+	}
+	return model.ShortURLInfo{
+		Key:         soURL.Key,
+		OriginalURL: soURL.OriginalURL,
+		UserID:      userID,
+		ExpiresAt:   soURL.ExpiresAt,
+		Redirect:    soURL.Redirect,
+		CreatedAt:   createdAt,
+	}
+}
+
+func addUserKey(users map[int]map[string]struct{}, userID int, key string) {
+	keys, ok := users[userID]
+	if !ok {
+		keys = map[string]struct{}{}
+		users[userID] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func toStoreFile(info model.ShortURLInfo) StoreFile {
+	record := StoreFile{
+		UUID:        uuid.NewString(),
+		ShortURL:    info.Key,
+		OriginalURL: info.OriginalURL,
+		UserID:      info.UserID,
+		Deleted:     info.Deleted,
+		DeletedAt:   info.DeletedAt,
+		ExpiresAt:   info.ExpiresAt,
+		Redirect:    info.Redirect,
+	}
+	if !info.CreatedAt.IsZero() {
+		record.CreatedAt = &info.CreatedAt
+	}
+	return record
 }
 
 // GetURL - получение ссылки
-func (storeMap *StoreURLMap) GetURL(ctx context.Context, keyURL string) (string, bool, bool) {
+func (storeMap *StoreURLMap) GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool) {
 	storeMap.mu.RLock()
-	url, exist := storeMap.urls[keyURL]
+	info, exist := storeMap.urls[keyURL]
 	storeMap.mu.RUnlock()
-	return url, false, exist
+	return info, exist
 }
 
-// GetAllURL - получение всех ссылок
-func (storeMap *StoreURLMap) GetAllURL(ctx context.Context, userID int) ([]model.KeyOriginalURL, error) {
-	storeMap.mu.Lock()
-	defer storeMap.mu.Unlock()
+// ListURLs - страница ссылок пользователя. Перебираются только ссылки пользователя из индекса по пользователям
+func (storeMap *StoreURLMap) ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error) {
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
 
-	res := make([]model.KeyOriginalURL, 0, len(storeMap.urls))
-	for k, v := range storeMap.urls {
-		res = append(res, model.KeyOriginalURL{Key: k, OriginalURL: v})
+	res := make([]model.ShortURLInfo, 0)
+	for key := range storeMap.users[userID] {
+		if info := storeMap.urls[key]; query.Match(info) {
+			res = append(res, info)
+		}
+	}
+
+	slices.SortFunc(res, query.Compare)
+	if query.Limit > 0 && len(res) > query.Limit {
+		res = res[:query.Limit]
 	}
 	return res, nil
 }
 
-// GetShortURL - реализация отсутствует
+// GetShortURL - получение ключа короткой ссылки по исходной ссылке
 func (storeMap *StoreURLMap) GetShortURL(ctx context.Context, originalURL string) (string, error) {
-	return "", errors.New("unsupport operation")
-}
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
 
-// DeleteURLBatchProcessor - реализация отсутствует
-func (storeMap *StoreURLMap) DeleteURLBatchProcessor(ctx context.Context) {
-	storeMap.logger.Error("unsupport operation")
+	key, exist := storeMap.originals[originalURL]
+	if !exist {
+		return "", model.ErrURLNotFound
+	}
+
+	return key, nil
 }
 
-func (storeMap *StoreURLMap) saveShortURLToFileBatch(shortOriginalURL []model.KeyOriginalURL) error {
-	storeMap.logger.Info("Write to file storage", zap.String("file", storeMap.cfg.FileStorage))
-	file, err := os.OpenFile(storeMap.cfg.FileStorage, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if errClose := file.Close(); errClose != nil {
-			storeMap.logger.Error("Can't close storage file when save it")
+// DeleteURLs - пометка удаленными ссылок разных пользователей одной записью в файл
+func (storeMap *StoreURLMap) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	now := time.Now().UTC()
+	owned := make([]model.UserKey, 0, len(keys))
+	records := make([]StoreFile, 0, len(keys))
+	for _, key := range keys {
+		info, exist := storeMap.urls[key.Key]
+		if !exist || info.UserID != key.UserID {
+			continue
+		}
+		owned = append(owned, key)
+		if info.Deleted {
+			continue
 		}
-	}()
+		info.Deleted = true
+		info.DeletedAt = &now
+		records = append(records, toStoreFile(info))
+	}
 
-	writer := bufio.NewWriter(file)
+	if err := storeMap.saveToFile(records); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save deleted links into file", zap.Error(err))
+		return nil, err
+	}
 
-	for _, soURL := range shortOriginalURL {
-		err = storeMap.writeToFile(soURL.Key, soURL.OriginalURL, writer)
-		if err != nil {
-			return err
+	for _, record := range records {
+		info := storeMap.urls[record.ShortURL]
+		info.Deleted = true
+		info.DeletedAt = &now
+		storeMap.urls[record.ShortURL] = info
+	}
+
+	return owned, nil
+}
+
+// RestoreURLs - снятие пометки удаления со ссылок пользователя одной записью в файл. Чужие и отсутствующие ссылки пропускаются
+func (storeMap *StoreURLMap) RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error) {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	owned := make([]string, 0, len(shortURL))
+	records := make([]StoreFile, 0, len(shortURL))
+	for _, key := range shortURL {
+		info, exist := storeMap.urls[key]
+		if !exist || info.UserID != userID {
+			continue
 		}
+		owned = append(owned, key)
+		if !info.Deleted {
+			continue
+		}
+		info.Deleted = false
+		info.DeletedAt = nil
+		records = append(records, toStoreFile(info))
 	}
 
-	if err := writer.Flush(); err != nil {
-		return err
+	if err := storeMap.saveToFile(records); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save restored links into file", zap.Error(err))
+		return nil, err
 	}
 
-	return nil
+	for _, record := range records {
+		info := storeMap.urls[record.ShortURL]
+		info.Deleted = false
+		info.DeletedAt = nil
+		storeMap.urls[record.ShortURL] = info
+	}
+
+	return owned, nil
 }
 
-func (storeMap *StoreURLMap) saveShortURLToFile(url string, originalURL string) error {
-	storeMap.logger.Info("Write to file storage", zap.String("file", storeMap.cfg.FileStorage))
-	file, err := os.OpenFile(storeMap.cfg.FileStorage, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
-	if err != nil {
-		return err
+func (storeMap *StoreURLMap) saveToFile(records []StoreFile) error {
+	if storeMap.journal == nil || len(records) == 0 {
+		return nil
+	}
+
+	return storeMap.journal.append(records)
+}
+
+// GetStats - получение кол-ва пользователей и кол-ва коротких ссылок
+func (storeMap *StoreURLMap) GetStats(ctx context.Context) (int, int, error) {
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
+
+	users := make(map[int]struct{})
+	for _, v := range storeMap.urls {
+		users[v.UserID] = struct{}{}
+	}
+
+	return len(users), len(storeMap.urls), nil
+}
+
+// DeleteExpiredURL - окончательное удаление ссылок, срок действия которых истек до expiredBefore
+func (storeMap *StoreURLMap) DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error) {
+	return storeMap.removeURLs(ctx, func(info model.ShortURLInfo) bool {
+		return info.Expired(expiredBefore)
+	})
+}
+
+// PurgeDeletedURL - окончательное удаление ссылок, помеченных удаленными до deletedBefore
+func (storeMap *StoreURLMap) PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error) {
+	return storeMap.removeURLs(ctx, func(info model.ShortURLInfo) bool {
+		return info.DeletedBefore(deletedBefore)
+	})
+}
+
+// removeURLs - окончательное удаление подходящих ссылок. В файл пишутся записи Removed,
+// чтобы ссылки не появились снова после перезапуска
+func (storeMap *StoreURLMap) removeURLs(ctx context.Context, match func(info model.ShortURLInfo) bool) (int, error) {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	var removed []model.ShortURLInfo
+	records := make([]StoreFile, 0)
+	for _, v := range storeMap.urls {
+		if match(v) {
+			removed = append(removed, v)
+			records = append(records, StoreFile{UUID: uuid.NewString(), ShortURL: v.Key, Removed: true})
+		}
+	}
+
+	if err := storeMap.saveToFile(records); err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't save removed links into file", zap.Error(err))
+		return 0, err
+	}
+
+	for _, v := range removed {
+		storeMap.removeURL(v)
 	}
-	defer func() {
-		if errClose := file.Close(); errClose != nil {
-			storeMap.logger.Error("Can't close storage file when save it")
+
+	return len(removed), nil
+}
+
+// removeURL - удаление ссылки, истории ее изменений и переходов по ней из памяти
+func (storeMap *StoreURLMap) removeURL(info model.ShortURLInfo) {
+	delete(storeMap.urls, info.Key)
+	delete(storeMap.history, info.Key)
+	storeMap.clicks.remove(info.Key)
+	if keys := storeMap.users[info.UserID]; keys != nil {
+		delete(keys, info.Key)
+		if len(keys) == 0 {
+			delete(storeMap.users, info.UserID)
 		}
-	}()
+	}
+	if storeMap.originals[info.OriginalURL] == info.Key {
+		delete(storeMap.originals, info.OriginalURL)
+	}
+}
 
-	writer := bufio.NewWriter(file)
+// Compact - перезапись файла хранилища только актуальными записями.
+// Замененные более поздними записи в новый файл не попадают. Удаленные ссылки сохраняются
+// до окончательного удаления в PurgeDeletedURL, чтобы их можно было восстановить
+func (storeMap *StoreURLMap) Compact(ctx context.Context) error {
+	if storeMap.journal == nil {
+		return nil
+	}
+
+	storeMap.compactMu.Lock()
+	defer storeMap.compactMu.Unlock()
 
-	if err := storeMap.writeToFile(url, originalURL, writer); err != nil {
+	// актуальные записи снимаются под блокировкой чтения, а пишутся во временный файл без блокировки,
+	// чтобы запись ссылок не ждала перезаписи файла. Сделанные за это время записи переносятся из хвоста файла
+	records, from := storeMap.snapshot()
+	c, err := storeMap.journal.startCompact(records, from)
+	if err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't compact storage file", zap.Error(err))
 		return err
 	}
 
-	if err := writer.Flush(); err != nil {
+	storeMap.mu.Lock()
+	err = storeMap.journal.finishCompact(c)
+	storeMap.mu.Unlock()
+	if err != nil {
+		ctxlog.From(ctx, storeMap.logger).Error("Can't compact storage file", zap.Error(err))
 		return err
 	}
 
+	ctxlog.From(ctx, storeMap.logger).Info("Storage file compacted", zap.Int("records", len(records)))
 	return nil
 }
 
-func (storeMap *StoreURLMap) writeToFile(shortURL string, originalURL string, writer *bufio.Writer) error {
-	shortURLToFile := StoreFile{
-		UUID:        uuid.NewString(),
-		ShortURL:    shortURL,
-		OriginalURL: originalURL,
+// snapshot - актуальные записи хранилища и размер файла хранилища, которому они соответствуют
+func (storeMap *StoreURLMap) snapshot() ([]StoreFile, int64) {
+	storeMap.mu.RLock()
+	defer storeMap.mu.RUnlock()
+
+	records := make([]StoreFile, 0, len(storeMap.urls)+len(storeMap.jobs))
+	for _, v := range storeMap.urls {
+		records = append(records, toStoreFile(v))
+		for _, entry := range storeMap.history[v.Key] {
+			records = append(records, historyStoreFile(v.Key, entry))
+		}
+	}
+	for _, job := range storeMap.jobs {
+		records = append(records, jobStoreFile(job))
+	}
+	if storeMap.sequence.reserved > 0 {
+		records = append(records, sequenceStoreFile(storeMap.sequence.reserved))
 	}
 
-	storeMap.logger.Info("Write short url", zap.Any("short url", shortURLToFile))
-	data, err := json.Marshal(shortURLToFile)
-	if err != nil {
-		return err
+	return records, storeMap.journal.size
+}
+
+// Maintain - фоновое обслуживание файла хранилища: периодический fsync и компактизация
+func (storeMap *StoreURLMap) Maintain(ctx context.Context) {
+	if storeMap.journal == nil {
+		return
 	}
 
-	if _, err := writer.Write(data); err != nil {
-		return err
+	var syncC, compactC <-chan time.Time
+	if storeMap.cfg.FileSync == FileSyncInterval && storeMap.cfg.FileSyncInterval > 0 {
+		syncTicker := time.NewTicker(storeMap.cfg.FileSyncInterval)
+		defer syncTicker.Stop()
+		syncC = syncTicker.C
+	}
+	if storeMap.cfg.FileCompactInterval > 0 {
+		compactTicker := time.NewTicker(storeMap.cfg.FileCompactInterval)
+		defer compactTicker.Stop()
+		compactC = compactTicker.C
 	}
 
-	if err := writer.WriteByte('\n'); err != nil {
-		return err
+	for {
+		select {
+		case <-ctx.Done():
+			ctxlog.From(ctx, storeMap.logger).Info("Maintain: graceful shutdown")
+			return
+		case <-syncC:
+			storeMap.mu.Lock()
+			if err := storeMap.journal.sync(); err != nil {
+				ctxlog.From(ctx, storeMap.logger).Error("Can't sync storage file", zap.Error(err))
+			}
+			storeMap.mu.Unlock()
+		case <-compactC:
+			if err := storeMap.Compact(ctx); err != nil {
+				ctxlog.From(ctx, storeMap.logger).Error("Periodic compaction failed", zap.Error(err))
+			}
+		}
 	}
+}
 
+// Ping - проверка доступности хранилища, хранилище в памяти доступно всегда
+func (storeMap *StoreURLMap) Ping(ctx context.Context) error {
 	return nil
 }
 
-// GetStats - получение кол-ва коротких ссылок и кол-ва пользователей из памяти пока не реализовано
-func (storeMap *StoreURLMap) GetStats(ctx context.Context) (int, int, error) {
-	return 0, 0, errors.New("unsupport operation")
+// Close - сброс на диск и закрытие файла хранилища
+func (storeMap *StoreURLMap) Close() error {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	if storeMap.journal == nil {
+		return nil
+	}
+
+	return storeMap.journal.close()
 }
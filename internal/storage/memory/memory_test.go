@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"github.com/kirillmashkov/shortener.git/internal/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestStore(t *testing.T, path string) *StoreURLMap {
+	t.Helper()
+
+	cfg := &config.ServerConfig{FileStorage: path}
+	store, err := New(cfg, zap.NewNop(), cfg)
+	require.NoError(t, err)
+	return store
+}
+
+func TestStoreURLMapPersistsOwnerAndDeletion(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	_, err := store.AddURLs(ctx, []model.KeyOriginalURL{
+		{Key: "bbb", OriginalURL: "http://b.ru"},
+		{Key: "ccc", OriginalURL: "http://c.ru"},
+	}, 2)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "ddd", OriginalURL: "http://a.ru"}, 2), model.ErrDuplicateURL)
+	assert.ErrorIs(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://d.ru"}, 2), model.ErrDuplicateKey)
+
+	key, err := store.GetShortURL(ctx, "http://a.ru")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", key)
+
+	_, err = store.DeleteURLs(ctx, []model.UserKey{{UserID: 2, Key: "aaa"}, {UserID: 2, Key: "bbb"}})
+	require.NoError(t, err)
+
+	reloaded := newTestStore(t, path)
+
+	info, exist := reloaded.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	assert.False(t, info.Deleted, "link of other user must not be deleted")
+	assert.Equal(t, 1, info.UserID)
+
+	info, exist = reloaded.GetURL(ctx, "bbb")
+	require.True(t, exist)
+	assert.True(t, info.Deleted)
+
+	urls, err := reloaded.ListURLs(ctx, 2, model.URLListQuery{Sort: model.URLSortKey})
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	users, count, err := reloaded.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, users)
+	assert.Equal(t, 3, count)
+}
+
+func TestStoreURLMapPersistsJobs(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	job := model.DeleteJob{
+		ID:        "job-1",
+		UserID:    1,
+		Status:    model.JobPending,
+		Keys:      []model.DeleteJobKey{{Key: "aaa", Status: model.JobPending}},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	require.NoError(t, store.SaveJob(ctx, job))
+	require.NoError(t, store.SaveJob(ctx, model.DeleteJob{ID: "job-2", Status: model.JobDone, UpdatedAt: time.Now().UTC()}))
+
+	reloaded := newTestStore(t, path)
+	pending, err := reloaded.PendingJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, job.Keys, pending[0].Keys)
+
+	_, exist := reloaded.GetURL(ctx, "aaa")
+	assert.True(t, exist, "job records must not break loading of links")
+
+	_, err = reloaded.DeleteFinishedJobs(ctx, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, reloaded.Compact(ctx))
+
+	compacted := newTestStore(t, path)
+	_, err = compacted.GetJob(ctx, "job-1")
+	assert.NoError(t, err)
+	_, err = compacted.GetJob(ctx, "job-2")
+	assert.ErrorIs(t, err, model.ErrJobNotFound, "deleted finished job must be dropped by compaction")
+}
+
+func TestStoreURLMapPersistsSequence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	last := uint64(0)
+	for range sequenceLease + 1 {
+		n, err := store.NextSequence(ctx)
+		require.NoError(t, err)
+		last = n
+	}
+
+	reloaded := newTestStore(t, path)
+	n, err := reloaded.NextSequence(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, n, last, "numbers issued before restart must not be reused")
+
+	require.NoError(t, reloaded.Compact(ctx))
+	compacted := newTestStore(t, path)
+	m, err := compacted.NextSequence(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, m, n, "compaction must keep the sequence")
+}
+
+func TestStoreURLMapPersistsUpdate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	_, err := store.UpdateURL(ctx, model.URLChange{
+		Key:         "aaa",
+		OriginalURL: "http://b.ru",
+		UserID:      1,
+		RequestID:   "req-1",
+		ChangedAt:   time.Now().UTC(),
+	})
+	require.NoError(t, err)
+
+	check := func(s *StoreURLMap) {
+		info, exist := s.GetURL(ctx, "aaa")
+		require.True(t, exist)
+		assert.Equal(t, "http://b.ru", info.OriginalURL)
+		assert.Equal(t, 1, info.UserID)
+
+		_, err := s.GetShortURL(ctx, "http://a.ru")
+		assert.ErrorIs(t, err, model.ErrURLNotFound, "previous original url must be free")
+
+		history, err := s.GetURLHistory(ctx, "aaa")
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, "http://a.ru", history[0].OriginalURL)
+		assert.Equal(t, "req-1", history[0].RequestID)
+	}
+
+	reloaded := newTestStore(t, path)
+	check(reloaded)
+
+	require.NoError(t, reloaded.Compact(ctx))
+	check(newTestStore(t, path))
+}
+
+func TestStoreURLMapDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.txt")
+	store := newTestStore(t, path)
+
+	expired := time.Now().Add(-time.Hour)
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "old", OriginalURL: "http://old.ru", ExpiresAt: &expired}, 1))
+	require.NoError(t, store.AddURL(ctx, model.KeyOriginalURL{Key: "new", OriginalURL: "http://new.ru"}, 1))
+
+	count, err := store.DeleteExpiredURL(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for _, s := range []*StoreURLMap{store, newTestStore(t, path)} {
+		_, exist := s.GetURL(ctx, "old")
+		assert.False(t, exist, "expired link must not return after reload")
+		_, err = s.GetShortURL(ctx, "http://old.ru")
+		assert.ErrorIs(t, err, model.ErrURLNotFound)
+		_, exist = s.GetURL(ctx, "new")
+		assert.True(t, exist)
+	}
+}
+
+func TestStoreClickMap(t *testing.T) {
+	storagetest.RunClicks(t, func(t *testing.T) (storage.Storage, storage.ClickStorage) {
+		store := newTestStore(t, "")
+		return store, store.Clicks()
+	})
+}
+
+func TestStoreURLMapBehavior(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Storage {
+			return newTestStore(t, "")
+		})
+	})
+
+	t.Run("file", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Storage {
+			return newTestStore(t, filepath.Join(t.TempDir(), "storage.txt"))
+		})
+		storagetest.RunPersistent(t, func(t *testing.T, dir string) storage.Storage {
+			return newTestStore(t, filepath.Join(dir, "storage.txt"))
+		})
+	})
+}
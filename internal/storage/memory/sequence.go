@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kirillmashkov/shortener.git/internal/logger/ctxlog"
+	"go.uber.org/zap"
+)
+
+// sequenceLease - кол-во номеров, резервируемых одной записью в файл хранилища.
+// После перезапуска неиспользованный остаток резерва пропускается
+const sequenceLease = 1000
+
+// sequence - последовательность номеров для ключей коротких ссылок
+type sequence struct {
+	// last - последний выданный номер
+	last uint64
+	// reserved - номер, до которого включительно резерв записан в файл хранилища
+	reserved uint64
+}
+
+// restore - продолжение последовательности после резерва, прочитанного из файла
+func (seq *sequence) restore(reserved uint64) {
+	if reserved > seq.reserved {
+		seq.reserved = reserved
+		seq.last = reserved
+	}
+}
+
+func sequenceStoreFile(reserved uint64) StoreFile {
+	return StoreFile{UUID: uuid.NewString(), Sequence: reserved}
+}
+
+// NextSequence - следующий номер последовательности, начиная с 1.
+// Если задан файл хранилища, номера не повторяются после перезапуска
+func (storeMap *StoreURLMap) NextSequence(ctx context.Context) (uint64, error) {
+	storeMap.mu.Lock()
+	defer storeMap.mu.Unlock()
+
+	next := storeMap.sequence.last + 1
+	if storeMap.journal != nil && next > storeMap.sequence.reserved {
+		reserved := storeMap.sequence.reserved + sequenceLease
+		if err := storeMap.saveToFile([]StoreFile{sequenceStoreFile(reserved)}); err != nil {
+			ctxlog.From(ctx, storeMap.logger).Error("Can't save key sequence into file", zap.Error(err))
+			return 0, err
+		}
+		storeMap.sequence.reserved = reserved
+	}
+
+	storeMap.sequence.last = next
+	return next, nil
+}
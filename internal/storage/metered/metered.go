@@ -0,0 +1,193 @@
+// Модуль metered - учет длительности и ошибок операций хранилища в метриках Prometheus
+package metered
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+)
+
+// Storage - хранилище ссылок с метриками операций
+type Storage struct {
+	inner   storage.Storage
+	backend string
+}
+
+// New - конструктор, backend - имя драйвера для метки метрик
+func New(inner storage.Storage, backend string) *Storage {
+	return &Storage{inner: inner, backend: backend}
+}
+
+func (s *Storage) observe(operation string, start time.Time, err error) {
+	metrics.ObserveStorage(s.backend, operation, start, err)
+}
+
+// AddURL - сохранение ссылки
+func (s *Storage) AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error {
+	start := time.Now()
+	err := s.inner.AddURL(ctx, soURL, userID)
+	s.observe("add_url", start, businessError(err))
+	return err
+}
+
+// GetURL - получение ссылки. Отсутствие ссылки ошибкой не считается
+func (s *Storage) GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool) {
+	start := time.Now()
+	info, exist := s.inner.GetURL(ctx, keyURL)
+	s.observe("get_url", start, nil)
+	return info, exist
+}
+
+// LookupURL - получение ссылки с ошибкой чтения отдельно от отсутствия ссылки
+func (s *Storage) LookupURL(ctx context.Context, keyURL string) (model.ShortURLInfo, error) {
+	start := time.Now()
+	info, err := storage.LookupURL(ctx, s.inner, keyURL)
+	s.observe("get_url", start, businessError(err))
+	return info, err
+}
+
+// ListURLs - страница ссылок пользователя
+func (s *Storage) ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error) {
+	start := time.Now()
+	urls, err := s.inner.ListURLs(ctx, userID, query)
+	s.observe("list_urls", start, err)
+	return urls, err
+}
+
+// AddURLs - сохранение массива ссылок с результатом по каждой ссылке
+func (s *Storage) AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error) {
+	start := time.Now()
+	results, err := s.inner.AddURLs(ctx, shortOriginalURL, userID)
+	s.observe("add_urls", start, err)
+	return results, err
+}
+
+// DeleteURLs - пометка удаленными ссылок разных пользователей
+func (s *Storage) DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error) {
+	start := time.Now()
+	deleted, err := s.inner.DeleteURLs(ctx, keys)
+	s.observe("delete_urls", start, err)
+	return deleted, err
+}
+
+// GetShortURL - получение ключа короткой ссылки по исходной ссылке
+func (s *Storage) GetShortURL(ctx context.Context, originalURL string) (string, error) {
+	start := time.Now()
+	key, err := s.inner.GetShortURL(ctx, originalURL)
+	s.observe("get_short_url", start, businessError(err))
+	return key, err
+}
+
+// UpdateURL - изменение ссылки пользователя
+func (s *Storage) UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error) {
+	start := time.Now()
+	updated, err := s.inner.UpdateURL(ctx, change)
+	s.observe("update_url", start, businessError(err))
+	return updated, err
+}
+
+// RestoreURLs - снятие пометки удаления со ссылок пользователя
+func (s *Storage) RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error) {
+	start := time.Now()
+	restored, err := s.inner.RestoreURLs(ctx, shortURL, userID)
+	s.observe("restore_urls", start, err)
+	return restored, err
+}
+
+// GetURLHistory - прежние состояния ссылки
+func (s *Storage) GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error) {
+	start := time.Now()
+	history, err := s.inner.GetURLHistory(ctx, key)
+	s.observe("get_url_history", start, err)
+	return history, err
+}
+
+// GetStats - получение кол-ва пользователей и кол-ва коротких ссылок
+func (s *Storage) GetStats(ctx context.Context) (int, int, error) {
+	start := time.Now()
+	users, urls, err := s.inner.GetStats(ctx)
+	s.observe("get_stats", start, err)
+	return users, urls, err
+}
+
+// DeleteExpiredURL - удаление просроченных ссылок
+func (s *Storage) DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error) {
+	start := time.Now()
+	count, err := s.inner.DeleteExpiredURL(ctx, expiredBefore)
+	s.observe("delete_expired_url", start, err)
+	return count, err
+}
+
+// PurgeDeletedURL - окончательное удаление ссылок, помеченных удаленными
+func (s *Storage) PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error) {
+	start := time.Now()
+	count, err := s.inner.PurgeDeletedURL(ctx, deletedBefore)
+	s.observe("purge_deleted_url", start, err)
+	return count, err
+}
+
+// Ping - проверка доступности хранилища
+func (s *Storage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.inner.Ping(ctx)
+	s.observe("ping", start, err)
+	return err
+}
+
+// Close - закрытие хранилища
+func (s *Storage) Close() error {
+	return s.inner.Close()
+}
+
+// Compact - компактизация хранилища, если драйвер ее поддерживает
+func (s *Storage) Compact(ctx context.Context) error {
+	compactor, ok := s.inner.(storage.Compactor)
+	if !ok {
+		return storage.ErrNotSupported
+	}
+
+	start := time.Now()
+	err := compactor.Compact(ctx)
+	s.observe("compact", start, err)
+	return err
+}
+
+// ClickStorage - хранилище переходов с метриками операций
+type ClickStorage struct {
+	inner   storage.ClickStorage
+	backend string
+}
+
+// NewClicks - конструктор, backend - имя драйвера для метки метрик
+func NewClicks(inner storage.ClickStorage, backend string) *ClickStorage {
+	return &ClickStorage{inner: inner, backend: backend}
+}
+
+// AddClicks - сохранение переходов
+func (s *ClickStorage) AddClicks(ctx context.Context, clicks []model.Click) error {
+	start := time.Now()
+	err := s.inner.AddClicks(ctx, clicks)
+	metrics.ObserveStorage(s.backend, "add_clicks", start, err)
+	return err
+}
+
+// GetClickStats - статистика переходов по ссылке
+func (s *ClickStorage) GetClickStats(ctx context.Context, key string, query model.ClickStatsQuery) (model.ClickStats, error) {
+	start := time.Now()
+	stats, err := s.inner.GetClickStats(ctx, key, query)
+	metrics.ObserveStorage(s.backend, "get_click_stats", start, err)
+	return stats, err
+}
+
+// businessError - ошибка для учета в метриках: дубли и отсутствие ссылки - штатные ответы хранилища
+func businessError(err error) error {
+	if errors.Is(err, model.ErrDuplicateURL) || errors.Is(err, model.ErrDuplicateKey) || errors.Is(err, model.ErrURLNotFound) ||
+		errors.Is(err, model.ErrURLDeleted) {
+		return nil
+	}
+	return err
+}
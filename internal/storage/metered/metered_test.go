@@ -0,0 +1,33 @@
+package metered
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/metrics"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStorageObservesOperations(t *testing.T) {
+	ctx := context.Background()
+	inner, err := memory.New(&config.ServerConfig{}, zap.NewNop(), &config.ServerConfig{})
+	require.NoError(t, err)
+	s := New(inner, "test")
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	assert.ErrorIs(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://b.ru"}, 1), model.ErrDuplicateKey)
+	_, exist := s.GetURL(ctx, "aaa")
+	assert.True(t, exist)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.StorageDuration), "add_url and get_url series")
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.StorageErrors.WithLabelValues("test", "add_url")),
+		"duplicate is not storage error")
+
+	assert.NoError(t, s.Compact(ctx))
+}
@@ -0,0 +1,195 @@
+// Модуль storage - реестр драйверов хранилища коротких ссылок и выбор драйвера по конфигурации
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"go.uber.org/zap"
+)
+
+// Имена встроенных драйверов
+const (
+	// DriverAuto - postgres, если задана строка подключения к БД, иначе file, если задан путь к файлу, иначе memory
+	DriverAuto = "auto"
+	// DriverMemory - хранение только в памяти, данные теряются при перезапуске
+	DriverMemory = "memory"
+	// DriverFile - хранение в памяти с записью в файл
+	DriverFile = "file"
+	// DriverPostgres - хранение в БД Postgres
+	DriverPostgres = "postgres"
+	// DriverBolt - хранение во встроенной key-value БД bbolt
+	DriverBolt = "bolt"
+)
+
+// Storage - хранилище коротких ссылок
+type Storage interface {
+	AddURL(ctx context.Context, soURL model.KeyOriginalURL, userID int) error
+	GetURL(ctx context.Context, keyURL string) (model.ShortURLInfo, bool)
+	// ListURLs - ссылки пользователя, подходящие под фильтры запроса, в порядке сортировки запроса.
+	// Возвращает не более query.Limit ссылок, следующих за позицией query.After
+	ListURLs(ctx context.Context, userID int, query model.URLListQuery) ([]model.ShortURLInfo, error)
+	// AddURLs - сохранение массива ссылок без отката при дублях. Возвращает результат по каждой ссылке в порядке запроса
+	AddURLs(ctx context.Context, shortOriginalURL []model.KeyOriginalURL, userID int) ([]model.AddURLResult, error)
+	// DeleteURLs - пометка удаленными ссылок разных пользователей одной операцией.
+	// Возвращает ссылки, которые существуют и принадлежат указанному пользователю
+	DeleteURLs(ctx context.Context, keys []model.UserKey) ([]model.UserKey, error)
+	// RestoreURLs - снятие пометки удаления со ссылок пользователя.
+	// Возвращает ключи ссылок, которые существуют и принадлежат пользователю
+	RestoreURLs(ctx context.Context, shortURL []string, userID int) ([]string, error)
+	GetShortURL(ctx context.Context, originalURL string) (string, error)
+	// UpdateURL - изменение заданных полей ссылки пользователя с сохранением прежнего состояния в историю изменений.
+	// Изменение применяется к ссылке, заблокированной до его сохранения, возвращает состояние ссылки после изменения.
+	// Если ссылки нет или она принадлежит другому пользователю - model.ErrURLNotFound, если удалена - model.ErrURLDeleted,
+	// если новая исходная ссылка уже сокращена - model.ErrDuplicateURL
+	UpdateURL(ctx context.Context, change model.URLChange) (model.ShortURLInfo, error)
+	// GetURLHistory - прежние состояния ссылки в порядке изменения
+	GetURLHistory(ctx context.Context, key string) ([]model.URLHistory, error)
+	GetStats(ctx context.Context) (int, int, error)
+	DeleteExpiredURL(ctx context.Context, expiredBefore time.Time) (int, error)
+	// PurgeDeletedURL - окончательное удаление ссылок, помеченных удаленными до deletedBefore
+	PurgeDeletedURL(ctx context.Context, deletedBefore time.Time) (int, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// ClickStorage - хранилище переходов по коротким ссылкам
+type ClickStorage interface {
+	AddClicks(ctx context.Context, clicks []model.Click) error
+	GetClickStats(ctx context.Context, key string, query model.ClickStatsQuery) (model.ClickStats, error)
+}
+
+// ErrNotSupported - операция не поддерживается драйвером хранилища
+var ErrNotSupported = errors.New("operation is not supported by storage driver")
+
+// Compactor - хранилище, поддерживающее компактизацию по требованию
+type Compactor interface {
+	Compact(ctx context.Context) error
+}
+
+// URLLookup - хранилище, отличающее отсутствие ссылки от ошибки чтения
+type URLLookup interface {
+	// LookupURL - получение ссылки. Если ссылки нет - model.ErrURLNotFound
+	LookupURL(ctx context.Context, keyURL string) (model.ShortURLInfo, error)
+}
+
+// LookupURL - получение ссылки из хранилища с ошибкой чтения отдельно от отсутствия ссылки.
+// Для хранилища без URLLookup отсутствие ссылки в GetURL считается model.ErrURLNotFound
+func LookupURL(ctx context.Context, s Storage, keyURL string) (model.ShortURLInfo, error) {
+	if lookup, ok := s.(URLLookup); ok {
+		return lookup.LookupURL(ctx, keyURL)
+	}
+
+	info, exist := s.GetURL(ctx, keyURL)
+	if !exist {
+		return model.ShortURLInfo{}, model.ErrURLNotFound
+	}
+	return info, nil
+}
+
+// JobStorage - сохранение заданий на удаление ссылок
+type JobStorage interface {
+	// SaveJob - сохранение нового задания или замена существующего с тем же ID
+	SaveJob(ctx context.Context, job model.DeleteJob) error
+	// GetJob - задание по ID, если его нет - model.ErrJobNotFound
+	GetJob(ctx context.Context, id string) (model.DeleteJob, error)
+	// PendingJobs - невыполненные задания в порядке создания
+	PendingJobs(ctx context.Context) ([]model.DeleteJob, error)
+	// DeleteFinishedJobs - удаление завершенных заданий, измененных до finishedBefore
+	DeleteFinishedJobs(ctx context.Context, finishedBefore time.Time) (int, error)
+	// FailJob - пометка задания окончательно не выполненным с причиной reason. Меняются только статус,
+	// ошибка и время изменения, остальные поля сохраненного задания остаются прежними.
+	// Если задания нет - model.ErrJobNotFound
+	FailJob(ctx context.Context, id string, reason string, at time.Time) error
+}
+
+// Sequencer - хранилище с последовательностью номеров для генерации ключей коротких ссылок.
+// Номера возрастают, начинаются с 1 и не повторяются после перезапуска
+type Sequencer interface {
+	NextSequence(ctx context.Context) (uint64, error)
+}
+
+// MigrationChecker - хранилище со схемой, которая должна быть приведена к последней миграции
+type MigrationChecker interface {
+	CheckMigrations(ctx context.Context) error
+}
+
+// Driver - открытие хранилища. Фоновые задачи хранилища завершаются по отмене ctx
+type Driver func(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (Storage, ClickStorage, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Register - регистрация драйвера под именем name. Повторная регистрация имени - ошибка программы
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("storage: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers - отсортированный список имен зарегистрированных драйверов
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DriverName - имя драйвера, выбранного конфигурацией. Пустое значение равносильно DriverAuto
+func DriverName(cfg *config.ServerConfig) string {
+	name := strings.ToLower(strings.TrimSpace(cfg.Storage))
+	if name != "" && name != DriverAuto {
+		return name
+	}
+
+	switch {
+	case cfg.Connection != "":
+		return DriverPostgres
+	case cfg.FileStorage != "":
+		return DriverFile
+	default:
+		return DriverMemory
+	}
+}
+
+// Open - открытие хранилища выбранным конфигурацией драйвером.
+// Если драйвер неизвестен или хранилище недоступно, возвращает ошибку
+func Open(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (Storage, ClickStorage, error) {
+	name := DriverName(cfg)
+
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown storage driver %q, available: %s", name, strings.Join(Drivers(), ", "))
+	}
+
+	log.Info("Open storage", zap.String("driver", name))
+	urls, clicks, err := driver(ctx, cfg, log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open storage %q: %w", name, err)
+	}
+
+	return urls, clicks, nil
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kirillmashkov/shortener.git/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDriverName(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ServerConfig
+		want string
+	}{
+		{name: "explicit", cfg: config.ServerConfig{Storage: " Memory ", Connection: "postgres://db"}, want: DriverMemory},
+		{name: "auto with dsn", cfg: config.ServerConfig{Storage: DriverAuto, Connection: "postgres://db", FileStorage: "f.txt"}, want: DriverPostgres},
+		{name: "auto with file", cfg: config.ServerConfig{FileStorage: "f.txt"}, want: DriverFile},
+		{name: "auto without anything", cfg: config.ServerConfig{}, want: DriverMemory},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, DriverName(&test.cfg))
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	Register("test-broken", func(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (Storage, ClickStorage, error) {
+		return nil, nil, errUnavailable
+	})
+
+	_, _, err := Open(context.Background(), &config.ServerConfig{Storage: "test-broken"}, zap.NewNop())
+	assert.ErrorIs(t, err, errUnavailable)
+
+	_, _, err = Open(context.Background(), &config.ServerConfig{Storage: "unknown"}, zap.NewNop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test-broken")
+
+	assert.Panics(t, func() {
+		Register("test-broken", func(ctx context.Context, cfg *config.ServerConfig, log *zap.Logger) (Storage, ClickStorage, error) {
+			return nil, nil, nil
+		})
+	})
+}
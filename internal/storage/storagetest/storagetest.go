@@ -0,0 +1,698 @@
+// Модуль storagetest - общий набор поведенческих тестов для драйверов хранилища коротких ссылок
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kirillmashkov/shortener.git/internal/model"
+	"github.com/kirillmashkov/shortener.git/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory - создание пустого хранилища для одного теста
+type Factory func(t *testing.T) storage.Storage
+
+// Run - прогон поведенческих тестов. Каждый подтест получает новое пустое хранилище
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{name: "add and get", test: testAddGet},
+		{name: "duplicates", test: testDuplicates},
+		{name: "batch with per item results", test: testAddURLs},
+		{name: "get short url", test: testGetShortURL},
+		{name: "delete only own", test: testDeleteOwnURLs},
+		{name: "delete urls of many users", test: testDeleteURLs},
+		{name: "stats", test: testStats},
+		{name: "delete expired", test: testDeleteExpired},
+		{name: "ping", test: testPing},
+		{name: "delete jobs", test: testJobs},
+		{name: "key sequence", test: testSequence},
+		{name: "update with history", test: testUpdateURL},
+		{name: "restore and purge deleted", test: testRestorePurge},
+		{name: "list with filters and cursor", test: testListURLs},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() {
+				assert.NoError(t, s.Close())
+			})
+			test.test(t, s)
+		})
+	}
+}
+
+// ClickFactory - создание пустого хранилища ссылок и хранилища переходов по ним для одного теста
+type ClickFactory func(t *testing.T) (storage.Storage, storage.ClickStorage)
+
+// RunClicks - прогон тестов хранилища переходов. Каждый подтест получает новые пустые хранилища
+func RunClicks(t *testing.T, newStorage ClickFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage, clicks storage.ClickStorage)
+	}{
+		{name: "stats by buckets", test: testClickStats},
+		{name: "clicks are removed with link", test: testClicksRemoved},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, clicks := newStorage(t)
+			t.Cleanup(func() {
+				assert.NoError(t, s.Close())
+			})
+			test.test(t, s, clicks)
+		})
+	}
+}
+
+// Opener - открытие хранилища с данными в каталоге dir. Повторное открытие с тем же каталогом
+// должно видеть данные, сохраненные до закрытия
+type Opener func(t *testing.T, dir string) storage.Storage
+
+// RunPersistent - прогон тестов сохранения данных между перезапусками. Каждый подтест получает новый каталог
+func RunPersistent(t *testing.T, open Opener) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, open func() storage.Storage)
+	}{
+		{name: "purged deleted links stay removed", test: testPurgePersists},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			test.test(t, func() storage.Storage {
+				return open(t, dir)
+			})
+		})
+	}
+}
+
+func testAddGet(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru", ExpiresAt: &expiresAt}, 1))
+
+	info, exist := s.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	assert.Equal(t, "aaa", info.Key)
+	assert.Equal(t, "http://a.ru", info.OriginalURL)
+	assert.Equal(t, 1, info.UserID)
+	assert.False(t, info.Deleted)
+	require.NotNil(t, info.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*info.ExpiresAt))
+	assert.Nil(t, info.Redirect)
+
+	_, exist = s.GetURL(ctx, "missing")
+	assert.False(t, exist)
+
+	redirect := &model.Redirect{Code: 302, PassQuery: true, UTM: map[string]string{"source": "mail"}}
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "rrr", OriginalURL: "http://r.ru", Redirect: redirect}, 1))
+	info, exist = s.GetURL(ctx, "rrr")
+	require.True(t, exist)
+	assert.Equal(t, redirect, info.Redirect)
+}
+
+func testDuplicates(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+
+	assert.ErrorIs(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://a.ru"}, 2), model.ErrDuplicateURL)
+	assert.ErrorIs(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://b.ru"}, 2), model.ErrDuplicateKey)
+	results, err := s.AddURLs(ctx, []model.KeyOriginalURL{
+		{Key: "ccc", OriginalURL: "http://c.ru"},
+		{Key: "ddd", OriginalURL: "http://c.ru"},
+	}, 1)
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, model.AddURLResult{Key: "ccc", Err: model.ErrDuplicateURL}, results[1])
+
+	_, exist := s.GetURL(ctx, "bbb")
+	assert.False(t, exist)
+}
+
+// addURLs - сохранение массива ссылок, все ссылки должны быть сохранены
+func addURLs(t *testing.T, s storage.Storage, urls []model.KeyOriginalURL, userID int) {
+	t.Helper()
+
+	results, err := s.AddURLs(context.Background(), urls, userID)
+	require.NoError(t, err)
+	for _, result := range results {
+		require.NoError(t, result.Err)
+	}
+}
+
+// deleteURLs - пометка удаленными ссылок keys пользователя userID
+func deleteURLs(t *testing.T, s storage.Storage, userID int, keys ...string) {
+	t.Helper()
+
+	userKeys := make([]model.UserKey, 0, len(keys))
+	for _, key := range keys {
+		userKeys = append(userKeys, model.UserKey{UserID: userID, Key: key})
+	}
+	_, err := s.DeleteURLs(context.Background(), userKeys)
+	require.NoError(t, err)
+}
+
+// userKeys - ключи всех ссылок пользователя по возрастанию
+func userKeys(t *testing.T, s storage.Storage, userID int) []string {
+	t.Helper()
+
+	urls, err := s.ListURLs(context.Background(), userID, model.URLListQuery{Sort: model.URLSortKey, Now: time.Now()})
+	require.NoError(t, err)
+	keys := make([]string, 0, len(urls))
+	for _, info := range urls {
+		keys = append(keys, info.Key)
+	}
+	return keys
+}
+
+func testAddURLs(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+
+	results, err := s.AddURLs(ctx, []model.KeyOriginalURL{
+		{Key: "bbb", OriginalURL: "http://b.ru"},
+		{Key: "xxx", OriginalURL: "http://a.ru"},
+		{Key: "aaa", OriginalURL: "http://c.ru"},
+		{Key: "ccc", OriginalURL: "http://b.ru"},
+		{Key: "bbb", OriginalURL: "http://d.ru"},
+		{Key: "eee", OriginalURL: "http://e.ru", Redirect: &model.Redirect{Code: 301}},
+	}, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 6)
+	assert.Equal(t, model.AddURLResult{Key: "bbb"}, results[0])
+	assert.Equal(t, "aaa", results[1].Key, "existing key of duplicate url must be returned")
+	assert.ErrorIs(t, results[1].Err, model.ErrDuplicateURL)
+	assert.ErrorIs(t, results[2].Err, model.ErrDuplicateKey)
+	assert.Equal(t, "bbb", results[3].Key, "duplicate url within batch must return key of the first item")
+	assert.ErrorIs(t, results[3].Err, model.ErrDuplicateURL)
+	assert.ErrorIs(t, results[4].Err, model.ErrDuplicateKey)
+	assert.Equal(t, model.AddURLResult{Key: "eee"}, results[5])
+
+	info, exist := s.GetURL(ctx, "eee")
+	require.True(t, exist)
+	assert.Equal(t, &model.Redirect{Code: 301}, info.Redirect)
+
+	assert.Equal(t, []string{"bbb", "eee"}, userKeys(t, s, 2), "only created urls must be saved")
+
+	_, err = s.GetShortURL(ctx, "http://c.ru")
+	assert.ErrorIs(t, err, model.ErrURLNotFound)
+}
+
+func testGetShortURL(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+
+	key, err := s.GetShortURL(ctx, "http://a.ru")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", key)
+
+	_, err = s.GetShortURL(ctx, "http://missing.ru")
+	assert.ErrorIs(t, err, model.ErrURLNotFound)
+}
+
+func testDeleteOwnURLs(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 2))
+
+	deleteURLs(t, s, 2, "aaa", "bbb", "missing")
+
+	info, exist := s.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	assert.False(t, info.Deleted, "link of other user must not be deleted")
+
+	info, exist = s.GetURL(ctx, "bbb")
+	require.True(t, exist)
+	assert.True(t, info.Deleted)
+	assert.Equal(t, "http://b.ru", info.OriginalURL)
+
+	// повторное удаление не является ошибкой
+	deleteURLs(t, s, 2, "bbb")
+}
+
+func testDeleteURLs(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 2))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "ccc", OriginalURL: "http://c.ru"}, 2))
+
+	deleted, err := s.DeleteURLs(ctx, []model.UserKey{
+		{UserID: 1, Key: "aaa"},
+		{UserID: 1, Key: "bbb"},
+		{UserID: 2, Key: "ccc"},
+		{UserID: 2, Key: "missing"},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []model.UserKey{{UserID: 1, Key: "aaa"}, {UserID: 2, Key: "ccc"}}, deleted)
+
+	for key, want := range map[string]bool{"aaa": true, "bbb": false, "ccc": true} {
+		info, exist := s.GetURL(ctx, key)
+		require.True(t, exist)
+		assert.Equal(t, want, info.Deleted, key)
+	}
+
+	deleted, err = s.DeleteURLs(ctx, []model.UserKey{{UserID: 1, Key: "aaa"}})
+	require.NoError(t, err)
+	assert.Equal(t, []model.UserKey{{UserID: 1, Key: "aaa"}}, deleted, "repeated deletion must report own url")
+}
+
+func testStats(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	users, urls, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, users)
+	assert.Equal(t, 0, urls)
+
+	addURLs(t, s, []model.KeyOriginalURL{
+		{Key: "aaa", OriginalURL: "http://a.ru"},
+		{Key: "bbb", OriginalURL: "http://b.ru"},
+	}, 1)
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "ccc", OriginalURL: "http://c.ru"}, 2))
+
+	users, urls, err = s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, users)
+	assert.Equal(t, 3, urls)
+}
+
+func testDeleteExpired(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Hour)
+	active := time.Now().Add(time.Hour)
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "old", OriginalURL: "http://old.ru", ExpiresAt: &expired}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "new", OriginalURL: "http://new.ru", ExpiresAt: &active}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "forever", OriginalURL: "http://forever.ru"}, 2))
+
+	count, err := s.DeleteExpiredURL(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, exist := s.GetURL(ctx, "old")
+	assert.False(t, exist)
+	_, err = s.GetShortURL(ctx, "http://old.ru")
+	assert.ErrorIs(t, err, model.ErrURLNotFound)
+
+	assert.Equal(t, []string{"new"}, userKeys(t, s, 1))
+
+	users, count, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, users)
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "old", OriginalURL: "http://old.ru"}, 1),
+		"key and original url of purged link must be free")
+}
+
+func testPing(t *testing.T, s storage.Storage) {
+	assert.NoError(t, s.Ping(context.Background()))
+}
+
+func testJobs(t *testing.T, s storage.Storage) {
+	jobs, ok := s.(storage.JobStorage)
+	if !ok {
+		t.Skip("storage does not keep delete jobs")
+	}
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	_, err := jobs.GetJob(ctx, "missing")
+	assert.ErrorIs(t, err, model.ErrJobNotFound)
+
+	first := model.DeleteJob{
+		ID:        "job-1",
+		UserID:    1,
+		Status:    model.JobPending,
+		Keys:      []model.DeleteJobKey{{Key: "aaa", Status: model.JobPending}, {Key: "bbb", Status: model.JobPending}},
+		RequestID: "req-1",
+		CreatedAt: now.Add(-2 * time.Hour),
+		UpdatedAt: now.Add(-2 * time.Hour),
+	}
+	second := model.DeleteJob{
+		ID:        "job-2",
+		UserID:    2,
+		Status:    model.JobPending,
+		Keys:      []model.DeleteJobKey{{Key: "ccc", Status: model.JobPending}},
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now.Add(-time.Hour),
+	}
+	require.NoError(t, jobs.SaveJob(ctx, second))
+	require.NoError(t, jobs.SaveJob(ctx, first))
+
+	pending, err := jobs.PendingJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "job-1", pending[0].ID, "pending jobs must be in creation order")
+	assert.Equal(t, "job-2", pending[1].ID)
+
+	first.Status = model.JobFailed
+	first.Attempts = 1
+	first.Keys = []model.DeleteJobKey{{Key: "aaa", Status: model.JobDone}, {Key: "bbb", Status: model.JobFailed, Error: "url not found"}}
+	first.UpdatedAt = now.Add(-90 * time.Minute)
+	require.NoError(t, jobs.SaveJob(ctx, first))
+
+	got, err := jobs.GetJob(ctx, "job-1")
+	require.NoError(t, err)
+	assert.True(t, got.CreatedAt.Equal(first.CreatedAt))
+	assert.True(t, got.UpdatedAt.Equal(first.UpdatedAt))
+	got.CreatedAt, got.UpdatedAt = first.CreatedAt, first.UpdatedAt
+	assert.Equal(t, first, got)
+
+	pending, err = jobs.PendingJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "job-2", pending[0].ID)
+
+	assert.ErrorIs(t, jobs.FailJob(ctx, "missing", "load job", now), model.ErrJobNotFound)
+	require.NoError(t, jobs.FailJob(ctx, "job-2", "load job: broken", now.Add(-30*time.Minute)))
+	failed, err := jobs.GetJob(ctx, "job-2")
+	require.NoError(t, err)
+	assert.Equal(t, model.JobFailed, failed.Status)
+	assert.Equal(t, "load job: broken", failed.Error)
+	assert.Equal(t, second.UserID, failed.UserID, "fail must keep other fields of the job")
+	assert.Equal(t, second.Keys, failed.Keys)
+
+	count, err := jobs.DeleteFinishedJobs(ctx, now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "jobs finished after the boundary must be kept")
+
+	count, err = jobs.DeleteFinishedJobs(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "jobs finished after the boundary must be kept")
+
+	_, err = jobs.GetJob(ctx, "job-1")
+	assert.ErrorIs(t, err, model.ErrJobNotFound)
+	_, err = jobs.GetJob(ctx, "job-2")
+	assert.NoError(t, err)
+}
+
+func testSequence(t *testing.T, s storage.Storage) {
+	sequencer, ok := s.(storage.Sequencer)
+	if !ok {
+		t.Skip("storage has no key sequence")
+	}
+	ctx := context.Background()
+
+	var prev uint64
+	for range 5 {
+		n, err := sequencer.NextSequence(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, n, prev, "sequence must increase")
+		prev = n
+	}
+}
+
+func testUpdateURL(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	changedAt := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru", ExpiresAt: &expiresAt}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 1))
+
+	change := func(key string, originalURL string, userID int, requestID string) model.URLChange {
+		return model.URLChange{Key: key, OriginalURL: originalURL, UserID: userID, RequestID: requestID, ChangedAt: changedAt}
+	}
+
+	_, err := s.UpdateURL(ctx, change("aaa", "http://c.ru", 2, ""))
+	assert.ErrorIs(t, err, model.ErrURLNotFound, "link of other user")
+	_, err = s.UpdateURL(ctx, change("missing", "http://c.ru", 1, ""))
+	assert.ErrorIs(t, err, model.ErrURLNotFound)
+	_, err = s.UpdateURL(ctx, change("aaa", "http://b.ru", 1, ""))
+	assert.ErrorIs(t, err, model.ErrDuplicateURL)
+
+	redirect := &model.Redirect{Code: 301}
+	first := change("aaa", "http://c.ru", 1, "req-1")
+	first.SetRedirect, first.Redirect = true, redirect
+	first.SetExpiry = true
+	_, err = s.UpdateURL(ctx, first)
+	require.NoError(t, err)
+	updated, err := s.UpdateURL(ctx, change("aaa", "http://d.ru", 1, "req-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://d.ru", updated.OriginalURL)
+	assert.Equal(t, redirect, updated.Redirect, "fields not set in change must be kept")
+
+	info, exist := s.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	assert.Equal(t, "http://d.ru", info.OriginalURL)
+	assert.Nil(t, info.ExpiresAt)
+	assert.Equal(t, redirect, info.Redirect)
+	assert.Equal(t, 1, info.UserID)
+
+	// изменение только политики перехода не меняет исходную ссылку, измененную другим запросом
+	_, err = s.UpdateURL(ctx, model.URLChange{Key: "aaa", SetRedirect: true, UserID: 1, ChangedAt: changedAt})
+	require.NoError(t, err)
+	info, _ = s.GetURL(ctx, "aaa")
+	assert.Equal(t, "http://d.ru", info.OriginalURL)
+	assert.Nil(t, info.Redirect)
+
+	key, err := s.GetShortURL(ctx, "http://d.ru")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", key)
+	_, err = s.GetShortURL(ctx, "http://a.ru")
+	assert.ErrorIs(t, err, model.ErrURLNotFound, "previous original url must be free")
+
+	history, err := s.GetURLHistory(ctx, "aaa")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "http://a.ru", history[0].OriginalURL)
+	require.NotNil(t, history[0].ExpiresAt)
+	assert.True(t, expiresAt.Equal(*history[0].ExpiresAt))
+	assert.Nil(t, history[0].Redirect)
+	assert.Equal(t, "req-1", history[0].RequestID)
+	assert.True(t, changedAt.Equal(history[0].ChangedAt))
+	assert.Equal(t, "http://c.ru", history[1].OriginalURL)
+	assert.Equal(t, redirect, history[1].Redirect)
+	assert.Equal(t, "req-2", history[1].RequestID)
+	assert.Equal(t, "http://d.ru", history[2].OriginalURL)
+	assert.Equal(t, redirect, history[2].Redirect)
+
+	history, err = s.GetURLHistory(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	deleteURLs(t, s, 1, "bbb")
+	_, err = s.UpdateURL(ctx, change("bbb", "http://e.ru", 1, ""))
+	assert.ErrorIs(t, err, model.ErrURLDeleted)
+}
+
+func testRestorePurge(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 2))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "ccc", OriginalURL: "http://c.ru"}, 1))
+	deleteURLs(t, s, 1, "aaa", "ccc")
+	deleteURLs(t, s, 2, "bbb")
+
+	info, exist := s.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	require.NotNil(t, info.DeletedAt)
+	deletedAt := *info.DeletedAt
+
+	deleteURLs(t, s, 1, "aaa")
+	info, _ = s.GetURL(ctx, "aaa")
+	require.NotNil(t, info.DeletedAt)
+	assert.True(t, deletedAt.Equal(*info.DeletedAt), "repeated deletion must keep deletion time")
+
+	restored, err := s.RestoreURLs(ctx, []string{"aaa", "bbb", "missing"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aaa"}, restored, "only own links are restored")
+
+	info, exist = s.GetURL(ctx, "aaa")
+	require.True(t, exist)
+	assert.False(t, info.Deleted)
+	assert.Nil(t, info.DeletedAt)
+	info, _ = s.GetURL(ctx, "bbb")
+	assert.True(t, info.Deleted, "link of other user must stay deleted")
+
+	count, err := s.PurgeDeletedURL(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "links deleted within retention are kept")
+
+	count, err = s.PurgeDeletedURL(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, exist = s.GetURL(ctx, "aaa")
+	assert.True(t, exist, "restored link must not be purged")
+	_, exist = s.GetURL(ctx, "ccc")
+	assert.False(t, exist)
+	_, err = s.GetShortURL(ctx, "http://c.ru")
+	assert.ErrorIs(t, err, model.ErrURLNotFound)
+
+	restored, err = s.RestoreURLs(ctx, []string{"ccc"}, 1)
+	require.NoError(t, err)
+	assert.Empty(t, restored, "purged link can't be restored")
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "ccc", OriginalURL: "http://c.ru"}, 2),
+		"key and original url of purged link must be free")
+}
+
+func testListURLs(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Hour)
+	created := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+
+	for i, soURL := range []model.KeyOriginalURL{
+		{Key: "k1", OriginalURL: "http://alpha.ru/one"},
+		{Key: "k2", OriginalURL: "http://beta.ru/two", ExpiresAt: &expired},
+		{Key: "k3", OriginalURL: "http://ALPHA.ru/three"},
+		{Key: "k4", OriginalURL: "http://gamma.ru/alpha"},
+	} {
+		soURL.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, s.AddURL(ctx, soURL, 1))
+	}
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "k5", OriginalURL: "http://alpha.ru/other"}, 2))
+	deleteURLs(t, s, 1, "k3")
+
+	k2, _ := s.GetURL(ctx, "k2")
+	k3, exist := s.GetURL(ctx, "k3")
+	require.True(t, exist)
+	require.True(t, created.Add(2*time.Minute).Equal(k3.CreatedAt), "explicit creation time must be kept")
+
+	yes, no := true, false
+	list := func(query model.URLListQuery) []string {
+		t.Helper()
+		query.Now = time.Now()
+		urls, err := s.ListURLs(ctx, 1, query)
+		require.NoError(t, err)
+		keys := make([]string, 0, len(urls))
+		for _, info := range urls {
+			keys = append(keys, info.Key)
+		}
+		return keys
+	}
+
+	assert.Equal(t, []string{"k4", "k3", "k2", "k1"}, list(model.URLListQuery{Sort: model.URLSortCreated, Desc: true}))
+	assert.Equal(t, []string{"k1", "k2"}, list(model.URLListQuery{Sort: model.URLSortCreated, Limit: 2}))
+	assert.Equal(t, []string{"k3", "k4"}, list(model.URLListQuery{Sort: model.URLSortCreated,
+		After: &model.URLCursor{Key: "k2", CreatedAt: k2.CreatedAt}}))
+	assert.Equal(t, []string{"k2", "k1"}, list(model.URLListQuery{Sort: model.URLSortKey, Desc: true,
+		After: &model.URLCursor{Key: "k3"}}))
+	assert.Equal(t, []string{"k2", "k4"}, list(model.URLListQuery{Sort: model.URLSortURL, Deleted: &no,
+		After: &model.URLCursor{Key: "k1", OriginalURL: "http://alpha.ru/one"}}))
+
+	assert.Equal(t, []string{"k1", "k3", "k4"}, list(model.URLListQuery{Sort: model.URLSortKey, Search: "alpha"}),
+		"search must ignore case")
+	assert.Empty(t, list(model.URLListQuery{Sort: model.URLSortKey, Search: "%"}), "search must not use wildcards")
+	assert.Equal(t, []string{"k3"}, list(model.URLListQuery{Sort: model.URLSortKey, Deleted: &yes}))
+	assert.Equal(t, []string{"k2"}, list(model.URLListQuery{Sort: model.URLSortKey, Expired: &yes}))
+	assert.Equal(t, []string{"k1", "k3", "k4"}, list(model.URLListQuery{Sort: model.URLSortKey, Expired: &no}))
+	assert.Equal(t, []string{"k3", "k4"}, list(model.URLListQuery{Sort: model.URLSortKey, CreatedFrom: k3.CreatedAt}))
+	assert.Equal(t, []string{"k1", "k2"}, list(model.URLListQuery{Sort: model.URLSortKey, CreatedTo: k3.CreatedAt}))
+
+	_, err := s.RestoreURLs(ctx, []string{"k3"}, 1)
+	require.NoError(t, err)
+	_, err = s.UpdateURL(ctx, model.URLChange{Key: "k3", OriginalURL: "http://delta.ru", UserID: 1, ChangedAt: time.Now()})
+	require.NoError(t, err)
+	info, _ := s.GetURL(ctx, "k3")
+	assert.True(t, k3.CreatedAt.Equal(info.CreatedAt), "update must keep creation time")
+}
+
+func testPurgePersists(t *testing.T, open func() storage.Storage) {
+	ctx := context.Background()
+
+	s := open()
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 1))
+	deleteURLs(t, s, 1, "aaa")
+
+	count, err := s.PurgeDeletedURL(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.NoError(t, s.Close())
+
+	s = open()
+	t.Cleanup(func() {
+		assert.NoError(t, s.Close())
+	})
+	_, exist := s.GetURL(ctx, "aaa")
+	assert.False(t, exist, "purged link must not return after reopen")
+	_, err = s.GetShortURL(ctx, "http://a.ru")
+	assert.ErrorIs(t, err, model.ErrURLNotFound)
+	_, exist = s.GetURL(ctx, "bbb")
+	assert.True(t, exist)
+}
+
+func testClickStats(t *testing.T, s storage.Storage, clicks storage.ClickStorage) {
+	ctx := context.Background()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := model.ClickStatsQuery{Bucket: "day", From: day, To: day.Add(48 * time.Hour)}
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "aaa", OriginalURL: "http://a.ru"}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "bbb", OriginalURL: "http://b.ru"}, 1))
+	require.NoError(t, clicks.AddClicks(ctx, []model.Click{
+		{Key: "aaa", Time: day.Add(-time.Hour)},
+		{Key: "aaa", Time: day.Add(time.Hour)},
+		{Key: "aaa", Time: day.Add(2 * time.Hour)},
+		{Key: "aaa", Time: day.Add(25 * time.Hour)},
+		{Key: "bbb", Time: day.Add(time.Hour)},
+		{Key: "missing", Time: day.Add(time.Hour)},
+	}))
+
+	stats, err := clicks.GetClickStats(ctx, "aaa", query)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Total)
+	assert.Equal(t, []model.ClickBucket{
+		{Time: day, Count: 2},
+		{Time: day.Add(24 * time.Hour), Count: 1},
+	}, stats.Series)
+
+	stats, err = clicks.GetClickStats(ctx, "missing", query)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Total, "clicks of unknown link must not be saved")
+	assert.Empty(t, stats.Series)
+}
+
+func testClicksRemoved(t *testing.T, s storage.Storage, clicks storage.ClickStorage) {
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Hour)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := model.ClickStatsQuery{Bucket: "day", From: day, To: day.Add(24 * time.Hour)}
+
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "old", OriginalURL: "http://old.ru", ExpiresAt: &expired}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "del", OriginalURL: "http://del.ru"}, 1))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "live", OriginalURL: "http://live.ru"}, 1))
+	require.NoError(t, clicks.AddClicks(ctx, []model.Click{
+		{Key: "old", Time: day.Add(time.Hour), Referrer: "http://ref.ru", IP: "10.0.0.0"},
+		{Key: "del", Time: day.Add(time.Hour), Referrer: "http://ref.ru", IP: "10.0.0.0"},
+		{Key: "live", Time: day.Add(time.Hour)},
+	}))
+	deleteURLs(t, s, 1, "del")
+
+	_, err := s.DeleteExpiredURL(ctx, time.Now())
+	require.NoError(t, err)
+	_, err = s.PurgeDeletedURL(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// ключи освободились и заняты другим пользователем
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "old", OriginalURL: "http://new-old.ru"}, 2))
+	require.NoError(t, s.AddURL(ctx, model.KeyOriginalURL{Key: "del", OriginalURL: "http://new-del.ru"}, 2))
+	for _, key := range []string{"old", "del"} {
+		stats, err := clicks.GetClickStats(ctx, key, query)
+		require.NoError(t, err)
+		assert.Equal(t, 0, stats.Total, "new owner of %s must not see clicks of removed link", key)
+		assert.Empty(t, stats.Series)
+	}
+
+	stats, err := clicks.GetClickStats(ctx, "live", query)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+}
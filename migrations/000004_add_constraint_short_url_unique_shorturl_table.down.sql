@@ -0,0 +1 @@
+alter table shorturl drop constraint if exists short_url_unique;
\ No newline at end of file
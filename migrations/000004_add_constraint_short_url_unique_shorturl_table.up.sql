@@ -0,0 +1 @@
+alter table shorturl add constraint short_url_unique unique (short_url);
\ No newline at end of file
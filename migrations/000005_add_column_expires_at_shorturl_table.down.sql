@@ -0,0 +1 @@
+alter table shorturl drop column expires_at;
\ No newline at end of file
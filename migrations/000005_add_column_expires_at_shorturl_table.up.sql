@@ -0,0 +1 @@
+alter table shorturl add expires_at timestamptz null;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists click;
\ No newline at end of file
@@ -0,0 +1,2 @@
+create table if not exists click (id bigserial primary key, short_url varchar NOT NULL, clicked_at timestamptz NOT NULL, referrer varchar NOT NULL default '', user_agent varchar NOT NULL default '', ip varchar NOT NULL default '');
+create index if not exists click_short_url_clicked_at_idx on click (short_url, clicked_at);
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists delete_job;
\ No newline at end of file
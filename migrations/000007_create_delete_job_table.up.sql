@@ -0,0 +1,2 @@
+create table if not exists delete_job (id varchar primary key, user_id bigint NOT NULL, status varchar NOT NULL, keys jsonb NOT NULL, attempts int NOT NULL default 0, error varchar NOT NULL default '', request_id varchar NOT NULL default '', created_at timestamptz NOT NULL, updated_at timestamptz NOT NULL);
+create index if not exists delete_job_status_idx on delete_job (status);
\ No newline at end of file
@@ -0,0 +1 @@
+drop sequence if exists shorturl_key_seq;
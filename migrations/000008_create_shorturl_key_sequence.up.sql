@@ -0,0 +1 @@
+create sequence if not exists shorturl_key_seq;
@@ -0,0 +1 @@
+alter table shorturl drop column redirect;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table shorturl add redirect jsonb null;
\ No newline at end of file
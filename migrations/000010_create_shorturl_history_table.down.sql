@@ -0,0 +1 @@
+drop table if exists shorturl_history;
\ No newline at end of file
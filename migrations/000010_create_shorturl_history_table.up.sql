@@ -0,0 +1,2 @@
+create table if not exists shorturl_history (id bigserial primary key, short_url varchar NOT NULL references shorturl (short_url) on delete cascade, original_url varchar NOT NULL, redirect jsonb null, expires_at timestamptz null, request_id varchar NOT NULL default '', changed_at timestamptz NOT NULL);
+create index if not exists shorturl_history_short_url_idx on shorturl_history (short_url, id);
\ No newline at end of file
@@ -0,0 +1 @@
+drop index if exists shorturl_deleted_at_idx;alter table shorturl drop column deleted_at;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table shorturl add deleted_at timestamptz null;update shorturl set deleted_at = now() where deleted;create index if not exists shorturl_deleted_at_idx on shorturl (deleted_at) where deleted;
\ No newline at end of file
@@ -0,0 +1 @@
+drop index if exists shorturl_user_id_short_url_idx;drop index if exists shorturl_user_id_created_at_idx;alter table shorturl drop column created_at;
\ No newline at end of file
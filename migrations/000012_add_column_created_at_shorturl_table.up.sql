@@ -0,0 +1 @@
+alter table shorturl add created_at timestamptz not null default now();create index if not exists shorturl_user_id_created_at_idx on shorturl (user_id, created_at, short_url);create index if not exists shorturl_user_id_short_url_idx on shorturl (user_id, short_url);
\ No newline at end of file
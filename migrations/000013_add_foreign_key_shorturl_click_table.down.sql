@@ -0,0 +1 @@
+alter table click drop constraint if exists click_short_url_fkey;
\ No newline at end of file
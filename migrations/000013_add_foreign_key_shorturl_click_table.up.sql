@@ -0,0 +1 @@
+delete from click where short_url not in (select short_url from shorturl);alter table click add constraint click_short_url_fkey foreign key (short_url) references shorturl (short_url) on delete cascade;
\ No newline at end of file